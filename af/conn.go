@@ -2,7 +2,9 @@ package af
 
 import "C"
 import (
+	"context"
 	"database/sql/driver"
+	"time"
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/af/async"
@@ -63,7 +65,7 @@ func (conn *Connector) StmtExecute(sql string, params *param.Param) (res driver.
 	}
 
 	defer stmt.Close()
-	return conn.stmtExecute(stmt, sql, params)
+	return conn.stmtExecute(stmt, sql, params, 0)
 }
 
 // StmtExecuteWithReqID Execute sql through stmt with reqID
@@ -75,10 +77,16 @@ func (conn *Connector) StmtExecuteWithReqID(sql string, params *param.Param, req
 	}
 
 	defer stmt.Close()
-	return conn.stmtExecute(stmt, sql, params)
+	return conn.stmtExecute(stmt, sql, params, reqID)
 }
 
-func (conn *Connector) stmtExecute(stmt *Stmt, sql string, params *param.Param) (res driver.Result, err error) {
+func (conn *Connector) stmtExecute(stmt *Stmt, sql string, params *param.Param, reqID int64) (res driver.Result, err error) {
+	span := startSpan(common.OpStmtExec, sql, reqID)
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(nil, common.DriverAf, common.ActionStmtExec, start, err)
+		common.EndSpan(span, err)
+	}()
 	err = stmt.Prepare(sql)
 	if err != nil {
 		return nil, err
@@ -96,29 +104,21 @@ func (conn *Connector) stmtExecute(stmt *Stmt, sql string, params *param.Param)
 		return nil, err
 	}
 	result := stmt.GetAffectedRows()
+	span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: result})
 	return driver.RowsAffected(result), nil
 }
 
 // Exec Execute sql
 func (conn *Connector) Exec(query string, args ...driver.Value) (driver.Result, error) {
-	if conn.taos == nil {
-		return nil, driver.ErrBadConn
-	}
-	if len(args) != 0 {
-		prepared, err := common.InterpolateParams(query, common.ValueArgsToNamedValueArgs(args))
-		if err != nil {
-			return nil, err
-		}
-		query = prepared
-	}
-	asyncHandler := async.GetHandler()
-	defer async.PutHandler(asyncHandler)
-	result := conn.taosQuery(query, asyncHandler, 0)
-	return conn.processExecResult(result)
+	return conn.exec(query, 0, args)
 }
 
 // ExecWithReqID Execute sql with reqID
 func (conn *Connector) ExecWithReqID(query string, reqID int64, args ...driver.Value) (driver.Result, error) {
+	return conn.exec(query, reqID, args)
+}
+
+func (conn *Connector) exec(query string, reqID int64, args []driver.Value) (driver.Result, error) {
 	if conn.taos == nil {
 		return nil, driver.ErrBadConn
 	}
@@ -129,10 +129,29 @@ func (conn *Connector) ExecWithReqID(query string, reqID int64, args ...driver.V
 		}
 		query = prepared
 	}
+	span := startSpan(common.OpExec, query, reqID)
+	start := time.Now()
 	asyncHandler := async.GetHandler()
 	defer async.PutHandler(asyncHandler)
 	result := conn.taosQuery(query, asyncHandler, reqID)
-	return conn.processExecResult(result)
+	res, err := conn.processExecResult(result)
+	common.RecordRequest(nil, common.DriverAf, common.ActionQuery, start, err)
+	if err == nil {
+		affected, _ := res.RowsAffected()
+		span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: int(affected)})
+	}
+	common.EndSpan(span, err)
+	return res, err
+}
+
+// startSpan starts tracing an operation, see common.Tracer
+func startSpan(operation string, sql string, reqID int64) common.Span {
+	attrs := []common.Attribute{{Key: common.AttrSQL, Value: sql}}
+	if reqID != 0 {
+		attrs = append(attrs, common.Attribute{Key: common.AttrReqID, Value: reqID})
+	}
+	_, span := common.StartSpan(context.Background(), operation, attrs...)
+	return span
 }
 
 func (conn *Connector) processExecResult(result *handler.AsyncResult) (driver.Result, error) {
@@ -154,23 +173,15 @@ func (conn *Connector) processExecResult(result *handler.AsyncResult) (driver.Re
 
 // Query Execute query sql
 func (conn *Connector) Query(query string, args ...driver.Value) (driver.Rows, error) {
-	if conn.taos == nil {
-		return nil, driver.ErrBadConn
-	}
-	if len(args) != 0 {
-		prepared, err := common.InterpolateParams(query, common.ValueArgsToNamedValueArgs(args))
-		if err != nil {
-			return nil, err
-		}
-		query = prepared
-	}
-	h := async.GetHandler()
-	result := conn.taosQuery(query, h, 0)
-	return conn.processQueryResult(result, h)
+	return conn.query(query, 0, args)
 }
 
 // QueryWithReqID Execute query sql with reqID
 func (conn *Connector) QueryWithReqID(query string, reqID int64, args ...driver.Value) (driver.Rows, error) {
+	return conn.query(query, reqID, args)
+}
+
+func (conn *Connector) query(query string, reqID int64, args []driver.Value) (driver.Rows, error) {
 	if conn.taos == nil {
 		return nil, driver.ErrBadConn
 	}
@@ -181,12 +192,22 @@ func (conn *Connector) QueryWithReqID(query string, reqID int64, args ...driver.
 		}
 		query = prepared
 	}
+	span := startSpan(common.OpQuery, query, reqID)
+	start := time.Now()
 	h := async.GetHandler()
 	result := conn.taosQuery(query, h, reqID)
-	return conn.processQueryResult(result, h)
+	rs, err := conn.processQueryResult(result, h)
+	common.RecordRequest(nil, common.DriverAf, common.ActionQuery, start, err)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	// the span ends when the rows are closed
+	rs.span = span
+	return rs, nil
 }
 
-func (conn *Connector) processQueryResult(result *handler.AsyncResult, h *handler.Handler) (driver.Rows, error) {
+func (conn *Connector) processQueryResult(result *handler.AsyncResult, h *handler.Handler) (*rows, error) {
 	res := result.Res
 	if code := wrapper.TaosError(res); code != int(errors.SUCCESS) {
 		async.PutHandler(h)
@@ -248,7 +269,13 @@ func (conn *Connector) SelectDB(db string) error {
 
 // InfluxDBInsertLines Insert data using influxdb line format
 // Deprecated
-func (conn *Connector) InfluxDBInsertLines(lines []string, precision string) error {
+func (conn *Connector) InfluxDBInsertLines(lines []string, precision string) (err error) {
+	span := startSchemalessSpan(wrapper.InfluxDBLineProtocol)
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(nil, common.DriverAf, common.ActionSchemaless, start, err)
+		common.EndSpan(span, err)
+	}()
 	locker.Lock()
 	result := wrapper.TaosSchemalessInsert(conn.taos, lines, wrapper.InfluxDBLineProtocol, precision)
 	locker.Unlock()
@@ -268,7 +295,13 @@ func (conn *Connector) InfluxDBInsertLines(lines []string, precision string) err
 
 // OpenTSDBInsertTelnetLines Insert data using opentsdb telnet format
 // Deprecated
-func (conn *Connector) OpenTSDBInsertTelnetLines(lines []string) error {
+func (conn *Connector) OpenTSDBInsertTelnetLines(lines []string) (err error) {
+	span := startSchemalessSpan(wrapper.OpenTSDBTelnetLineProtocol)
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(nil, common.DriverAf, common.ActionSchemaless, start, err)
+		common.EndSpan(span, err)
+	}()
 	locker.Lock()
 	result := wrapper.TaosSchemalessInsert(conn.taos, lines, wrapper.OpenTSDBTelnetLineProtocol, "")
 	locker.Unlock()
@@ -286,7 +319,13 @@ func (conn *Connector) OpenTSDBInsertTelnetLines(lines []string) error {
 
 // OpenTSDBInsertJsonPayload Insert data using opentsdb json format
 // Deprecated
-func (conn *Connector) OpenTSDBInsertJsonPayload(payload string) error {
+func (conn *Connector) OpenTSDBInsertJsonPayload(payload string) (err error) {
+	span := startSchemalessSpan(wrapper.OpenTSDBJsonFormatProtocol)
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(nil, common.DriverAf, common.ActionSchemaless, start, err)
+		common.EndSpan(span, err)
+	}()
 	result := wrapper.TaosSchemalessInsert(conn.taos, []string{payload}, wrapper.OpenTSDBJsonFormatProtocol, "")
 	code := wrapper.TaosError(result)
 	if code != 0 {
@@ -302,6 +341,14 @@ func (conn *Connector) OpenTSDBInsertJsonPayload(payload string) error {
 	return nil
 }
 
+// startSchemalessSpan starts tracing a schemaless insert, see common.Tracer
+func startSchemalessSpan(protocol int) common.Span {
+	_, span := common.StartSpan(context.Background(), common.OpSchemaless,
+		common.Attribute{Key: common.AttrSchemalessProtocol, Value: protocol},
+	)
+	return span
+}
+
 func (conn *Connector) GetTableVGroupID(db, table string) (vgID int, err error) {
 	var code int
 	vgID, code = wrapper.TaosGetTableVgID(conn.taos, db, table)
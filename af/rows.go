@@ -4,10 +4,12 @@ import (
 	"database/sql/driver"
 	"io"
 	"reflect"
+	"time"
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/af/async"
 	"github.com/taosdata/driver-go/v3/af/locker"
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/parser"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
@@ -23,6 +25,9 @@ type rows struct {
 	blockSize   int
 	result      unsafe.Pointer
 	precision   int
+	span        common.Span // the span of the query, ended when the result is freed
+	rowsRead    int
+	err         error // the error that ended Next early
 }
 
 func (rs *rows) Columns() []string {
@@ -58,6 +63,7 @@ func (rs *rows) Next(dest []driver.Value) error {
 
 	if rs.block == nil {
 		if err := rs.taosFetchBlock(); err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -69,6 +75,7 @@ func (rs *rows) Next(dest []driver.Value) error {
 
 	if rs.blockOffset >= rs.blockSize {
 		if err := rs.taosFetchBlock(); err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -79,10 +86,15 @@ func (rs *rows) Next(dest []driver.Value) error {
 	}
 	parser.ReadRow(dest, rs.block, rs.blockSize, rs.blockOffset, rs.rowsHeader.ColTypes, rs.precision)
 	rs.blockOffset++
+	rs.rowsRead++
 	return nil
 }
 
-func (rs *rows) taosFetchBlock() error {
+func (rs *rows) taosFetchBlock() (err error) {
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(nil, common.DriverAf, common.ActionFetchBlock, start, err)
+	}()
 	result := rs.asyncFetchRows()
 	if result.N == 0 {
 		rs.blockSize = 0
@@ -121,4 +133,9 @@ func (rs *rows) freeResult() {
 		async.PutHandler(rs.handler)
 		rs.handler = nil
 	}
+	if rs.span != nil {
+		rs.span.SetAttributes(common.Attribute{Key: common.AttrRows, Value: rs.rowsRead})
+		common.EndSpan(rs.span, rs.err)
+		rs.span = nil
+	}
 }
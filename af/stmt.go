@@ -6,6 +6,7 @@ import (
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/af/locker"
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/param"
 	taosError "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
@@ -15,6 +16,7 @@ type Stmt struct {
 	stmt       unsafe.Pointer
 	isInsert   bool
 	paramCount int
+	params     *common.Params
 }
 
 func NewStmt(taosConn unsafe.Pointer) *Stmt {
@@ -31,9 +33,18 @@ func NewStmtWithReqID(taosConn unsafe.Pointer, reqID int64) *Stmt {
 	return &Stmt{stmt: stmt}
 }
 
+// Prepare prepares sql, its placeholders are either all ? or @name, :name and $N placeholders, the table name
+// of an insert may be ? in both cases. With @name and :name placeholders SetTableNameWithTags and BindRow each take
+// a value per distinct name of the tags or the values in order of first appearance, with $N placeholders a value
+// per distinct $N in order of N. A parameter may be used more than once.
 func (s *Stmt) Prepare(sql string) error {
+	rewritten, params, err := common.ParseParams(sql)
+	if err != nil {
+		return err
+	}
+	s.params = params
 	locker.Lock()
-	code := wrapper.TaosStmtPrepare(s.stmt, sql)
+	code := wrapper.TaosStmtPrepare(s.stmt, rewritten)
 	locker.Unlock()
 	if code != 0 {
 		errStr := wrapper.TaosStmtErrStr(s.stmt)
@@ -55,6 +66,12 @@ func (s *Stmt) Prepare(sql string) error {
 }
 
 func (s *Stmt) SetTableNameWithTags(tableName string, tags *param.Param) error {
+	if s.params != nil && tags != nil {
+		if n := len(tags.GetValues()); n != s.params.Tags.NumArgs {
+			return fmt.Errorf("tag param count error : expect %d got %d", s.params.Tags.NumArgs, n)
+		}
+		tags = tags.Select(s.params.Tags.Index)
+	}
 	locker.Lock()
 	code := wrapper.TaosStmtSetTBNameTags(s.stmt, tableName, tags.GetValues())
 	locker.Unlock()
@@ -90,6 +107,12 @@ func (s *Stmt) BindRow(row *param.Param) error {
 	if row == nil {
 		return fmt.Errorf("row param got nil")
 	}
+	if s.params != nil {
+		if n := len(row.GetValues()); n != s.params.Values.NumArgs {
+			return fmt.Errorf("row param count error : expect %d got %d", s.params.Values.NumArgs, n)
+		}
+		row = row.Select(s.params.Values.Index)
+	}
 	value := row.GetValues()
 	if len(value) != s.paramCount {
 		return fmt.Errorf("row param count error : expect %d got %d", s.paramCount, len(value))
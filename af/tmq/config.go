@@ -3,12 +3,15 @@ package tmq
 import (
 	"unsafe"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
 )
 
 type config struct {
-	cConfig unsafe.Pointer
+	cConfig       unsafe.Pointer
+	lagIntervalMs int           // lag.interval.ms, handled by the driver
+	logger        common.Logger // logger, handled by the driver
 }
 
 func newConfig() *config {
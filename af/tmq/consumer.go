@@ -2,6 +2,8 @@ package tmq
 
 import (
 	"errors"
+	"fmt"
+	"time"
 	"unsafe"
 
 	jsoniter "github.com/json-iterator/go"
@@ -10,12 +12,28 @@ import (
 	"github.com/taosdata/driver-go/v3/common/tmq"
 	taosError "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
+	"github.com/taosdata/driver-go/v3/wrapper/cgo"
 )
 
 type Consumer struct {
 	cConsumer unsafe.Pointer
+	// the results of the commits made by enable.auto.commit, failures are logged
+	autoCommitChan   chan *wrapper.TMQCommitCallbackResult
+	autoCommitHandle cgo.Handle
+	rebalanceCb      RebalanceCb
+	assignment       tmq.AssignmentTracker
+	lagInterval      time.Duration
+	lastLag          time.Time
+	logger           common.Logger
 }
 
+// loggerKey is the ConfigMap key of the logger of the consumer, a common.Logger or the name of one registered
+// with common.RegisterLogger. It is handled by the driver and not sent to the server.
+const loggerKey = "logger"
+
+// assignmentCheckInterval is how often Poll checks the assignment for the RebalanceCb
+var assignmentCheckInterval = time.Second
+
 // NewConsumer Create new TMQ consumer with TMQ config
 func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 	confStruct, err := configMapToConfig(conf)
@@ -23,25 +41,67 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 		return nil, err
 	}
 	defer confStruct.destroy()
+	autoCommitChan := make(chan *wrapper.TMQCommitCallbackResult, 1)
+	autoCommitHandle := cgo.NewHandle(autoCommitChan)
+	wrapper.TMQConfSetAutoCommitCB(confStruct.cConfig, autoCommitHandle)
 	cConsumer, err := wrapper.TMQConsumerNew(confStruct.cConfig)
 	if err != nil {
+		autoCommitHandle.Delete()
 		return nil, err
 	}
 	consumer := &Consumer{
-		cConsumer: cConsumer,
+		cConsumer:        cConsumer,
+		autoCommitChan:   autoCommitChan,
+		autoCommitHandle: autoCommitHandle,
+		lagInterval:      time.Duration(confStruct.lagIntervalMs) * time.Millisecond,
+		logger:           confStruct.logger,
 	}
+	go consumer.logAutoCommit()
 	return consumer, nil
 }
 
+// log reports the failed auto commits and assignment, lag and rebalance checks to the logger of the consumer
+func (c *Consumer) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(c.logger, level, msg, keyvals...)
+}
+
+// logAutoCommit logs the failed auto commits until the consumer is closed
+func (c *Consumer) logAutoCommit() {
+	for r := range c.autoCommitChan {
+		if err := r.GetError(); err != nil {
+			c.log(common.LogLevelError, "auto commit failed", common.LogKeyError, err)
+		}
+		wrapper.PutTMQCommitCallbackResult(r)
+	}
+}
+
 func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	c := newConfig()
 	confCopy := m.Clone()
 	for k, v := range confCopy {
+		if k == loggerKey {
+			logger, err := tmq.ParseLogger(loggerKey, v)
+			if err != nil {
+				c.destroy()
+				return nil, err
+			}
+			c.logger = logger
+			continue
+		}
 		vv, ok := v.(string)
 		if !ok {
 			c.destroy()
 			return nil, errors.New("config value requires string")
 		}
+		if k == tmq.LagIntervalKey {
+			ms, err := tmq.ParseLagInterval(vv)
+			if err != nil {
+				c.destroy()
+				return nil, err
+			}
+			c.lagIntervalMs = ms
+			continue
+		}
 		err := c.setConfig(k, vv)
 		if err != nil {
 			c.destroy()
@@ -51,12 +111,15 @@ func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	return c, nil
 }
 
+// RebalanceCb is called by Poll with a tmq.AssignedPartitions or tmq.RevokedPartitions event when the vgroups
+// assigned to the consumer change, and by Unsubscribe with the vgroups it is about to lose.
 type RebalanceCb func(*Consumer, tmq.Event) error
 
 func (c *Consumer) Subscribe(topic string, rebalanceCb RebalanceCb) error {
 	return c.SubscribeTopics([]string{topic}, rebalanceCb)
 }
 
+// SubscribeTopics subscribes to topics, rebalanceCb is called when the assignment changes, it may be nil.
 func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) error {
 	topicList := wrapper.TMQListNew()
 	defer wrapper.TMQListDestroy(topicList)
@@ -72,11 +135,31 @@ func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) err
 		errStr := wrapper.TMQErr2Str(errCode)
 		return taosError.NewError(int(errCode), errStr)
 	}
+	c.rebalanceCb = rebalanceCb
+	c.assignment.Expire()
+	c.lastLag = time.Now()
 	return nil
 }
 
-// Unsubscribe TMQ unsubscribe
+// subscription returns the subscribed topics
+func (c *Consumer) subscription() ([]string, error) {
+	errCode, list := wrapper.TMQSubscription(c.cConsumer)
+	if errCode != taosError.SUCCESS {
+		errStr := wrapper.TMQErr2Str(errCode)
+		return nil, taosError.NewError(int(errCode), errStr)
+	}
+	defer wrapper.TMQListDestroy(list)
+	size := wrapper.TMQListGetSize(list)
+	return wrapper.TMQListToCArray(list, int(size)), nil
+}
+
+// Unsubscribe TMQ unsubscribe, the RebalanceCb is called first with the vgroups assigned so far
+// so that their offsets can still be committed.
 func (c *Consumer) Unsubscribe() error {
+	revoked := c.assignment.Reset()
+	if c.rebalanceCb != nil && len(revoked) != 0 {
+		c.callRebalanceCb(tmq.RevokedPartitions{Partitions: revoked})
+	}
 	errCode := wrapper.TMQUnsubscribe(c.cConsumer)
 	if errCode != taosError.SUCCESS {
 		errStr := wrapper.TMQErr2Str(errCode)
@@ -87,6 +170,68 @@ func (c *Consumer) Unsubscribe() error {
 
 // Poll consumer poll message with timeout
 func (c *Consumer) Poll(timeoutMs int) tmq.Event {
+	span := tmq.StartPollSpan()
+	start := time.Now()
+	ev := c.poll(timeoutMs)
+	var err error
+	if tmqErr, ok := ev.(tmq.Error); ok {
+		err = tmqErr
+	}
+	common.RecordRequest(nil, common.DriverAf, common.ActionPoll, start, err)
+	tmq.EndPollSpan(span, ev)
+	return ev
+}
+
+// PollBatch polls up to maxMessages messages within timeout, it returns early when no message arrives in time
+// or with a tmq.Error as the last event.
+func (c *Consumer) PollBatch(maxMessages int, timeout time.Duration) []tmq.Event {
+	return tmq.PollBatch(c.Poll, maxMessages, timeout)
+}
+
+// checkAssignment calls the RebalanceCb with the vgroups revoked and assigned since the last check
+func (c *Consumer) checkAssignment() {
+	if c.rebalanceCb == nil || !c.assignment.Due(assignmentCheckInterval) {
+		return
+	}
+	partitions, err := c.Assignment()
+	if err != nil {
+		c.log(common.LogLevelWarn, "get assignment failed", common.LogKeyError, err)
+		return
+	}
+	assigned, revoked := c.assignment.Update(partitions)
+	if len(revoked) != 0 {
+		c.callRebalanceCb(tmq.RevokedPartitions{Partitions: revoked})
+	}
+	if len(assigned) != 0 {
+		c.callRebalanceCb(tmq.AssignedPartitions{Partitions: assigned})
+	}
+}
+
+// lagEvent returns a tmq.ConsumerLag when lag.interval.ms has passed since the last one, nil otherwise
+func (c *Consumer) lagEvent() tmq.Event {
+	if c.lagInterval <= 0 || c.lastLag.IsZero() || time.Since(c.lastLag) < c.lagInterval {
+		return nil
+	}
+	partitions, err := c.Lag()
+	c.lastLag = time.Now()
+	if err != nil {
+		c.log(common.LogLevelWarn, "get lag failed", common.LogKeyError, err)
+		return nil
+	}
+	return tmq.ConsumerLag{Partitions: partitions}
+}
+
+func (c *Consumer) callRebalanceCb(ev tmq.Event) {
+	if err := c.rebalanceCb(c, ev); err != nil {
+		c.log(common.LogLevelWarn, "rebalance callback failed", common.LogKeyError, err)
+	}
+}
+
+func (c *Consumer) poll(timeoutMs int) tmq.Event {
+	c.checkAssignment()
+	if ev := c.lagEvent(); ev != nil {
+		return ev
+	}
 	message := wrapper.TMQConsumerPoll(c.cConsumer, int64(timeoutMs))
 	if message == nil {
 		return nil
@@ -103,6 +248,7 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 		result.SetTopic(topic)
 		data, err := c.getData(message)
 		if err != nil {
+			wrapper.TaosFreeResult(message)
 			return tmq.NewTMQErrorWithErr(err)
 		}
 		result.SetData(data)
@@ -120,6 +266,7 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 		result.SetTopic(topic)
 		meta, err := c.getMeta(message)
 		if err != nil {
+			wrapper.TaosFreeResult(message)
 			return tmq.NewTMQErrorWithErr(err)
 		}
 		result.SetMeta(meta)
@@ -138,10 +285,12 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 		result.SetOffset(offset)
 		data, err := c.getData(message)
 		if err != nil {
+			wrapper.TaosFreeResult(message)
 			return tmq.NewTMQErrorWithErr(err)
 		}
 		meta, err := c.getMeta(message)
 		if err != nil {
+			wrapper.TaosFreeResult(message)
 			return tmq.NewTMQErrorWithErr(err)
 		}
 		result.SetMetaData(&tmq.MetaData{
@@ -156,7 +305,8 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 		wrapper.TaosFreeResult(message)
 		return result
 	default:
-		return tmq.NewTMQError(0xfffff, "invalid tmq message type")
+		wrapper.TaosFreeResult(message)
+		return tmq.NewTMQError(tmq.ErrorOther, "invalid tmq message type")
 	}
 }
 
@@ -197,6 +347,8 @@ func (c *Consumer) getData(message unsafe.Pointer) ([]*tmq.Data, error) {
 		tmqData = append(tmqData, &tmq.Data{
 			TableName: tableName,
 			Data:      parser.ReadBlock(block, blockSize, rh.ColTypes, precision),
+			Fields:    tmq.NewFields(rh.ColNames, rh.ColTypes, rh.ColLength),
+			Precision: precision,
 		})
 	}
 	return tmqData, nil
@@ -225,14 +377,10 @@ func (c *Consumer) doCommit(message unsafe.Pointer) ([]tmq.TopicPartition, error
 }
 
 func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
-	errCode, list := wrapper.TMQSubscription(c.cConsumer)
-	if errCode != taosError.SUCCESS {
-		errStr := wrapper.TMQErr2Str(errCode)
-		return nil, taosError.NewError(int(errCode), errStr)
+	topics, err := c.subscription()
+	if err != nil {
+		return nil, err
 	}
-	defer wrapper.TMQListDestroy(list)
-	size := wrapper.TMQListGetSize(list)
-	topics := wrapper.TMQListToCArray(list, int(size))
 	for _, topic := range topics {
 		errCode, assignment := wrapper.TMQGetTopicAssignment(c.cConsumer, topic)
 		if errCode != taosError.SUCCESS {
@@ -251,6 +399,46 @@ func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
 	return partitions, nil
 }
 
+// Lag returns the committed offset, the position and the watermarks of every vgroup assigned to the consumer.
+func (c *Consumer) Lag() ([]tmq.PartitionLag, error) {
+	topics, err := c.subscription()
+	if err != nil {
+		return nil, err
+	}
+	var lags []tmq.PartitionLag
+	for _, topic := range topics {
+		errCode, assignment := wrapper.TMQGetTopicAssignment(c.cConsumer, topic)
+		if errCode != taosError.SUCCESS {
+			errStr := wrapper.TMQErr2Str(errCode)
+			return nil, taosError.NewError(int(errCode), errStr)
+		}
+		for i := 0; i < len(assignment); i++ {
+			committed := tmq.Offset(wrapper.TMQCommitted(c.cConsumer, topic, assignment[i].VGroupID))
+			if !committed.Valid() {
+				return nil, taosError.NewError(int(committed), wrapper.TMQErr2Str(int32(committed)))
+			}
+			lags = append(lags, tmq.NewPartitionLag(topic, assignment[i], committed))
+		}
+	}
+	return lags, nil
+}
+
+// WatermarkOffsets returns the first offset still stored and the offset of the next message written
+// of a vgroup assigned to the consumer.
+func (c *Consumer) WatermarkOffsets(topic string, vgroupID int32) (low, high int64, err error) {
+	errCode, assignment := wrapper.TMQGetTopicAssignment(c.cConsumer, topic)
+	if errCode != taosError.SUCCESS {
+		errStr := wrapper.TMQErr2Str(errCode)
+		return 0, 0, taosError.NewError(int(errCode), errStr)
+	}
+	for i := 0; i < len(assignment); i++ {
+		if assignment[i].VGroupID == vgroupID {
+			return assignment[i].Begin, assignment[i].End, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("vgroup %d of topic %s is not assigned", vgroupID, topic)
+}
+
 func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) error {
 	errCode := wrapper.TMQOffsetSeek(c.cConsumer, *partition.Topic, partition.Partition, int64(partition.Offset))
 	if errCode != taosError.SUCCESS {
@@ -307,6 +495,9 @@ func (c *Consumer) Position(partitions []tmq.TopicPartition) (offsets []tmq.Topi
 // Close release consumer
 func (c *Consumer) Close() error {
 	errCode := wrapper.TMQConsumerClose(c.cConsumer)
+	// no auto commit is made once the consumer is closed
+	close(c.autoCommitChan)
+	c.autoCommitHandle.Delete()
 	if errCode != 0 {
 		errStr := wrapper.TMQErr2Str(errCode)
 		return taosError.NewError(int(errCode), errStr)
@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "github.com/taosdata/driver-go/v3/taosRestful"
+	_ "github.com/taosdata/driver-go/v3/taosWS"
+)
+
+// TestBinaryTypesAcrossDrivers checks that taosSql, taosWS and taosRestful return the same
+// VARBINARY and GEOMETRY values for the same result set.
+func TestBinaryTypesAcrossDrivers(t *testing.T) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, sqlStr := range []string{
+		"drop database if exists test_binary_types",
+		"create database test_binary_types",
+		"create table test_binary_types.t (ts timestamp, vb varbinary(20), geo geometry(100))",
+		"insert into test_binary_types.t values('2023-10-01 00:00:00.000', '\\x0102ff', 'POINT(1.0 2.0)')" +
+			"('2023-10-01 00:00:00.001', 'abc', 'LINESTRING(1.0 1.0, 2.0 2.0)')" +
+			"('2023-10-01 00:00:00.002', null, null)",
+	} {
+		if _, err = db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.Exec("drop database if exists test_binary_types")
+
+	query := func(driver, dsn string) [][]interface{} {
+		conn, err := sql.Open(driver, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		rows, err := conn.Query("select vb, geo from test_binary_types.t order by ts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var result [][]interface{}
+		for rows.Next() {
+			var vb, geo []byte
+			if err = rows.Scan(&vb, &geo); err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, []interface{}{vb, geo})
+		}
+		assert.NoError(t, rows.Err())
+		return result
+	}
+	native := query(driverName, dataSourceName)
+	assert.Equal(t, 3, len(native))
+	assert.Equal(t, []byte{0x01, 0x02, 0xff}, native[0][0])
+	assert.Equal(t, []byte("abc"), native[1][0])
+	assert.Nil(t, native[2][0])
+	assert.Nil(t, native[2][1])
+	assert.Equal(t, native, query("taosWS", fmt.Sprintf("%s:%s@ws(%s:%d)/", user, password, "127.0.0.1", 6041)))
+	assert.Equal(t, native, query("taosRestful", fmt.Sprintf("%s:%s@http(%s:%d)/", user, password, "127.0.0.1", 6041)))
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	afTMQ "github.com/taosdata/driver-go/v3/af/tmq"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+	_ "github.com/taosdata/driver-go/v3/taosSql"
+	wsTMQ "github.com/taosdata/driver-go/v3/ws/tmq"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
+)
+
+// The WS and Native benchmarks read benchMessages messages, one per insert, from a topic of a local server with Poll
+// and with PollBatch:
+//
+//	go test -bench='WS|Native' -benchtime=10x
+const (
+	benchDB       = "bench_tmq_poll"
+	benchTopic    = "bench_tmq_poll_topic"
+	benchTables   = 10
+	benchMessages = 1000
+	batchSize     = 100
+)
+
+// The Fake benchmarks read fakeMessages messages of fakeRows rows from a fake taosAdapter answering each request
+// after fakeLatency, they need no server:
+//
+//	go test -bench=Fake -benchtime=5x -cpu 4
+//
+// Each message takes four round trips: poll, fetch, fetch_block and the fetch completing it. PollBatch sends the
+// poll of the next message before decoding the current one, so the server looks for that message while the client
+// decodes, about 3ms per message with the defaults. Measured on one core shared by the consumer and the fake
+// server, which limits the overlap:
+//
+//	BenchmarkFakeWSPoll-4        	       5	1705777638 ns/op
+//	BenchmarkFakeWSPollBatch-4   	       5	1564305319 ns/op
+const (
+	fakeLatency   = 5 * time.Millisecond
+	fakeMessages  = 50
+	fakeRows      = 10000
+	fakeBatchSize = 10
+)
+
+var (
+	prepareOnce sync.Once
+	prepareErr  error
+	groupID     uint64
+)
+
+func prepare(b *testing.B) {
+	prepareOnce.Do(func() {
+		db, err := sql.Open("taosSql", "root:taosdata@/tcp(localhost:6030)/")
+		if err != nil {
+			prepareErr = err
+			return
+		}
+		defer db.Close()
+		steps := []string{
+			"drop topic if exists " + benchTopic,
+			"drop database if exists " + benchDB,
+			"create database " + benchDB + " vgroups 1 WAL_RETENTION_PERIOD 86400",
+			"create stable " + benchDB + ".st (ts timestamp, c1 int, c2 float, c3 binary(10)) tags (t1 int)",
+		}
+		for i := 0; i < benchTables; i++ {
+			steps = append(steps, fmt.Sprintf("create table %s.ct%d using %s.st tags(%d)", benchDB, i, benchDB, i))
+		}
+		steps = append(steps, "create topic "+benchTopic+" as select ts, c1, c2, c3 from "+benchDB+".st")
+		now := time.Now().UnixNano() / 1e6
+		for i := 0; i < benchMessages; i++ {
+			steps = append(steps, fmt.Sprintf("insert into %s.ct%d values(%d,%d,%d.5,'v%d')", benchDB, i%benchTables, now+int64(i), i, i, i))
+		}
+		for _, step := range steps {
+			if _, err = db.Exec(step); err != nil {
+				prepareErr = err
+				return
+			}
+		}
+	})
+	if prepareErr != nil {
+		b.Fatal(prepareErr)
+	}
+}
+
+// nextGroupID returns a new consumer group so that each iteration reads the topic from the start
+func nextGroupID() string {
+	return "bench_" + strconv.FormatUint(atomic.AddUint64(&groupID, 1), 10)
+}
+
+type consumer interface {
+	Poll(timeoutMs int) tmq.Event
+	PollBatch(maxMessages int, timeout time.Duration) []tmq.Event
+	Close() error
+}
+
+// consume reads the topic until want messages are read with poll
+func consume(b *testing.B, want int, poll func() []tmq.Event) {
+	messages := 0
+	for messages < want {
+		events := poll()
+		if len(events) == 0 {
+			b.Fatalf("%d messages read, want %d", messages, want)
+		}
+		for _, ev := range events {
+			switch e := ev.(type) {
+			case *tmq.DataMessage:
+				messages += 1
+			case tmq.Error:
+				b.Fatal(e)
+			}
+		}
+	}
+}
+
+// benchmarkConsumer reads messages messages with PollBatch of batch messages, with Poll when batch is 0
+func benchmarkConsumer(b *testing.B, newConsumer func() (consumer, error), messages int, batch int) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c, err := newConsumer()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if batch != 0 {
+			consume(b, messages, func() []tmq.Event {
+				return c.PollBatch(batch, time.Second)
+			})
+		} else {
+			consume(b, messages, func() []tmq.Event {
+				ev := c.Poll(1000)
+				if ev == nil {
+					return nil
+				}
+				return []tmq.Event{ev}
+			})
+		}
+		b.StopTimer()
+		c.Close()
+		b.StartTimer()
+	}
+}
+
+func newWSConsumer(url string) (consumer, error) {
+	c, err := wsTMQ.NewConsumer(&tmq.ConfigMap{
+		"ws.url":             url,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           nextGroupID(),
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": "false",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Subscribe(benchTopic, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newNativeConsumer() (consumer, error) {
+	c, err := afTMQ.NewConsumer(&tmq.ConfigMap{
+		"td.connect.ip":      "127.0.0.1",
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"td.connect.port":    "6030",
+		"group.id":           nextGroupID(),
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": "false",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Subscribe(benchTopic, nil); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newLiveWSConsumer() (consumer, error) {
+	return newWSConsumer("ws://127.0.0.1:6041/rest/tmq")
+}
+
+// newFakeServer returns a fake taosAdapter answering each request after fakeLatency with fakeMessages messages
+// of fakeRows rows in benchTopic
+func newFakeServer() *wstest.Server {
+	s := wstest.NewServer()
+	s.SetLatency(fakeLatency)
+	fields := []wstest.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		{Name: "c1", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+		{Name: "c2", Type: common.TSDB_DATA_TYPE_FLOAT, Length: 4},
+		{Name: "c3", Type: common.TSDB_DATA_TYPE_BINARY, Length: 10},
+	}
+	now := time.Now()
+	messages := make([]wstest.Message, fakeMessages)
+	for i := range messages {
+		rows := make([][]driver.Value, fakeRows)
+		for j := range rows {
+			rows[j] = []driver.Value{now.Add(time.Duration(i*fakeRows+j) * time.Millisecond), int32(j), float32(j) + 0.5, "v" + strconv.Itoa(j)}
+		}
+		messages[i] = wstest.Message{
+			Database: benchDB,
+			VgroupID: 1,
+			Blocks:   []wstest.Block{{TableName: "ct" + strconv.Itoa(i%benchTables), Fields: fields, Rows: rows}},
+		}
+	}
+	s.Produce(benchTopic, messages...)
+	return s
+}
+
+func benchmarkFake(b *testing.B, batch int) {
+	s := newFakeServer()
+	defer s.Close()
+	benchmarkConsumer(b, func() (consumer, error) {
+		return newWSConsumer(s.URL() + "/rest/tmq")
+	}, fakeMessages, batch)
+}
+
+func BenchmarkFakeWSPoll(b *testing.B) {
+	benchmarkFake(b, 0)
+}
+
+func BenchmarkFakeWSPollBatch(b *testing.B) {
+	benchmarkFake(b, fakeBatchSize)
+}
+
+func BenchmarkWSPoll(b *testing.B) {
+	prepare(b)
+	benchmarkConsumer(b, newLiveWSConsumer, benchMessages, 0)
+}
+
+func BenchmarkWSPollBatch(b *testing.B) {
+	prepare(b)
+	benchmarkConsumer(b, newLiveWSConsumer, benchMessages, batchSize)
+}
+
+func BenchmarkNativePoll(b *testing.B) {
+	prepare(b)
+	benchmarkConsumer(b, newNativeConsumer, benchMessages, 0)
+}
+
+func BenchmarkNativePollBatch(b *testing.B) {
+	prepare(b)
+	benchmarkConsumer(b, newNativeConsumer, benchMessages, batchSize)
+}
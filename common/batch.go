@@ -0,0 +1,216 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+const insertInto = "insert into "
+
+// WriteBatch buffers the INSERT statements executed in a transaction of taosSql, taosWS and taosRestful,
+// they are merged into multi-table INSERT statements by Statements when BatchTx flushes them.
+type WriteBatch struct {
+	precision string
+	tables    []string // the part of each buffered statement after INSERT INTO
+}
+
+// NewWriteBatch returns an empty WriteBatch, arguments are interpolated with time precision, see InterpolateParamsWithPrecision.
+func NewWriteBatch(precision string) *WriteBatch {
+	return &WriteBatch{precision: precision}
+}
+
+// Add buffers query when it is an INSERT INTO ... statement, its arguments are interpolated even when the
+// interpolateParams DSN parameter is false. buffered is false for any other statement and for INSERT INTO ... SELECT,
+// the caller executes them after the buffered statements.
+func (b *WriteBatch) Add(query string, args []driver.NamedValue) (buffered bool, err error) {
+	tables, ok := insertTables(query)
+	if !ok {
+		return false, nil
+	}
+	if placeholders := scanPlaceholders(query); len(placeholders) != 0 && isTableName(query, placeholders[0]) {
+		// the argument would be interpolated as a string literal, not as a table name
+		return false, errors.New("the ? table name of an insert can not be buffered in a transaction")
+	}
+	if len(args) != 0 || hasPlaceholders(tables) {
+		tables, err = InterpolateParamsWithPrecision(tables, args, b.precision)
+		if err == driver.ErrSkip {
+			return false, errors.New("the number of arguments does not match the placeholders of the insert")
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	b.tables = append(b.tables, tables)
+	return true, nil
+}
+
+// Len returns the number of buffered statements.
+func (b *WriteBatch) Len() int {
+	return len(b.tables)
+}
+
+// Statements merges the buffered statements in order into INSERT INTO t1 VALUES (...) t2 VALUES (...) statements
+// of at most maxLen bytes, a buffered statement longer than maxLen is returned alone.
+func (b *WriteBatch) Statements(maxLen int) []string {
+	var statements []string
+	buf := &strings.Builder{}
+	for _, tables := range b.tables {
+		if buf.Len() != 0 && buf.Len()+1+len(tables) > maxLen {
+			statements = append(statements, buf.String())
+			buf.Reset()
+		}
+		if buf.Len() == 0 {
+			buf.WriteString(insertInto)
+		} else {
+			// a newline ends a -- comment of the previous statement
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(tables)
+	}
+	if buf.Len() != 0 {
+		statements = append(statements, buf.String())
+	}
+	return statements
+}
+
+// Reset discards the buffered statements.
+func (b *WriteBatch) Reset() {
+	b.tables = nil
+}
+
+// BatchTx is the transaction returned by BeginTx of taosSql, taosWS and taosRestful. TDengine has no transactions,
+// the INSERT statements of the transaction are buffered in a WriteBatch and executed merged on Commit. Any other
+// statement flushes the buffered statements before it runs, so the statements of the transaction keep their order.
+type BatchTx struct {
+	ctx   context.Context
+	batch *WriteBatch // nil once the transaction is committed or rolled back
+	exec  func(ctx context.Context, query string) error
+	err   error // the first error of a flush, the transaction has failed
+}
+
+// NewBatchTx returns a transaction executing its statements with exec on the connection that began it,
+// arguments are interpolated with time precision, see NewWriteBatch.
+func NewBatchTx(ctx context.Context, precision string, exec func(ctx context.Context, query string) error) *BatchTx {
+	return &BatchTx{ctx: ctx, batch: NewWriteBatch(precision), exec: exec}
+}
+
+// Add buffers query when it is an INSERT INTO ... statement, see WriteBatch.Add. For any other statement buffered
+// is false and the buffered statements are flushed, the caller then executes it. Once a flush has failed, Add
+// returns its error and the statement is not executed.
+func (t *BatchTx) Add(ctx context.Context, query string, args []driver.NamedValue) (buffered bool, err error) {
+	if t.batch == nil {
+		return false, nil
+	}
+	if t.err != nil {
+		return false, t.err
+	}
+	buffered, err = t.batch.Add(query, args)
+	if err != nil || buffered {
+		return buffered, err
+	}
+	return false, t.Flush(ctx)
+}
+
+// Flush executes the buffered statements merged into statements of at most MaxTaosSqlLen bytes, it is called
+// before a statement that is not buffered, such as a query or a prepared statement, runs in the transaction.
+// When a statement fails the ones after it are not executed and the statements executed before it are not
+// undone, the transaction has failed: the error is returned again by Add, Flush and Commit.
+func (t *BatchTx) Flush(ctx context.Context) error {
+	if t.batch == nil || t.err != nil || t.batch.Len() == 0 {
+		return t.err
+	}
+	statements := t.batch.Statements(MaxTaosSqlLen)
+	t.batch.Reset()
+	for _, query := range statements {
+		if err := t.exec(ctx, query); err != nil {
+			t.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit flushes the buffered statements and ends the transaction, it returns the error of a failed transaction.
+func (t *BatchTx) Commit() error {
+	if t.batch == nil {
+		return t.err
+	}
+	err := t.Flush(t.ctx)
+	t.batch = nil
+	return err
+}
+
+// Rollback discards the buffered statements and ends the transaction.
+func (t *BatchTx) Rollback() error {
+	t.batch = nil
+	return nil
+}
+
+// insertTables returns the part of an INSERT INTO statement after INSERT INTO, false for any other statement
+// and for INSERT INTO ... SELECT which cannot be merged.
+func insertTables(query string) (string, bool) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	rest, ok := cutKeyword(query, "insert")
+	if !ok {
+		return "", false
+	}
+	rest, ok = cutKeyword(rest, "into")
+	if !ok || rest == "" || hasKeyword(rest, "select") {
+		return "", false
+	}
+	return rest, true
+}
+
+// cutKeyword returns s without its leading keyword kw and the spaces after it
+func cutKeyword(s string, kw string) (string, bool) {
+	if len(s) <= len(kw) || !strings.EqualFold(s[:len(kw)], kw) || isNameChar(s[len(kw)]) {
+		return "", false
+	}
+	return strings.TrimLeft(s[len(kw):], " \t\r\n"), true
+}
+
+// hasKeyword reports whether kw is a word of query outside of quotes and comments
+func hasKeyword(query string, kw string) bool {
+	return len(keywordOffsets(query, kw)) != 0
+}
+
+// keywordOffsets returns the offsets of the words kw of query outside of quotes and comments
+func keywordOffsets(query string, kw string) []int {
+	var offsets []int
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return offsets
+			}
+			i += end
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				return offsets
+			}
+			i += end + 3
+		case isNameChar(c):
+			end := i + 1
+			for end < len(query) && isNameChar(query[end]) {
+				end++
+			}
+			if strings.EqualFold(query[i:end], kw) {
+				offsets = append(offsets, i)
+			}
+			i = end - 1
+		}
+	}
+	return offsets
+}
+
+func hasPlaceholders(query string) bool {
+	return len(scanPlaceholders(query)) != 0
+}
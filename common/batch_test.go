@@ -0,0 +1,131 @@
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBatch(t *testing.T) {
+	b := NewWriteBatch("ms")
+	for _, query := range []string{
+		"INSERT INTO t1 VALUES (1, 'a')",
+		"  insert\ninto t2 using st tags(1) values (2, 'b') (3, 'c');",
+		"insert into t3 values (4, 'select') -- comment",
+	} {
+		buffered, err := b.Add(query, nil)
+		assert.NoError(t, err)
+		assert.True(t, buffered, query)
+	}
+	buffered, err := b.Add("insert into t4 values (?, @v)", []driver.NamedValue{{Ordinal: 1, Value: int64(5)}, {Name: "v", Ordinal: 2, Value: "d"}})
+	assert.Error(t, err)
+	assert.False(t, buffered)
+	buffered, err = b.Add("insert into t4 values (?, ?)", []driver.NamedValue{{Ordinal: 1, Value: int64(5)}, {Ordinal: 2, Value: "it's"}})
+	assert.NoError(t, err)
+	assert.True(t, buffered)
+	_, err = b.Add("insert into t4 values (?, ?)", []driver.NamedValue{{Ordinal: 1, Value: int64(5)}})
+	assert.Error(t, err)
+
+	for _, query := range []string{
+		"select * from t1",
+		"insert into t1 select * from t2",
+		"create table t1 (ts timestamp, v int)",
+		"insert t1 values (1, 'a')",
+	} {
+		buffered, err = b.Add(query, nil)
+		assert.NoError(t, err)
+		assert.False(t, buffered, query)
+	}
+	assert.Equal(t, 4, b.Len())
+
+	assert.Equal(t, []string{
+		"insert into t1 VALUES (1, 'a')\n" +
+			"t2 using st tags(1) values (2, 'b') (3, 'c')\n" +
+			"t3 values (4, 'select') -- comment\n" +
+			"t4 values (5, 'it\\'s')",
+	}, b.Statements(MaxTaosSqlLen))
+
+	statements := b.Statements(70)
+	assert.Equal(t, []string{
+		"insert into t1 VALUES (1, 'a')",
+		"insert into t2 using st tags(1) values (2, 'b') (3, 'c')",
+		"insert into t3 values (4, 'select') -- comment\nt4 values (5, 'it\\'s')",
+	}, statements)
+	for _, statement := range statements {
+		assert.LessOrEqual(t, len(statement), 70)
+	}
+
+	// a statement longer than the limit is sent alone
+	long := "insert into t5 values (6, '" + strings.Repeat("x", 100) + "')"
+	_, err = b.Add(long, nil)
+	assert.NoError(t, err)
+	statements = b.Statements(70)
+	assert.Equal(t, long, statements[len(statements)-1])
+
+	b.Reset()
+	assert.Equal(t, 0, b.Len())
+	assert.Nil(t, b.Statements(MaxTaosSqlLen))
+}
+
+func TestBatchTx(t *testing.T) {
+	var executed []string
+	tx := NewBatchTx(context.Background(), "ms", func(ctx context.Context, query string) error {
+		executed = append(executed, query)
+		return nil
+	})
+	buffered, err := tx.Add(context.Background(), "insert into t1 values(1, 1)", nil)
+	assert.NoError(t, err)
+	assert.True(t, buffered)
+	assert.Empty(t, executed)
+
+	// the buffered inserts run before any other statement
+	buffered, err = tx.Add(context.Background(), "select * from t1", nil)
+	assert.NoError(t, err)
+	assert.False(t, buffered)
+	assert.Equal(t, []string{"insert into t1 values(1, 1)"}, executed)
+
+	_, err = tx.Add(context.Background(), "insert into t2 values(2, 2)", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"insert into t1 values(1, 1)", "insert into t2 values(2, 2)"}, executed)
+
+	// the transaction has ended
+	buffered, err = tx.Add(context.Background(), "insert into t3 values(3, 3)", nil)
+	assert.NoError(t, err)
+	assert.False(t, buffered)
+
+	executed = nil
+	tx = NewBatchTx(context.Background(), "ms", func(ctx context.Context, query string) error {
+		executed = append(executed, query)
+		return errors.New("exec failed")
+	})
+	// the merged statement is split in two, the second one is not executed after the first one failed
+	long := "insert into t1 values(1, '" + strings.Repeat("x", MaxTaosSqlLen/2) + "')"
+	for i := 0; i < 2; i++ {
+		_, err = tx.Add(context.Background(), long, nil)
+		assert.NoError(t, err)
+	}
+	assert.EqualError(t, tx.Flush(context.Background()), "exec failed")
+	assert.Equal(t, 1, len(executed))
+	// the transaction has failed, it reports the error until it ends
+	buffered, err = tx.Add(context.Background(), "insert into t2 values(2, 2)", nil)
+	assert.EqualError(t, err, "exec failed")
+	assert.False(t, buffered)
+	assert.EqualError(t, tx.Flush(context.Background()), "exec failed")
+	assert.EqualError(t, tx.Commit(), "exec failed")
+	assert.Equal(t, 1, len(executed))
+
+	tx = NewBatchTx(context.Background(), "ms", nil)
+	_, err = tx.Add(context.Background(), "insert into ? values(?)", []driver.NamedValue{{Ordinal: 1, Value: "t1"}, {Ordinal: 2, Value: int64(1)}})
+	assert.EqualError(t, err, "the ? table name of an insert can not be buffered in a transaction")
+	assert.NoError(t, tx.Commit())
+
+	tx = NewBatchTx(context.Background(), "ms", nil)
+	_, err = tx.Add(context.Background(), "insert into t1 values(1, 1)", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+}
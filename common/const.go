@@ -59,6 +59,9 @@ const (
 	Float64Size = unsafe.Sizeof(float64(0))
 )
 
+// ReqIDKey is the context key of the request ID.
+//
+// Deprecated: use WithReqID and ReqIDFrom, a string key may collide with other packages.
 const ReqIDKey = "taos_req_id"
 
 const (
@@ -0,0 +1,182 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Keys of the values logged by the drivers.
+const (
+	LogKeyReqID    = "req_id"
+	LogKeyEndpoint = "endpoint"
+	LogKeyError    = "error"
+	LogKeySQL      = "sql"
+	LogKeyDuration = "duration"
+	LogKeyAttempt  = "attempt"
+)
+
+// Logger receives the logs of the drivers: connection lifecycle, reconnect attempts, slow queries
+// and errors raised in background goroutines. keyvals are alternating keys and values, the keys are LogKey* constants.
+// Implementations must be safe for concurrent use, an adapter to a logging library is a few lines.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+type loggerHolder struct {
+	logger Logger
+}
+
+var globalLogger atomic.Value
+
+// SetLogger installs the Logger used by all drivers that have no Logger of their own, nil disables logging.
+func SetLogger(logger Logger) {
+	globalLogger.Store(loggerHolder{logger: logger})
+}
+
+// GetLogger returns the Logger installed with SetLogger, nil if there is none.
+func GetLogger() Logger {
+	holder, _ := globalLogger.Load().(loggerHolder)
+	return holder.logger
+}
+
+var (
+	loggerLock     sync.RWMutex
+	loggerRegistry map[string]Logger
+)
+
+// RegisterLogger registers a Logger to be used per connector by the `logger` DSN parameter of taosSql,
+// taosWS and taosRestful, by the `ws.logger` option of ws/tmq and by the `logger` option of af/tmq.
+func RegisterLogger(key string, logger Logger) error {
+	if key == "" {
+		return errors.New("logger key is empty")
+	}
+	if logger == nil {
+		return errors.New("logger is nil")
+	}
+	loggerLock.Lock()
+	if loggerRegistry == nil {
+		loggerRegistry = make(map[string]Logger)
+	}
+	loggerRegistry[key] = logger
+	loggerLock.Unlock()
+	return nil
+}
+
+// DeregisterLogger removes the Logger registered with key.
+func DeregisterLogger(key string) {
+	loggerLock.Lock()
+	delete(loggerRegistry, key)
+	loggerLock.Unlock()
+}
+
+// GetRegisteredLogger returns the Logger registered with key, nil for an empty key.
+func GetRegisteredLogger(key string) (Logger, error) {
+	if key == "" {
+		return nil, nil
+	}
+	loggerLock.RLock()
+	logger, exist := loggerRegistry[key]
+	loggerLock.RUnlock()
+	if !exist {
+		return nil, fmt.Errorf("logger '%s' is not registered", key)
+	}
+	return logger, nil
+}
+
+// Log writes an entry to logger, or to the global Logger when logger is nil.
+func Log(logger Logger, level LogLevel, msg string, keyvals ...interface{}) {
+	if logger == nil {
+		logger = GetLogger()
+		if logger == nil {
+			return
+		}
+	}
+	logger.Log(level, msg, keyvals...)
+}
+
+type textLogger struct {
+	lock  sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+// NewTextLogger returns a Logger writing the entries of level and above to w, one line per entry:
+//
+//	2006-01-02T15:04:05.000Z07:00 WARN slow query sql="select * from meters" req_id=4660 duration=1.2s
+func NewTextLogger(w io.Writer, level LogLevel) Logger {
+	return &textLogger{w: w, level: level}
+}
+
+func (l *textLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	if level < l.level {
+		return
+	}
+	b := &strings.Builder{}
+	b.WriteString(time.Now().Format("2006-01-02T15:04:05.000Z07:00"))
+	b.WriteByte(' ')
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(' ')
+		fmt.Fprint(b, keyvals[i])
+		b.WriteByte('=')
+		if i+1 >= len(keyvals) {
+			b.WriteString("<missing>")
+			break
+		}
+		writeLogValue(b, keyvals[i+1])
+	}
+	b.WriteByte('\n')
+	l.lock.Lock()
+	io.WriteString(l.w, b.String())
+	l.lock.Unlock()
+}
+
+func writeLogValue(b *strings.Builder, value interface{}) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \"=\n") {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(s)
+}
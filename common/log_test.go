@@ -0,0 +1,71 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	entries []string
+}
+
+func (l *recordingLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	l.entries = append(l.entries, level.String()+" "+msg)
+}
+
+func TestRegisterLogger(t *testing.T) {
+	assert.Error(t, RegisterLogger("", &recordingLogger{}))
+	assert.Error(t, RegisterLogger("l", nil))
+	l := &recordingLogger{}
+	assert.NoError(t, RegisterLogger("l", l))
+	got, err := GetRegisteredLogger("l")
+	assert.NoError(t, err)
+	assert.Equal(t, l, got)
+	got, err = GetRegisteredLogger("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	DeregisterLogger("l")
+	_, err = GetRegisteredLogger("l")
+	assert.Error(t, err)
+}
+
+func TestLog(t *testing.T) {
+	// nothing is logged without a logger
+	Log(nil, LogLevelInfo, "connected")
+
+	global := &recordingLogger{}
+	SetLogger(global)
+	defer SetLogger(nil)
+	own := &recordingLogger{}
+	Log(nil, LogLevelInfo, "connected")
+	Log(own, LogLevelWarn, "slow query")
+	assert.Equal(t, []string{"INFO connected"}, global.entries)
+	assert.Equal(t, []string{"WARN slow query"}, own.entries)
+}
+
+func TestTextLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewTextLogger(buf, LogLevelInfo)
+	l.Log(LogLevelDebug, "dropped")
+	l.Log(LogLevelWarn, "slow query",
+		LogKeySQL, "select * from meters",
+		LogKeyReqID, int64(4660),
+		LogKeyDuration, 1200*time.Millisecond,
+		LogKeyError, errors.New("a=b"),
+		"odd",
+	)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if !assert.Len(t, lines, 1) {
+		return
+	}
+	parts := strings.SplitN(lines[0], " ", 2)
+	_, err := time.Parse("2006-01-02T15:04:05.000Z07:00", parts[0])
+	assert.NoError(t, err)
+	assert.Equal(t, `WARN slow query sql="select * from meters" req_id=4660 duration=1.2s error="a=b" odd=<missing>`, parts[1])
+	assert.Equal(t, "LEVEL(9)", LogLevel(9).String())
+}
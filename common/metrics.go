@@ -0,0 +1,137 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drivers reported to Metrics.
+const (
+	DriverTaosSql     = "taosSql"
+	DriverTaosWS      = "taosWS"
+	DriverTaosRestful = "taosRestful"
+	DriverStmt        = "ws/stmt"
+	DriverTMQ         = "ws/tmq"
+	DriverSchemaless  = "ws/schemaless"
+	DriverAf          = "af"
+)
+
+// Directions of the bytes reported to Metrics.
+const (
+	BytesSent     = "sent"
+	BytesReceived = "received"
+)
+
+// Actions reported to Metrics by the drivers that do not speak the WebSocket protocol,
+// the WebSocket drivers report the action name of each message such as "query", "fetch_block" or "poll".
+const (
+	ActionQuery      = "query"
+	ActionFetchBlock = "fetch_block"
+	ActionPoll       = "poll"
+	ActionStmtExec   = "stmt_exec" // binding, adding the batch and executing a prepared statement
+	ActionSchemaless = "schemaless_insert"
+	ActionSQL        = "sql" // a request to /rest/sql
+)
+
+// Metrics collects what the drivers do, implementations must be safe for concurrent use.
+// The metrics package exposes them in Prometheus text format.
+type Metrics interface {
+	// ObserveRequest is called when a request to the server finishes, err is nil on success.
+	ObserveRequest(driver string, action string, duration time.Duration, err error)
+	// AddBytes is called with the size of the messages sent to and received from the server.
+	AddBytes(driver string, direction string, n int)
+	// IncReconnect is called when a connection is reestablished after it was lost.
+	IncReconnect(driver string)
+}
+
+type metricsHolder struct {
+	metrics Metrics
+}
+
+var globalMetrics atomic.Value
+
+// SetMetrics installs the Metrics used by all drivers that have no Metrics of their own, nil disables it.
+func SetMetrics(metrics Metrics) {
+	globalMetrics.Store(metricsHolder{metrics: metrics})
+}
+
+// GetMetrics returns the Metrics installed with SetMetrics, nil if there is none.
+func GetMetrics() Metrics {
+	holder, _ := globalMetrics.Load().(metricsHolder)
+	return holder.metrics
+}
+
+var (
+	metricsLock     sync.RWMutex
+	metricsRegistry map[string]Metrics
+)
+
+// RegisterMetrics registers a Metrics to be used per connector by the `metrics` DSN parameter of taosSql,
+// taosWS and taosRestful and by the `ws.metrics` option of ws/tmq.
+func RegisterMetrics(key string, metrics Metrics) error {
+	if key == "" {
+		return errors.New("metrics key is empty")
+	}
+	if metrics == nil {
+		return errors.New("metrics is nil")
+	}
+	metricsLock.Lock()
+	if metricsRegistry == nil {
+		metricsRegistry = make(map[string]Metrics)
+	}
+	metricsRegistry[key] = metrics
+	metricsLock.Unlock()
+	return nil
+}
+
+// DeregisterMetrics removes the Metrics registered with key.
+func DeregisterMetrics(key string) {
+	metricsLock.Lock()
+	delete(metricsRegistry, key)
+	metricsLock.Unlock()
+}
+
+// GetRegisteredMetrics returns the Metrics registered with key, nil for an empty key.
+func GetRegisteredMetrics(key string) (Metrics, error) {
+	if key == "" {
+		return nil, nil
+	}
+	metricsLock.RLock()
+	metrics, exist := metricsRegistry[key]
+	metricsLock.RUnlock()
+	if !exist {
+		return nil, fmt.Errorf("metrics '%s' is not registered", key)
+	}
+	return metrics, nil
+}
+
+func resolveMetrics(metrics Metrics) Metrics {
+	if metrics != nil {
+		return metrics
+	}
+	return GetMetrics()
+}
+
+// RecordRequest reports a request started at start to metrics, or to the global Metrics when metrics is nil.
+func RecordRequest(metrics Metrics, driver string, action string, start time.Time, err error) {
+	if metrics = resolveMetrics(metrics); metrics != nil {
+		metrics.ObserveRequest(driver, action, time.Since(start), err)
+	}
+}
+
+// RecordBytes reports n bytes to metrics, or to the global Metrics when metrics is nil.
+func RecordBytes(metrics Metrics, driver string, direction string, n int) {
+	if metrics = resolveMetrics(metrics); metrics != nil {
+		metrics.AddBytes(driver, direction, n)
+	}
+}
+
+// RecordReconnect reports a reconnection to metrics, or to the global Metrics when metrics is nil.
+func RecordReconnect(metrics Metrics, driver string) {
+	if metrics = resolveMetrics(metrics); metrics != nil {
+		metrics.IncReconnect(driver)
+	}
+}
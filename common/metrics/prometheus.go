@@ -0,0 +1,208 @@
+// Package metrics exposes the common.Metrics collected by the drivers in Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the request duration histogram.
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	requestsName  = "taos_driver_requests_total"
+	durationName  = "taos_driver_request_duration_seconds"
+	bytesName     = "taos_driver_bytes_total"
+	reconnectName = "taos_driver_reconnects_total"
+)
+
+type requestKey struct {
+	driver string
+	action string
+}
+
+type requestStats struct {
+	errors  uint64
+	buckets []uint64 // cumulative counts, one per bucket
+	count   uint64
+	sum     float64
+}
+
+type bytesKey struct {
+	driver    string
+	direction string
+}
+
+// Prometheus is a common.Metrics that serves what it collected as an http.Handler in Prometheus text format:
+//
+//	taos_driver_requests_total{driver,action,result="success|error"}
+//	taos_driver_request_duration_seconds{driver,action} (histogram)
+//	taos_driver_bytes_total{driver,direction="sent|received"}
+//	taos_driver_reconnects_total{driver}
+type Prometheus struct {
+	buckets    []float64
+	lock       sync.Mutex
+	requests   map[requestKey]*requestStats
+	bytes      map[bytesKey]uint64
+	reconnects map[string]uint64
+}
+
+// NewPrometheus returns a Prometheus using buckets as the upper bounds of the duration histogram, DefaultBuckets when empty.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	return &Prometheus{
+		buckets:    sorted,
+		requests:   map[requestKey]*requestStats{},
+		bytes:      map[bytesKey]uint64{},
+		reconnects: map[string]uint64{},
+	}
+}
+
+func (p *Prometheus) ObserveRequest(driver string, action string, duration time.Duration, err error) {
+	seconds := duration.Seconds()
+	key := requestKey{driver: driver, action: action}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	stats, exist := p.requests[key]
+	if !exist {
+		stats = &requestStats{buckets: make([]uint64, len(p.buckets))}
+		p.requests[key] = stats
+	}
+	if err != nil {
+		stats.errors += 1
+	}
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			stats.buckets[i] += 1
+		}
+	}
+	stats.count += 1
+	stats.sum += seconds
+}
+
+func (p *Prometheus) AddBytes(driver string, direction string, n int) {
+	p.lock.Lock()
+	p.bytes[bytesKey{driver: driver, direction: direction}] += uint64(n)
+	p.lock.Unlock()
+}
+
+func (p *Prometheus) IncReconnect(driver string) {
+	p.lock.Lock()
+	p.reconnects[driver] += 1
+	p.lock.Unlock()
+}
+
+// ServeHTTP writes the collected metrics in Prometheus text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	p.write(buf)
+	buf.Flush()
+}
+
+func (p *Prometheus) write(w *bufio.Writer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	requestKeys := make([]requestKey, 0, len(p.requests))
+	for key := range p.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		if requestKeys[i].driver != requestKeys[j].driver {
+			return requestKeys[i].driver < requestKeys[j].driver
+		}
+		return requestKeys[i].action < requestKeys[j].action
+	})
+	writeHeader(w, requestsName, "counter", "Requests sent to the server.")
+	for _, key := range requestKeys {
+		stats := p.requests[key]
+		labels := labelPairs("driver", key.driver, "action", key.action)
+		writeSample(w, requestsName, labels+`,result="success"`, float64(stats.count-stats.errors))
+		writeSample(w, requestsName, labels+`,result="error"`, float64(stats.errors))
+	}
+	writeHeader(w, durationName, "histogram", "Duration of the requests sent to the server.")
+	for _, key := range requestKeys {
+		stats := p.requests[key]
+		labels := labelPairs("driver", key.driver, "action", key.action)
+		for i, bound := range p.buckets {
+			writeSample(w, durationName+"_bucket", labels+`,le="`+formatFloat(bound)+`"`, float64(stats.buckets[i]))
+		}
+		writeSample(w, durationName+"_bucket", labels+`,le="+Inf"`, float64(stats.count))
+		writeSample(w, durationName+"_sum", labels, stats.sum)
+		writeSample(w, durationName+"_count", labels, float64(stats.count))
+	}
+
+	bytesKeys := make([]bytesKey, 0, len(p.bytes))
+	for key := range p.bytes {
+		bytesKeys = append(bytesKeys, key)
+	}
+	sort.Slice(bytesKeys, func(i, j int) bool {
+		if bytesKeys[i].driver != bytesKeys[j].driver {
+			return bytesKeys[i].driver < bytesKeys[j].driver
+		}
+		return bytesKeys[i].direction < bytesKeys[j].direction
+	})
+	writeHeader(w, bytesName, "counter", "Bytes sent to and received from the server.")
+	for _, key := range bytesKeys {
+		writeSample(w, bytesName, labelPairs("driver", key.driver, "direction", key.direction), float64(p.bytes[key]))
+	}
+
+	drivers := make([]string, 0, len(p.reconnects))
+	for driver := range p.reconnects {
+		drivers = append(drivers, driver)
+	}
+	sort.Strings(drivers)
+	writeHeader(w, reconnectName, "counter", "Connections reestablished after they were lost.")
+	for _, driver := range drivers {
+		writeSample(w, reconnectName, labelPairs("driver", driver), float64(p.reconnects[driver]))
+	}
+}
+
+func writeHeader(w *bufio.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeSample(w *bufio.Writer, name string, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// labelPairs formats name value pairs as name="value" separated by commas
+func labelPairs(pairs ...string) string {
+	b := &strings.Builder{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+var _ common.Metrics = (*Prometheus)(nil)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus(0.1, 0.01, 1)
+	p.ObserveRequest(common.DriverTaosWS, "query", 5*time.Millisecond, nil)
+	p.ObserveRequest(common.DriverTaosWS, "query", 50*time.Millisecond, errors.New("timeout"))
+	p.ObserveRequest(common.DriverTaosWS, "fetch_block", 2*time.Second, nil)
+	p.AddBytes(common.DriverTaosWS, common.BytesSent, 100)
+	p.AddBytes(common.DriverTaosWS, common.BytesSent, 20)
+	p.AddBytes(common.DriverTaosWS, common.BytesReceived, 4096)
+	p.IncReconnect(common.DriverTMQ)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body, err := ioutil.ReadAll(w.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP taos_driver_requests_total Requests sent to the server.
+# TYPE taos_driver_requests_total counter
+taos_driver_requests_total{driver="taosWS",action="fetch_block",result="success"} 1
+taos_driver_requests_total{driver="taosWS",action="fetch_block",result="error"} 0
+taos_driver_requests_total{driver="taosWS",action="query",result="success"} 1
+taos_driver_requests_total{driver="taosWS",action="query",result="error"} 1
+# HELP taos_driver_request_duration_seconds Duration of the requests sent to the server.
+# TYPE taos_driver_request_duration_seconds histogram
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="fetch_block",le="0.01"} 0
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="fetch_block",le="0.1"} 0
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="fetch_block",le="1"} 0
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="fetch_block",le="+Inf"} 1
+taos_driver_request_duration_seconds_sum{driver="taosWS",action="fetch_block"} 2
+taos_driver_request_duration_seconds_count{driver="taosWS",action="fetch_block"} 1
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="query",le="0.01"} 1
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="query",le="0.1"} 2
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="query",le="1"} 2
+taos_driver_request_duration_seconds_bucket{driver="taosWS",action="query",le="+Inf"} 2
+taos_driver_request_duration_seconds_sum{driver="taosWS",action="query"} 0.055
+taos_driver_request_duration_seconds_count{driver="taosWS",action="query"} 2
+# HELP taos_driver_bytes_total Bytes sent to and received from the server.
+# TYPE taos_driver_bytes_total counter
+taos_driver_bytes_total{driver="taosWS",direction="received"} 4096
+taos_driver_bytes_total{driver="taosWS",direction="sent"} 120
+# HELP taos_driver_reconnects_total Connections reestablished after they were lost.
+# TYPE taos_driver_reconnects_total counter
+taos_driver_reconnects_total{driver="ws/tmq"} 1
+`, string(body))
+}
+
+func TestLabelEscape(t *testing.T) {
+	assert.Equal(t, `driver="a\"b\\c\nd"`, labelPairs("driver", "a\"b\\c\nd"))
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingMetrics struct {
+	requests   map[string]int
+	bytes      map[string]int
+	reconnects int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{requests: map[string]int{}, bytes: map[string]int{}}
+}
+
+func (m *countingMetrics) ObserveRequest(driver string, action string, duration time.Duration, err error) {
+	m.requests[driver+" "+action] += 1
+}
+
+func (m *countingMetrics) AddBytes(driver string, direction string, n int) {
+	m.bytes[driver+" "+direction] += n
+}
+
+func (m *countingMetrics) IncReconnect(driver string) {
+	m.reconnects += 1
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	assert.Error(t, RegisterMetrics("", newCountingMetrics()))
+	assert.Error(t, RegisterMetrics("m", nil))
+	m := newCountingMetrics()
+	assert.NoError(t, RegisterMetrics("m", m))
+	got, err := GetRegisteredMetrics("m")
+	assert.NoError(t, err)
+	assert.Equal(t, m, got)
+	got, err = GetRegisteredMetrics("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	DeregisterMetrics("m")
+	_, err = GetRegisteredMetrics("m")
+	assert.Error(t, err)
+}
+
+func TestRecordMetrics(t *testing.T) {
+	// nothing is recorded without metrics
+	RecordRequest(nil, DriverTaosWS, ActionQuery, time.Now(), nil)
+
+	global := newCountingMetrics()
+	SetMetrics(global)
+	defer SetMetrics(nil)
+	own := newCountingMetrics()
+	RecordRequest(nil, DriverTaosWS, ActionQuery, time.Now(), nil)
+	RecordRequest(own, DriverTaosWS, ActionQuery, time.Now(), nil)
+	RecordBytes(own, DriverTaosWS, BytesSent, 10)
+	RecordReconnect(nil, DriverTMQ)
+	assert.Equal(t, map[string]int{"taosWS query": 1}, global.requests)
+	assert.Equal(t, 1, global.reconnects)
+	assert.Equal(t, map[string]int{"taosWS query": 1}, own.requests)
+	assert.Equal(t, map[string]int{"taosWS sent": 10}, own.bytes)
+}
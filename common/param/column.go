@@ -214,3 +214,20 @@ func (c *ColumnType) GetValue() ([]*types.ColumnType, error) {
 	}
 	return c.value, nil
 }
+
+// Select returns a ColumnType holding the columns at index in order, a column may be selected more than once.
+func (c *ColumnType) Select(index []int) (*ColumnType, error) {
+	value, err := c.GetValue()
+	if err != nil {
+		return nil, err
+	}
+	selected := NewColumnType(len(index))
+	for _, i := range index {
+		if i < 0 || i >= len(value) {
+			return nil, fmt.Errorf("column %d out of range, %d columns set", i, len(value))
+		}
+		selected.value[selected.column] = value[i]
+		selected.column += 1
+	}
+	return selected, nil
+}
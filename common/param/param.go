@@ -5,6 +5,7 @@ import (
 	"time"
 
 	taosTypes "github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
 )
 
 type Param struct {
@@ -149,6 +150,13 @@ func (p *Param) SetGeometry(offset int, value []byte) {
 	p.value[offset] = taosTypes.TaosGeometry(value)
 }
 
+func (p *Param) SetGeometryValue(offset int, value geometry.Geometry) {
+	if offset >= p.size {
+		return
+	}
+	p.value[offset] = taosTypes.TaosGeometry(geometry.Marshal(value))
+}
+
 func (p *Param) AddBool(value bool) *Param {
 	if p.offset >= p.size {
 		return p
@@ -314,6 +322,15 @@ func (p *Param) AddGeometry(value []byte) *Param {
 	return p
 }
 
+func (p *Param) AddGeometryValue(value geometry.Geometry) *Param {
+	if p.offset >= p.size {
+		return p
+	}
+	p.value[p.offset] = taosTypes.TaosGeometry(geometry.Marshal(value))
+	p.offset += 1
+	return p
+}
+
 func (p *Param) GetValues() []driver.Value {
 	return p.value
 }
@@ -326,3 +343,17 @@ func (p *Param) AddValue(value interface{}) *Param {
 	p.offset += 1
 	return p
 }
+
+// Select returns a Param holding the values at index in order, a value may be selected more than once.
+// An index out of range selects a null.
+func (p *Param) Select(index []int) *Param {
+	selected := NewParam(len(index))
+	for _, i := range index {
+		if i < 0 || i >= len(p.value) {
+			selected.AddNull()
+			continue
+		}
+		selected.AddValue(p.value[i])
+	}
+	return selected
+}
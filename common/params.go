@@ -0,0 +1,277 @@
+package common
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Params maps the ? placeholders of a query rewritten by ParseParams to its arguments.
+type Params struct {
+	// Names are the distinct parameters of @name and :name placeholders in order of first appearance,
+	// empty for $N placeholders
+	Names []string
+	// Index is the argument bound to each ? other than the table name, an index into Names or N-1 for $N
+	Index []int
+	// TableName is true when the first ? is the table name of an INSERT, set with SetTableName and not bound
+	TableName bool
+	// Tags are the placeholders of the TAGS (...) clause of an INSERT, set with SetTags
+	Tags Binding
+	// Values are the other placeholders, bound with BindParam or BindRow
+	Values Binding
+}
+
+// Binding maps the ? placeholders of the tags or the values of a query to the arguments of a stmt call,
+// a value per distinct name in order of first appearance or per $N in order of N.
+type Binding struct {
+	// Index is the argument bound to each ?
+	Index []int
+	// NumArgs is the number of arguments
+	NumArgs int
+}
+
+// ParseParams replaces the @name, :name or $N placeholders of query with ? so that it can be prepared by the server,
+// a parameter used more than once takes the same argument each time. params is nil when query has no such placeholder.
+// One kind of placeholder is resolved: ? when the query has any other than the ? table name of an INSERT, then $N,
+// then @name and :name. The placeholders of the other kinds are left as literal text, such as the :00 of a time.
+func ParseParams(query string) (rewritten string, params *Params, err error) {
+	return parseParams(query, false)
+}
+
+// parseParams is ParseParams resolving the @name and :name placeholders when namedArgs is true,
+// for the arguments of InterpolateParams given by name.
+func parseParams(query string, namedArgs bool) (rewritten string, params *Params, err error) {
+	placeholders := scanPlaceholders(query)
+	tableName := len(placeholders) != 0 && isTableName(query, placeholders[0])
+	all := placeholders
+	if tableName {
+		all = placeholders[1:]
+	}
+	var positional, named, numbered bool
+	for _, p := range all {
+		switch {
+		case p.name != "":
+			named = true
+		case p.number != 0:
+			numbered = true
+		default:
+			positional = true
+		}
+	}
+	switch {
+	case namedArgs && positional:
+		return "", nil, errors.New("arguments given by name can not be bound to the ? placeholders of the query")
+	case namedArgs && !named:
+		return "", nil, errors.New("arguments given by name need @name or :name placeholders")
+	case !namedArgs && (positional || (!named && !numbered)):
+		return query, nil, nil
+	}
+	resolveNumbered := !namedArgs && numbered
+	var args []placeholder
+	for _, p := range all {
+		if (resolveNumbered && p.number != 0) || (!resolveNumbered && p.name != "") {
+			args = append(args, p)
+		}
+	}
+	params = &Params{Index: make([]int, len(args)), TableName: tableName}
+	names := map[string]int{}
+	tags := tagsClauses(query)
+	var tagArgs, valueArgs []placeholder
+	b := &strings.Builder{}
+	b.Grow(len(query))
+	last := 0
+	if tableName {
+		b.WriteString(query[:placeholders[0].offset+1])
+		last = placeholders[0].offset + 1
+	}
+	for i, p := range args {
+		b.WriteString(query[last:p.offset])
+		b.WriteByte('?')
+		last = p.offset + p.length
+		if inClauses(tags, p.offset) {
+			tagArgs = append(tagArgs, p)
+		} else {
+			valueArgs = append(valueArgs, p)
+		}
+		if p.number != 0 {
+			// every $N up to the largest one must be given, so N can not exceed the placeholders
+			if p.number > len(args) {
+				return "", nil, fmt.Errorf("placeholder $%d exceeds the %d placeholders of the query", p.number, len(args))
+			}
+			params.Index[i] = p.number - 1
+			continue
+		}
+		index, exist := names[p.name]
+		if !exist {
+			index = len(params.Names)
+			names[p.name] = index
+			params.Names = append(params.Names, p.name)
+		}
+		params.Index[i] = index
+	}
+	b.WriteString(query[last:])
+	params.Tags = newBinding(tagArgs)
+	params.Values = newBinding(valueArgs)
+	return b.String(), params, nil
+}
+
+// newBinding numbers the distinct parameters of placeholders, names in order of first appearance and $N in order of N
+func newBinding(placeholders []placeholder) Binding {
+	binding := Binding{Index: make([]int, len(placeholders))}
+	args := map[string]int{}
+	var numbers []int
+	for _, p := range placeholders {
+		if _, exist := args[p.key()]; exist {
+			continue
+		}
+		args[p.key()] = len(args)
+		if p.number != 0 {
+			numbers = append(numbers, p.number)
+		}
+	}
+	if len(numbers) != 0 {
+		sort.Ints(numbers)
+		for i, number := range numbers {
+			args["$"+strconv.Itoa(number)] = i
+		}
+	}
+	for i, p := range placeholders {
+		binding.Index[i] = args[p.key()]
+	}
+	binding.NumArgs = len(args)
+	return binding
+}
+
+// isTableName reports whether p is the ? table name of INSERT INTO ?
+func isTableName(query string, p placeholder) bool {
+	if p.name != "" || p.number != 0 {
+		return false
+	}
+	words := strings.Fields(query[:p.offset])
+	return len(words) == 2 && strings.EqualFold(words[0], "insert") && strings.EqualFold(words[1], "into")
+}
+
+// tagsClauses returns the offsets of the parentheses of the TAGS (...) clauses of query
+func tagsClauses(query string) [][2]int {
+	var clauses [][2]int
+	for _, offset := range keywordOffsets(query, "tags") {
+		open := offset + len("tags")
+		for open < len(query) && (query[open] == ' ' || query[open] == '\t' || query[open] == '\r' || query[open] == '\n') {
+			open++
+		}
+		if open == len(query) || query[open] != '(' {
+			continue
+		}
+		clauses = append(clauses, [2]int{open, closingParen(query, open)})
+	}
+	return clauses
+}
+
+func inClauses(clauses [][2]int, offset int) bool {
+	for _, c := range clauses {
+		if offset > c[0] && offset < c[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// closingParen returns the offset of the parenthesis closing the one at open, len(query) when it is not closed
+func closingParen(query string, open int) int {
+	depth := 0
+	for i := open; i < len(query); i++ {
+		switch query[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(query, i)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(query)
+}
+
+// NumArgs returns the number of arguments of the query, the number of names or the largest N of $N.
+func (p *Params) NumArgs() int {
+	if len(p.Names) != 0 {
+		return len(p.Names)
+	}
+	n := 0
+	for _, index := range p.Index {
+		if index >= n {
+			n = index + 1
+		}
+	}
+	return n
+}
+
+// ArgIndex returns the argument index of arg, see Index. A named arg is matched by name,
+// an unnamed one by its Ordinal so that @name placeholders can also take positional arguments.
+func (p *Params) ArgIndex(arg driver.NamedValue) (int, error) {
+	if arg.Name == "" {
+		if arg.Ordinal < 1 || arg.Ordinal > p.NumArgs() {
+			return 0, fmt.Errorf("argument $%d is not used by the query", arg.Ordinal)
+		}
+		return arg.Ordinal - 1, nil
+	}
+	for i, name := range p.Names {
+		if name == arg.Name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("argument @%s is not used by the query", arg.Name)
+}
+
+// Position returns the position of the first ? bound to arg, -1 if there is none.
+func (p *Params) Position(arg driver.NamedValue) int {
+	index, err := p.ArgIndex(arg)
+	if err != nil {
+		return -1
+	}
+	for i, argIndex := range p.Index {
+		if argIndex == index {
+			return i
+		}
+	}
+	return -1
+}
+
+// Bind returns args in the order of the ? of the query, with their Ordinal set to the position of the ?.
+func (p *Params) Bind(args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if p.TableName {
+		return nil, errors.New("the ? table name of the query can not be bound to an argument")
+	}
+	values := make([]*driver.NamedValue, p.NumArgs())
+	for i := range args {
+		index, err := p.ArgIndex(args[i])
+		if err != nil {
+			return nil, err
+		}
+		if values[index] != nil {
+			return nil, fmt.Errorf("argument %s is given twice", p.argName(index))
+		}
+		values[index] = &args[i]
+	}
+	bound := make([]driver.NamedValue, len(p.Index))
+	for i, index := range p.Index {
+		if values[index] == nil {
+			return nil, fmt.Errorf("missing argument %s", p.argName(index))
+		}
+		bound[i] = driver.NamedValue{Name: values[index].Name, Ordinal: i + 1, Value: values[index].Value}
+	}
+	return bound, nil
+}
+
+func (p *Params) argName(index int) string {
+	if len(p.Names) != 0 {
+		return "@" + p.Names[index]
+	}
+	return "$" + strconv.Itoa(index+1)
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		want      string
+		wantNames []string
+		wantIndex []int
+		wantErr   bool
+	}{
+		{
+			name:  "positional",
+			query: "select * from t where a = ? and b = ?",
+			want:  "select * from t where a = ? and b = ?",
+		},
+		{
+			name:      "named",
+			query:     "select * from t where a = @a and b = :b and c > @a",
+			want:      "select * from t where a = ? and b = ? and c > ?",
+			wantNames: []string{"a", "b"},
+			wantIndex: []int{0, 1, 0},
+		},
+		{
+			name:      "numbered",
+			query:     "select * from t where a = $2 and b = $1 and c > $2",
+			want:      "select * from t where a = ? and b = ? and c > ?",
+			wantIndex: []int{1, 0, 1},
+		},
+		{
+			name:  "not placeholders",
+			query: "select * from t where a = '@a' and `:b` = 1 and c = b::int -- $1",
+			want:  "select * from t where a = '@a' and `:b` = 1 and c = b::int -- $1",
+		},
+		{
+			name:  "positional with named and numbered",
+			query: "select * from t where a = ? and b = @b and c = $1",
+			want:  "select * from t where a = ? and b = @b and c = $1",
+		},
+		{
+			name:      "numbered with named",
+			query:     "select * from t where a = $1 and b = @b",
+			want:      "select * from t where a = ? and b = @b",
+			wantIndex: []int{0},
+		},
+		{
+			name:  "positional after the table name",
+			query: "insert into ? values(?, @v)",
+			want:  "insert into ? values(?, @v)",
+		},
+		{
+			name:    "number exceeds the placeholders",
+			query:   "select * from t where a = $999999999",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, params, err := ParseParams(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			if tt.wantIndex == nil {
+				assert.Nil(t, params)
+				return
+			}
+			assert.Equal(t, tt.wantNames, params.Names)
+			assert.Equal(t, tt.wantIndex, params.Index)
+		})
+	}
+}
+
+func TestParamsBind(t *testing.T) {
+	_, params, err := ParseParams("select * from t where a = @a and b = @b and c > @a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, params.NumArgs())
+	bound, err := params.Bind([]driver.NamedValue{
+		{Name: "b", Ordinal: 1, Value: "b"},
+		{Name: "a", Ordinal: 2, Value: int64(1)},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []driver.NamedValue{
+			{Name: "a", Ordinal: 1, Value: int64(1)},
+			{Name: "b", Ordinal: 2, Value: "b"},
+			{Name: "a", Ordinal: 3, Value: int64(1)},
+		}, bound)
+	}
+	// unnamed arguments take the names in order of first appearance
+	bound, err = params.Bind([]driver.NamedValue{{Ordinal: 1, Value: int64(1)}, {Ordinal: 2, Value: "b"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), bound[2].Value)
+	}
+	assert.Equal(t, 1, params.Position(driver.NamedValue{Name: "b"}))
+	assert.Equal(t, -1, params.Position(driver.NamedValue{Name: "c"}))
+
+	_, err = params.Bind([]driver.NamedValue{{Name: "a", Ordinal: 1, Value: int64(1)}})
+	assert.EqualError(t, err, "missing argument @b")
+	_, err = params.Bind([]driver.NamedValue{{Name: "c", Ordinal: 1, Value: int64(1)}})
+	assert.EqualError(t, err, "argument @c is not used by the query")
+	_, err = params.Bind([]driver.NamedValue{{Name: "a", Ordinal: 1}, {Ordinal: 1}})
+	assert.EqualError(t, err, "argument @a is given twice")
+
+	_, params, err = ParseParams("select * from t where a = $2 and b = $1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = params.Bind([]driver.NamedValue{{Ordinal: 1}})
+	assert.EqualError(t, err, "missing argument $2")
+}
+
+func TestParseParamsInsert(t *testing.T) {
+	got, params, err := ParseParams("insert into ? values(@ts, @v)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "insert into ? values(?, ?)", got)
+	assert.True(t, params.TableName)
+	assert.Equal(t, []int{0, 1}, params.Index)
+	assert.Equal(t, Binding{Index: []int{0, 1}, NumArgs: 2}, params.Values)
+	assert.Equal(t, 0, params.Tags.NumArgs)
+	_, err = params.Bind([]driver.NamedValue{{Name: "ts", Ordinal: 1}, {Name: "v", Ordinal: 2}})
+	assert.Error(t, err)
+
+	got, params, err = ParseParams("insert into t1 using st tags(@t, 'a(b') values(@ts, @v, @t)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "insert into t1 using st tags(?, 'a(b') values(?, ?, ?)", got)
+	assert.False(t, params.TableName)
+	assert.Equal(t, 3, params.NumArgs())
+	assert.Equal(t, Binding{Index: []int{0}, NumArgs: 1}, params.Tags)
+	assert.Equal(t, Binding{Index: []int{0, 1, 2}, NumArgs: 3}, params.Values)
+
+	got, params, err = ParseParams("insert into ? using st TAGS ($3) values($2, $1, $2)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "insert into ? using st TAGS (?) values(?, ?, ?)", got)
+	assert.Equal(t, []int{2, 1, 0, 1}, params.Index)
+	assert.Equal(t, Binding{Index: []int{0}, NumArgs: 1}, params.Tags)
+	assert.Equal(t, Binding{Index: []int{1, 0, 1}, NumArgs: 2}, params.Values)
+
+	// a query without tags binds every placeholder as a value
+	_, params, err = ParseParams("select * from t where a = @a and b = @b and c > @a")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Binding{Index: []int{0, 1, 0}, NumArgs: 2}, params.Values)
+}
+
+func TestInterpolateNamedParams(t *testing.T) {
+	args := func(values ...interface{}) []driver.NamedValue {
+		named := make([]driver.NamedValue, len(values))
+		for i, v := range values {
+			if arg, ok := v.(sql.NamedArg); ok {
+				named[i] = driver.NamedValue{Name: arg.Name, Ordinal: i + 1, Value: arg.Value}
+			} else {
+				named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+			}
+		}
+		return named
+	}
+	got, err := InterpolateParams("select * from t where a = @a and b = :b or a = @a",
+		args(sql.Named("b", "x"), sql.Named("a", int64(1))))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select * from t where a = 1 and b = 'x' or a = 1", got)
+	}
+	got, err = InterpolateParams("select * from t where a = $2 and b = $1", args("x", int64(1)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select * from t where a = 1 and b = 'x'", got)
+	}
+	_, err = InterpolateParams("select * from t where a = @a", args(sql.Named("b", "x")))
+	assert.Error(t, err)
+	_, err = InterpolateParams("select * from t where a = ? and b = @b", args(int64(1), sql.Named("b", "x")))
+	assert.Error(t, err)
+
+	// the @name, :name and $N of a query with ? are literal text
+	got, err = InterpolateParams("select * from t where a = ? and b = :x", args(int64(1)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select * from t where a = 1 and b = :x", got)
+	}
+	got, err = InterpolateParams("select * from t where a = ? and b = @x and c = $1", args(int64(1)))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select * from t where a = 1 and b = @x and c = $1", got)
+	}
+	// as are those of a query without arguments
+	got, err = InterpolateParams("select * from t where b = :x", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select * from t where b = :x", got)
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"math/bits"
 	"os"
 	"sync/atomic"
@@ -27,6 +28,38 @@ func GetReqID() int64 {
 	return tUUIDHashId | pid | ((ts & 0x3ffffff) << 20) | (val & 0xfffff)
 }
 
+type reqIDKey struct{}
+
+// WithReqID returns a copy of ctx carrying reqID, the request ID the drivers send with the statements run in ctx.
+func WithReqID(ctx context.Context, reqID int64) context.Context {
+	return context.WithValue(ctx, reqIDKey{}, reqID)
+}
+
+// ReqIDFrom returns the request ID set with WithReqID, or stored under the legacy ReqIDKey as an int64 or uint64.
+func ReqIDFrom(ctx context.Context) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	if reqID, ok := ctx.Value(reqIDKey{}).(int64); ok && reqID != 0 {
+		return reqID, true
+	}
+	switch reqID := ctx.Value(ReqIDKey).(type) {
+	case int64:
+		return reqID, reqID != 0
+	case uint64:
+		return int64(reqID), reqID != 0
+	}
+	return 0, false
+}
+
+// GetReqIDFromContext returns the request ID of ctx (see ReqIDFrom), or a new one from GetReqID when ctx carries none.
+func GetReqIDFromContext(ctx context.Context) int64 {
+	if reqID, ok := ReqIDFrom(ctx); ok {
+		return reqID
+	}
+	return GetReqID()
+}
+
 const (
 	c1 uint32 = 0xcc9e2d51
 	c2 uint32 = 0x1b873593
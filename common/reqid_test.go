@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"testing"
 )
 
@@ -39,3 +40,24 @@ func TestMurmurHash(t *testing.T) {
 		t.Fatal("fail")
 	}
 }
+
+func TestGetReqIDFromContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ReqIDKey, int64(123))
+	if GetReqIDFromContext(ctx) != 123 {
+		t.Fatal("want the request ID of the context")
+	}
+	ctx = context.WithValue(context.Background(), ReqIDKey, uint64(456))
+	if GetReqIDFromContext(ctx) != 456 {
+		t.Fatal("want the request ID of the context")
+	}
+	ctx = WithReqID(ctx, 789)
+	if GetReqIDFromContext(ctx) != 789 {
+		t.Fatal("want the request ID set with WithReqID")
+	}
+	if _, ok := ReqIDFrom(context.Background()); ok {
+		t.Fatal("want no request ID")
+	}
+	if GetReqIDFromContext(context.Background()) == 0 {
+		t.Fatal("want a generated request ID")
+	}
+}
@@ -2,88 +2,306 @@ package common
 
 import (
 	"database/sql/driver"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
 )
 
+// InterpolateParams replaces the ? placeholders of query with args, see InterpolateParamsWithPrecision.
+// time.Time arguments are written as RFC 3339 strings.
 func InterpolateParams(query string, args []driver.NamedValue) (string, error) {
-	// Number of ? should be same to len(args)
-	if strings.Count(query, "?") != len(args) {
+	return InterpolateParamsWithPrecision(query, args, "")
+}
+
+// InterpolateParamsWithPrecision replaces the placeholders of query with args, @name, :name and $N placeholders
+// are resolved with ParseParams. Placeholders inside string literals, quoted identifiers and comments are not
+// replaced. Strings are quoted and escaped, []byte is written as a VARBINARY hex literal, floats are written with
+// the fewest digits that read back to the same value and time.Time as an integer timestamp of precision (ms, us
+// or ns), or as an RFC 3339 string when precision is empty. driver.ErrSkip is returned when the number of
+// placeholders is not len(args) or an argument has an unsupported type.
+func InterpolateParamsWithPrecision(query string, args []driver.NamedValue, precision string) (string, error) {
+	timePrecision := -1
+	if precision != "" {
+		p, err := ParsePrecision(precision)
+		if err != nil {
+			return "", err
+		}
+		timePrecision = p
+	}
+	if len(args) != 0 {
+		// without arguments there is nothing to resolve, @name, :name and $N are literal text
+		namedArgs := false
+		for _, arg := range args {
+			if arg.Name != "" {
+				namedArgs = true
+				break
+			}
+		}
+		var params *Params
+		var err error
+		query, params, err = parseParams(query, namedArgs)
+		if err != nil {
+			return "", err
+		}
+		if params != nil {
+			if args, err = params.Bind(args); err != nil {
+				return "", err
+			}
+		}
+	}
+	placeholders := positionalPlaceholders(query)
+	if len(placeholders) != len(args) {
 		return "", driver.ErrSkip
 	}
 	buf := &strings.Builder{}
-	argPos := 0
+	buf.Grow(len(query))
+	last := 0
+	for i, p := range placeholders {
+		buf.WriteString(query[last:p.offset])
+		last = p.offset + p.length
+		if err := WriteValue(buf, args[i].Value, timePrecision); err != nil {
+			return "", err
+		}
+		if buf.Len() > MaxTaosSqlLen {
+			return "", errors.New("sql statement exceeds the maximum length")
+		}
+	}
+	buf.WriteString(query[last:])
+	return buf.String(), nil
+}
+
+// ParsePrecision parses the time precision ms, us or ns into PrecisionMilliSecond, PrecisionMicroSecond
+// or PrecisionNanoSecond.
+func ParsePrecision(precision string) (int, error) {
+	switch precision {
+	case "ms":
+		return PrecisionMilliSecond, nil
+	case "us":
+		return PrecisionMicroSecond, nil
+	case "ns":
+		return PrecisionNanoSecond, nil
+	}
+	return 0, fmt.Errorf("invalid time precision %q, must be ms, us or ns", precision)
+}
+
+// placeholder is a ?, @name, :name or $N in a query
+type placeholder struct {
+	offset int
+	length int
+	name   string // the name of @name and :name
+	number int    // the N of $N
+}
 
+// key identifies the parameter of a named or numbered placeholder
+func (p placeholder) key() string {
+	if p.number != 0 {
+		return "$" + strconv.Itoa(p.number)
+	}
+	return p.name
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// scanPlaceholders returns the placeholders of query. String literals quoted with ' or ", identifiers quoted
+// with ` and -- or /* */ comments are skipped, an unterminated one runs to the end of query.
+func scanPlaceholders(query string) []placeholder {
+	var placeholders []placeholder
 	for i := 0; i < len(query); i++ {
-		q := strings.IndexByte(query[i:], '?')
-		if q == -1 {
-			buf.WriteString(query[i:])
-			break
+		switch query[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(query, i)
+		case '-':
+			if i+1 < len(query) && query[i+1] == '-' {
+				end := strings.IndexByte(query[i:], '\n')
+				if end == -1 {
+					return placeholders
+				}
+				i += end
+			}
+		case '/':
+			if i+1 < len(query) && query[i+1] == '*' {
+				end := strings.Index(query[i+2:], "*/")
+				if end == -1 {
+					return placeholders
+				}
+				i += end + 3
+			}
+		case '?':
+			placeholders = append(placeholders, placeholder{offset: i, length: 1})
+		case '@', ':':
+			if i+1 == len(query) || !isNameStart(query[i+1]) || (i > 0 && (isNameChar(query[i-1]) || query[i-1] == ':')) {
+				// an email, a cast b::int or a time 12:00
+				continue
+			}
+			end := i + 2
+			for end < len(query) && isNameChar(query[end]) {
+				end++
+			}
+			placeholders = append(placeholders, placeholder{offset: i, length: end - i, name: query[i+1 : end]})
+			i = end - 1
+		case '$':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+			if end == i+1 {
+				continue
+			}
+			number, err := strconv.Atoi(query[i+1 : end])
+			if err != nil || number == 0 {
+				continue
+			}
+			placeholders = append(placeholders, placeholder{offset: i, length: end - i, number: number})
+			i = end - 1
 		}
-		buf.WriteString(query[i : i+q])
-		i += q
-
-		arg := args[argPos].Value
-		argPos++
+	}
+	return placeholders
+}
 
-		if arg == nil {
-			buf.WriteString("NULL")
-			continue
+// positionalPlaceholders returns the ? placeholders of query, its other placeholders are literal text
+func positionalPlaceholders(query string) []placeholder {
+	var positional []placeholder
+	for _, p := range scanPlaceholders(query) {
+		if p.name == "" && p.number == 0 {
+			positional = append(positional, p)
 		}
-		switch v := arg.(type) {
-		case int8:
-			buf.WriteString(strconv.FormatInt(int64(v), 10))
-		case int16:
-			buf.WriteString(strconv.FormatInt(int64(v), 10))
-		case int32:
-			buf.WriteString(strconv.FormatInt(int64(v), 10))
-		case int64:
-			buf.WriteString(strconv.FormatInt(v, 10))
-		case uint8:
-			buf.WriteString(strconv.FormatUint(uint64(v), 10))
-		case uint16:
-			buf.WriteString(strconv.FormatUint(uint64(v), 10))
-		case uint32:
-			buf.WriteString(strconv.FormatUint(uint64(v), 10))
-		case uint64:
-			buf.WriteString(strconv.FormatUint(v, 10))
-		case float32:
-			fmt.Fprintf(buf, "%f", v)
-		case float64:
-			fmt.Fprintf(buf, "%f", v)
-		case int:
-			buf.WriteString(strconv.Itoa(v))
-		case uint:
-			buf.WriteString(strconv.FormatUint(uint64(v), 10))
-		case bool:
-			if v {
-				buf.WriteByte('1')
-			} else {
-				buf.WriteByte('0')
+	}
+	return positional
+}
+
+// skipQuoted returns the offset of the quote closing the one at start. Backslash escapes the next character
+// of a string literal, a doubled quote stands for itself.
+func skipQuoted(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if quote != '`' {
+				i++
 			}
-		case time.Time:
-			t := v.Format(time.RFC3339Nano)
-			buf.WriteByte('\'')
-			buf.WriteString(t)
-			buf.WriteByte('\'')
-		case []byte:
-			buf.Write(v)
-		case string:
-			buf.WriteString(v)
-		default:
-			return "", driver.ErrSkip
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i
 		}
-		if buf.Len() > MaxTaosSqlLen {
-			return "", errors.New("sql statement exceeds the maximum length")
+	}
+	return len(query)
+}
+
+var stringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// WriteValue writes arg to buf as a SQL literal the way InterpolateParamsWithPrecision does, time.Time is written
+// as an integer timestamp of timePrecision, or as an RFC 3339 string when timePrecision is negative. Geometry values
+// are written as WKT strings. driver.ErrSkip is returned for an unsupported type.
+func WriteValue(buf *strings.Builder, arg driver.Value, timePrecision int) error {
+	if arg == nil {
+		buf.WriteString("NULL")
+		return nil
+	}
+	switch v := arg.(type) {
+	case int8:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int16:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int32:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case int64:
+		buf.WriteString(strconv.FormatInt(v, 10))
+	case uint8:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case float32:
+		return writeFloat(buf, float64(v), 32)
+	case float64:
+		return writeFloat(buf, v, 64)
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(v), 10))
+	case bool:
+		if v {
+			buf.WriteByte('1')
+		} else {
+			buf.WriteByte('0')
+		}
+	case time.Time:
+		if timePrecision >= 0 {
+			buf.WriteString(strconv.FormatInt(TimeToTimestamp(v, timePrecision), 10))
+			return nil
+		}
+		buf.WriteByte('\'')
+		buf.WriteString(v.Format(time.RFC3339Nano))
+		buf.WriteByte('\'')
+	case []byte:
+		buf.WriteString(`'\x`)
+		buf.WriteString(hex.EncodeToString(v))
+		buf.WriteByte('\'')
+	case string:
+		buf.WriteByte('\'')
+		stringEscaper.WriteString(buf, v)
+		buf.WriteByte('\'')
+	case geometry.Geometry:
+		return WriteValue(buf, v.String(), timePrecision)
+	case geometry.NullGeometry:
+		if !v.Valid || v.Inner == nil {
+			return WriteValue(buf, nil, timePrecision)
+		}
+		return WriteValue(buf, v.Inner.String(), timePrecision)
+	case types.TaosGeometry:
+		g, err := geometry.Unmarshal(v)
+		if err != nil {
+			return err
 		}
+		return WriteValue(buf, g.String(), timePrecision)
+	default:
+		return driver.ErrSkip
 	}
-	if argPos != len(args) {
-		return "", driver.ErrSkip
+	return nil
+}
+
+// CheckNamedValue keeps the geometry arguments of a connection as they are, so the interpolated statement
+// gets their WKT from WriteValue rather than the WKB of their Value method. Other arguments return
+// driver.ErrSkip and are converted by database/sql.
+func CheckNamedValue(v *driver.NamedValue) error {
+	switch v.Value.(type) {
+	case geometry.Geometry, geometry.NullGeometry:
+		return nil
 	}
-	return buf.String(), nil
+	return driver.ErrSkip
+}
+
+func writeFloat(buf *strings.Builder, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%v can not be interpolated", f)
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	return nil
 }
 
 func ValueArgsToNamedValueArgs(args []driver.Value) (values []driver.NamedValue) {
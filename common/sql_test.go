@@ -2,8 +2,13 @@ package common
 
 import (
 	"database/sql/driver"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
 )
 
 // @author: xftan
@@ -56,8 +61,8 @@ func TestInterpolateParams(t *testing.T) {
 					{Ordinal: 12, Value: 6},
 					{Ordinal: 13, Value: uint(6)},
 					{Ordinal: 14, Value: true},
-					{Ordinal: 15, Value: []byte("'bytes'")},
-					{Ordinal: 16, Value: []byte("'str'")},
+					{Ordinal: 15, Value: []byte("bytes")},
+					{Ordinal: 16, Value: "it's"},
 					{Ordinal: 17, Value: nil},
 				},
 			},
@@ -71,16 +76,60 @@ func TestInterpolateParams(t *testing.T) {
 				"ui16 = 2 and " +
 				"ui32 = 3 and " +
 				"ui64 = 4 and " +
-				"f32 = 5.200000 and " +
-				"f64 = 5.200000 and " +
+				"f32 = 5.2 and " +
+				"f64 = 5.2 and " +
 				"i = 6 and " +
 				"u = 6 and " +
 				"b = 1 and " +
-				"bs = 'bytes' and " +
-				"str = 'str' and " +
+				"bs = '\\x6279746573' and " +
+				"str = 'it\\'s' and " +
 				"nil is NULL",
 			wantErr: false,
 		},
+		{
+			name: "quoted and commented",
+			args: args{
+				query: "select '?', \"it\\\"s?\", 'a''?', `c?` /* ? */ from t -- ?\nwhere v = ?",
+				args:  []driver.NamedValue{{Ordinal: 1, Value: "a\\b\n"}},
+			},
+			want: "select '?', \"it\\\"s?\", 'a''?', `c?` /* ? */ from t -- ?\nwhere v = 'a\\\\b\\n'",
+		},
+		{
+			name: "unterminated comment",
+			args: args{
+				query: "select * from t where v = ? /* ?",
+				args:  []driver.NamedValue{{Ordinal: 1, Value: int64(1)}},
+			},
+			want: "select * from t where v = 1 /* ?",
+		},
+		{
+			name: "float",
+			args: args{
+				query: "insert into t values(?, ?, ?)",
+				args: []driver.NamedValue{
+					{Ordinal: 1, Value: 0.1},
+					{Ordinal: 2, Value: 1.5e-300},
+					{Ordinal: 3, Value: float32(3.4e38)},
+				},
+			},
+			want: "insert into t values(0.1, 1.5e-300, 3.4e+38)",
+		},
+		{
+			name: "NaN",
+			args: args{
+				query: "insert into t values(?)",
+				args:  []driver.NamedValue{{Ordinal: 1, Value: math.NaN()}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "placeholder count",
+			args: args{
+				query: "select * from t where v = '?'",
+				args:  []driver.NamedValue{{Ordinal: 1, Value: 1}},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -95,3 +144,38 @@ func TestInterpolateParams(t *testing.T) {
 		})
 	}
 }
+
+func TestInterpolateParamsWithPrecision(t *testing.T) {
+	ts := time.Unix(1643068800, 123456789)
+	args := []driver.NamedValue{{Ordinal: 1, Value: ts}}
+	for precision, want := range map[string]string{
+		"":   "insert into t values('" + ts.Format(time.RFC3339Nano) + "')",
+		"ms": "insert into t values(1643068800123)",
+		"us": "insert into t values(1643068800123456)",
+		"ns": "insert into t values(1643068800123456789)",
+	} {
+		got, err := InterpolateParamsWithPrecision("insert into t values(?)", args, precision)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := InterpolateParamsWithPrecision("insert into t values(?)", args, "s")
+	assert.Error(t, err)
+}
+
+func TestInterpolateGeometry(t *testing.T) {
+	p := geometry.Point{X: 1, Y: 2}
+	args := []driver.NamedValue{
+		{Ordinal: 1, Value: p},
+		{Ordinal: 2, Value: geometry.NullGeometry{Inner: p, Valid: true}},
+		{Ordinal: 3, Value: geometry.NullGeometry{}},
+		{Ordinal: 4, Value: types.TaosGeometry(geometry.Marshal(p))},
+	}
+	got, err := InterpolateParams("insert into t values(?, ?, ?, ?)", args)
+	assert.NoError(t, err)
+	assert.Equal(t, "insert into t values('POINT(1 2)', 'POINT(1 2)', NULL, 'POINT(1 2)')", got)
+
+	v := &driver.NamedValue{Ordinal: 1, Value: p}
+	assert.NoError(t, CheckNamedValue(v))
+	assert.Equal(t, p, v.Value)
+	assert.Equal(t, driver.ErrSkip, CheckNamedValue(&driver.NamedValue{Ordinal: 1, Value: 1}))
+}
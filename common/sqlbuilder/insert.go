@@ -0,0 +1,251 @@
+package sqlbuilder
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
+)
+
+const insertInto = "insert into"
+
+// Table is the target of the rows of an InsertBuilder.
+type Table struct {
+	// Name of the subtable or normal table, written as is so it may be qualified with a database or quoted with `
+	Name string
+	// STable and Tags create the subtable when it does not exist with USING STable [(TagNames)] TAGS (Tags)
+	STable   string
+	TagNames []string
+	Tags     []interface{}
+	// Columns are the names of the columns of the rows, all columns in order when empty
+	Columns []string
+}
+
+type tableRows struct {
+	clause string   // tb [using stb [(tag names)] tags(...)] [(columns)] values
+	rows   []string // the formatted rows, ` (...)`
+}
+
+// InsertBuilder builds multi-table INSERT statements from rows of many tables:
+//
+//	insert into d1001 using meters tags('California.SanFrancisco', 2) values (1648432611249, 10.3, 219, 0.31) (...) d1002 ...
+//
+// and splits them into statements of at most MaxLen bytes that can be executed by any of the drivers.
+// The rows of a table keep their order, tables are written in the order they are first added.
+type InsertBuilder struct {
+	precision int
+	maxLen    int
+	tables    []*tableRows
+	index     map[string]*tableRows
+	rows      int
+}
+
+// NewInsertBuilder returns an InsertBuilder writing time.Time values as integer timestamps of precision,
+// one of common.PrecisionMilliSecond, common.PrecisionMicroSecond or common.PrecisionNanoSecond.
+// Statements are at most common.MaxTaosSqlLen bytes.
+func NewInsertBuilder(precision int) (*InsertBuilder, error) {
+	switch precision {
+	case common.PrecisionMilliSecond, common.PrecisionMicroSecond, common.PrecisionNanoSecond:
+	default:
+		return nil, fmt.Errorf("sqlbuilder: invalid precision %d", precision)
+	}
+	return &InsertBuilder{
+		precision: precision,
+		maxLen:    common.MaxTaosSqlLen,
+		index:     map[string]*tableRows{},
+	}, nil
+}
+
+// SetMaxLen sets the maximum length in bytes of the statements, for a server configured with a smaller maxSQLLength.
+func (b *InsertBuilder) SetMaxLen(maxLen int) {
+	b.maxLen = maxLen
+}
+
+// AddRow adds a row of values to table. The USING clause of the first row added to a table name is used for all its rows.
+//
+// Values are nil for NULL, Go integers, floats, bool, string for BINARY, VARCHAR and NCHAR (escaped), []byte for
+// VARBINARY (written as a hex literal), time.Time for TIMESTAMP, a geometry.Geometry for GEOMETRY (written as WKT),
+// the types.Taos* types and driver.Valuer such as the types.Null* types.
+func (b *InsertBuilder) AddRow(table *Table, values ...interface{}) error {
+	if table == nil || table.Name == "" {
+		return errors.New("sqlbuilder: table name is empty")
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("sqlbuilder: row of table %s has no value", table.Name)
+	}
+	if len(table.Columns) != 0 && len(values) != len(table.Columns) {
+		return fmt.Errorf("sqlbuilder: row of table %s has %d values for %d columns", table.Name, len(values), len(table.Columns))
+	}
+	t, exist := b.index[table.Name]
+	if !exist {
+		clause, err := b.tableClause(table)
+		if err != nil {
+			return err
+		}
+		t = &tableRows{clause: clause}
+	}
+	buf := &strings.Builder{}
+	buf.WriteString(" (")
+	for i, value := range values {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		if err := b.writeValue(buf, value); err != nil {
+			return fmt.Errorf("sqlbuilder: value %d of table %s: %w", i, table.Name, err)
+		}
+	}
+	buf.WriteByte(')')
+	row := buf.String()
+	if len(insertInto)+1+len(t.clause)+len(row) > b.maxLen {
+		return fmt.Errorf("sqlbuilder: row of table %s exceeds the maximum statement length %d", table.Name, b.maxLen)
+	}
+	if !exist {
+		b.tables = append(b.tables, t)
+		b.index[table.Name] = t
+	}
+	t.rows = append(t.rows, row)
+	b.rows += 1
+	return nil
+}
+
+// Len returns the number of rows added.
+func (b *InsertBuilder) Len() int {
+	return b.rows
+}
+
+// Statements returns the INSERT statements writing all rows added, each at most MaxLen bytes. The clause of a table
+// whose rows are split is repeated in the next statement.
+func (b *InsertBuilder) Statements() []string {
+	var statements []string
+	buf := &strings.Builder{}
+	for _, t := range b.tables {
+		open := false
+		for _, row := range t.rows {
+			need := len(row)
+			if !open {
+				need += 1 + len(t.clause)
+			}
+			if buf.Len() != 0 && buf.Len()+need > b.maxLen {
+				statements = append(statements, buf.String())
+				buf.Reset()
+				open = false
+			}
+			if buf.Len() == 0 {
+				buf.WriteString(insertInto)
+			}
+			if !open {
+				buf.WriteByte(' ')
+				buf.WriteString(t.clause)
+				open = true
+			}
+			buf.WriteString(row)
+		}
+	}
+	if buf.Len() != 0 {
+		statements = append(statements, buf.String())
+	}
+	return statements
+}
+
+// Reset removes all rows, the builder can be reused.
+func (b *InsertBuilder) Reset() {
+	b.tables = nil
+	b.index = map[string]*tableRows{}
+	b.rows = 0
+}
+
+func (b *InsertBuilder) tableClause(table *Table) (string, error) {
+	buf := &strings.Builder{}
+	buf.WriteString(table.Name)
+	if table.STable != "" {
+		if len(table.Tags) == 0 {
+			return "", fmt.Errorf("sqlbuilder: table %s using %s has no tag", table.Name, table.STable)
+		}
+		if len(table.TagNames) != 0 && len(table.TagNames) != len(table.Tags) {
+			return "", fmt.Errorf("sqlbuilder: table %s has %d tags for %d tag names", table.Name, len(table.Tags), len(table.TagNames))
+		}
+		buf.WriteString(" using ")
+		buf.WriteString(table.STable)
+		writeNames(buf, table.TagNames)
+		buf.WriteString(" tags (")
+		for i, tag := range table.Tags {
+			if i != 0 {
+				buf.WriteString(", ")
+			}
+			if err := b.writeValue(buf, tag); err != nil {
+				return "", fmt.Errorf("sqlbuilder: tag %d of table %s: %w", i, table.Name, err)
+			}
+		}
+		buf.WriteByte(')')
+	}
+	writeNames(buf, table.Columns)
+	buf.WriteString(" values")
+	return buf.String(), nil
+}
+
+func writeNames(buf *strings.Builder, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	buf.WriteString(" (")
+	buf.WriteString(strings.Join(names, ", "))
+	buf.WriteByte(')')
+}
+
+// writeValue converts the TDengine specific types to the types written by common.WriteValue
+func (b *InsertBuilder) writeValue(buf *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case geometry.Geometry, geometry.NullGeometry, types.TaosGeometry:
+		// written as WKT by common.WriteValue, not as the WKB of their Value method
+	case types.TaosBool:
+		value = bool(v)
+	case types.TaosTinyint:
+		value = int8(v)
+	case types.TaosSmallint:
+		value = int16(v)
+	case types.TaosInt:
+		value = int32(v)
+	case types.TaosBigint:
+		value = int64(v)
+	case types.TaosUTinyint:
+		value = uint8(v)
+	case types.TaosUSmallint:
+		value = uint16(v)
+	case types.TaosUInt:
+		value = uint32(v)
+	case types.TaosUBigint:
+		value = uint64(v)
+	case types.TaosFloat:
+		value = float32(v)
+	case types.TaosDouble:
+		value = float64(v)
+	case types.TaosBinary:
+		value = string(v)
+	case types.TaosNchar:
+		value = string(v)
+	case types.TaosJson:
+		value = string(v)
+	case types.TaosVarBinary:
+		value = []byte(v)
+	case types.TaosTimestamp:
+		value = v.T
+	case driver.Valuer:
+		converted, err := v.Value()
+		if err != nil {
+			return err
+		}
+		if _, ok := converted.(driver.Valuer); ok {
+			return fmt.Errorf("unsupported type %T", value)
+		}
+		return b.writeValue(buf, converted)
+	}
+	err := common.WriteValue(buf, value, b.precision)
+	if err == driver.ErrSkip {
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package sqlbuilder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
+)
+
+func TestInsertBuilder(t *testing.T) {
+	b, err := NewInsertBuilder(common.PrecisionMicroSecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ts := time.Unix(1700000000, 123456000)
+	d1001 := &Table{Name: "d1001", STable: "meters", Tags: []interface{}{"California.SanFrancisco", 2}}
+	d1002 := &Table{Name: "db.d1002", STable: "meters", TagNames: []string{"location"}, Tags: []interface{}{"it's"}, Columns: []string{"ts", "current"}}
+	assert.NoError(t, b.AddRow(d1001, ts, 10.3, int32(219), float32(0.31)))
+	assert.NoError(t, b.AddRow(d1002, ts, nil))
+	assert.NoError(t, b.AddRow(d1001, ts.Add(time.Second), 12.6, types.NullInt32{Inner: 218, Valid: true}, types.NullFloat32{}))
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, []string{
+		"insert into d1001 using meters tags ('California.SanFrancisco', 2) values" +
+			" (1700000000123456, 10.3, 219, 0.31) (1700000001123456, 12.6, 218, NULL)" +
+			" db.d1002 using meters (location) tags ('it\\'s') (ts, current) values (1700000000123456, NULL)",
+	}, b.Statements())
+
+	b.Reset()
+	assert.Equal(t, 0, b.Len())
+	assert.Nil(t, b.Statements())
+}
+
+func TestInsertBuilderTypes(t *testing.T) {
+	b, err := NewInsertBuilder(common.PrecisionMilliSecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ts := time.Unix(1700000000, 0)
+	err = b.AddRow(&Table{Name: "t"},
+		ts,
+		types.TaosTimestamp{T: ts, Precision: common.PrecisionNanoSecond},
+		true,
+		types.TaosBool(false),
+		int8(-1), types.TaosTinyint(-2), int16(-3), types.TaosSmallint(-4), types.TaosInt(-5), types.TaosBigint(-6),
+		uint8(1), types.TaosUTinyint(2), uint16(3), types.TaosUSmallint(4), types.TaosUInt(5), types.TaosUBigint(6), uint64(7),
+		types.TaosFloat(1.5), types.TaosDouble(2.5),
+		"line\nbreak", types.TaosBinary("bin"), types.TaosNchar("涛思"),
+		[]byte{0x01, 0xff}, types.TaosVarBinary{0x02},
+		types.TaosJson(`{"k":"v"}`),
+		geometry.Point{X: 1, Y: 2},
+		types.TaosGeometry(geometry.Marshal(geometry.Point{X: 3, Y: 4})),
+		geometry.NullGeometry{},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	point := geometry.Point{X: 1, Y: 2}.String()
+	point2 := geometry.Point{X: 3, Y: 4}.String()
+	assert.Equal(t, []string{
+		"insert into t values (1700000000000, 1700000000000, 1, 0, -1, -2, -3, -4, -5, -6, 1, 2, 3, 4, 5, 6, 7, 1.5, 2.5," +
+			" 'line\\nbreak', 'bin', '涛思', '\\x01ff', '\\x02', '{\"k\":\"v\"}', '" + point + "', '" + point2 + "', NULL)",
+	}, b.Statements())
+}
+
+func TestInsertBuilderSplit(t *testing.T) {
+	b, err := NewInsertBuilder(common.PrecisionMilliSecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	b.SetMaxLen(80)
+	tables := []*Table{
+		{Name: "d1", STable: "st", Tags: []interface{}{1}},
+		{Name: "d2", STable: "st", Tags: []interface{}{2}},
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, b.AddRow(tables[i%2], int64(1700000000000+i), i))
+	}
+	statements := b.Statements()
+	assert.Equal(t, []string{
+		"insert into d1 using st tags (1) values (1700000000000, 0) (1700000000002, 2)",
+		"insert into d1 using st tags (1) values (1700000000004, 4) (1700000000006, 6)",
+		"insert into d1 using st tags (1) values (1700000000008, 8)",
+		"insert into d2 using st tags (2) values (1700000000001, 1) (1700000000003, 3)",
+		"insert into d2 using st tags (2) values (1700000000005, 5) (1700000000007, 7)",
+		"insert into d2 using st tags (2) values (1700000000009, 9)",
+	}, statements)
+	for _, statement := range statements {
+		assert.LessOrEqual(t, len(statement), 80, statement)
+	}
+
+	err = b.AddRow(&Table{Name: "d3"}, strings.Repeat("x", 80))
+	assert.Error(t, err)
+	assert.Equal(t, 10, b.Len())
+}
+
+func TestInsertBuilderErrors(t *testing.T) {
+	_, err := NewInsertBuilder(3)
+	assert.Error(t, err)
+	b, err := NewInsertBuilder(common.PrecisionMilliSecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Error(t, b.AddRow(&Table{}, 1))
+	assert.Error(t, b.AddRow(&Table{Name: "t"}))
+	assert.Error(t, b.AddRow(&Table{Name: "t", Columns: []string{"ts"}}, 1, 2))
+	assert.Error(t, b.AddRow(&Table{Name: "t", STable: "st"}, 1))
+	assert.Error(t, b.AddRow(&Table{Name: "t", STable: "st", TagNames: []string{"a", "b"}, Tags: []interface{}{1}}, 1))
+	assert.Error(t, b.AddRow(&Table{Name: "t"}, struct{}{}))
+	assert.Error(t, b.AddRow(&Table{Name: "t"}, types.TaosGeometry{0x01}))
+	assert.Equal(t, 0, b.Len())
+	assert.Nil(t, b.Statements())
+}
@@ -0,0 +1,335 @@
+package sqlbuilder
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// Pseudo-columns of the window of a window query.
+const (
+	WStart    = "_wstart"
+	WEnd      = "_wend"
+	WDuration = "_wduration"
+)
+
+// FillMode is the FILL clause of an INTERVAL window.
+type FillMode string
+
+const (
+	FillNone   FillMode = "none"
+	FillNull   FillMode = "null"
+	FillNullF  FillMode = "null_f"
+	FillPrev   FillMode = "prev"
+	FillNext   FillMode = "next"
+	FillLinear FillMode = "linear"
+)
+
+// Query builds a SELECT statement with the TDengine window clauses:
+//
+//	sql, args, err := sqlbuilder.Select(sqlbuilder.WStart, "avg(current)").
+//		From("meters").
+//		TimeRange("ts", start, end).
+//		WhereTag("location", "California.SanFrancisco").
+//		PartitionBy("tbname").
+//		Interval(time.Minute).Sliding(30 * time.Second).Fill(sqlbuilder.FillPrev).
+//		Build()
+//
+// Values are passed as ? placeholders and returned as positional arguments in the order of the statement.
+// The first error of the chain is returned by Build.
+type Query struct {
+	columns     []string
+	from        string
+	where       []string
+	whereArgs   []interface{}
+	partitionBy []string
+	window      string
+	windowArgs  []interface{}
+	interval    bool
+	sliding     string
+	fill        string
+	orderBy     []string
+	slimit      int
+	soffset     int
+	limit       int
+	offset      int
+	err         error
+}
+
+// Select starts a query of columns, * when there is none.
+func Select(columns ...string) *Query {
+	return &Query{columns: columns, slimit: -1, soffset: -1, limit: -1, offset: -1}
+}
+
+// From sets the table, super table or subquery of the query.
+func (q *Query) From(table string) *Query {
+	q.from = table
+	return q
+}
+
+// Where adds a condition with ? placeholders for args, the conditions are joined with AND.
+func (q *Query) Where(condition string, args ...interface{}) *Query {
+	q.where = append(q.where, condition)
+	q.whereArgs = append(q.whereArgs, args...)
+	return q
+}
+
+// TimeRange adds the condition start <= column < end.
+func (q *Query) TimeRange(column string, start, end time.Time) *Query {
+	return q.Where(column+" >= ? and "+column+" < ?", start, end)
+}
+
+// WhereTag adds the tag filter tag = value.
+func (q *Query) WhereTag(tag string, value interface{}) *Query {
+	return q.Where(tag+" = ?", value)
+}
+
+// WhereTagIn adds the tag filter tag IN (values).
+func (q *Query) WhereTagIn(tag string, values ...interface{}) *Query {
+	if len(values) == 0 {
+		return q.setErr(fmt.Errorf("sqlbuilder: tag filter on %s has no value", tag))
+	}
+	return q.Where(tag+" in ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")", values...)
+}
+
+// PartitionBy splits the query into one result per value of exprs, such as tbname or a tag.
+func (q *Query) PartitionBy(exprs ...string) *Query {
+	q.partitionBy = append(q.partitionBy, exprs...)
+	return q
+}
+
+// Interval groups the rows in time windows of interval, the optional offset shifts the windows.
+func (q *Query) Interval(interval time.Duration, offset ...time.Duration) *Query {
+	if len(offset) > 1 {
+		return q.setErr(errors.New("sqlbuilder: interval takes one offset"))
+	}
+	d, err := Duration(interval)
+	if err != nil {
+		return q.setErr(err)
+	}
+	if len(offset) == 1 {
+		o, err := Duration(offset[0])
+		if err != nil {
+			return q.setErr(err)
+		}
+		d += ", " + o
+	}
+	q.interval = true
+	return q.setWindow("interval("+d+")", nil)
+}
+
+// Sliding sets the step of the windows of Interval.
+func (q *Query) Sliding(sliding time.Duration) *Query {
+	d, err := Duration(sliding)
+	if err != nil {
+		return q.setErr(err)
+	}
+	q.sliding = d
+	return q
+}
+
+// Fill sets how the windows of Interval without rows are filled.
+func (q *Query) Fill(mode FillMode) *Query {
+	q.fill = string(mode)
+	return q
+}
+
+// FillValue fills the windows of Interval without rows with values, one per column.
+func (q *Query) FillValue(values ...interface{}) *Query {
+	b := &strings.Builder{}
+	b.WriteString("value")
+	for _, value := range values {
+		b.WriteString(", ")
+		if err := common.WriteValue(b, value, -1); err != nil {
+			return q.setErr(fmt.Errorf("sqlbuilder: unsupported fill value %T", value))
+		}
+	}
+	q.fill = b.String()
+	return q
+}
+
+// StateWindow groups consecutive rows with the same value of column.
+func (q *Query) StateWindow(column string) *Query {
+	return q.setWindow("state_window("+column+")", nil)
+}
+
+// Session groups rows of column whose timestamps are less than tolerance apart.
+func (q *Query) Session(column string, tolerance time.Duration) *Query {
+	d, err := Duration(tolerance)
+	if err != nil {
+		return q.setErr(err)
+	}
+	return q.setWindow("session("+column+", "+d+")", nil)
+}
+
+// EventWindow groups rows from one matching start to the next matching end, the conditions take ? placeholders for args.
+func (q *Query) EventWindow(start, end string, args ...interface{}) *Query {
+	return q.setWindow("event_window start with "+start+" end with "+end, args)
+}
+
+// OrderBy sorts the result by exprs, such as "_wstart desc".
+func (q *Query) OrderBy(exprs ...string) *Query {
+	q.orderBy = append(q.orderBy, exprs...)
+	return q
+}
+
+// SLimit limits the number of partitions of PartitionBy.
+func (q *Query) SLimit(limit int) *Query {
+	q.slimit = limit
+	return q
+}
+
+// SOffset skips the first partitions of PartitionBy.
+func (q *Query) SOffset(offset int) *Query {
+	q.soffset = offset
+	return q
+}
+
+// Limit limits the number of rows, of each partition with PartitionBy.
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Offset skips the first rows, of each partition with PartitionBy.
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+// Build returns the statement and its arguments for db.Query(sql, args...) or a prepared statement.
+func (q *Query) Build() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	if q.from == "" {
+		return "", nil, errors.New("sqlbuilder: query has no table")
+	}
+	if !q.interval && (q.sliding != "" || q.fill != "") {
+		return "", nil, errors.New("sqlbuilder: sliding and fill need an interval")
+	}
+	if len(q.partitionBy) == 0 && (q.slimit >= 0 || q.soffset >= 0) {
+		return "", nil, errors.New("sqlbuilder: slimit and soffset need a partition by")
+	}
+	if (q.soffset >= 0 && q.slimit < 0) || (q.offset >= 0 && q.limit < 0) {
+		return "", nil, errors.New("sqlbuilder: offset needs a limit")
+	}
+	b := &strings.Builder{}
+	var args []interface{}
+	b.WriteString("select ")
+	if len(q.columns) == 0 {
+		b.WriteByte('*')
+	} else {
+		b.WriteString(strings.Join(q.columns, ", "))
+	}
+	b.WriteString(" from ")
+	b.WriteString(q.from)
+	if len(q.where) != 0 {
+		b.WriteString(" where ")
+		for i, condition := range q.where {
+			if i != 0 {
+				b.WriteString(" and ")
+			}
+			if len(q.where) > 1 {
+				b.WriteString("(" + condition + ")")
+			} else {
+				b.WriteString(condition)
+			}
+		}
+		args = append(args, q.whereArgs...)
+	}
+	if len(q.partitionBy) != 0 {
+		b.WriteString(" partition by ")
+		b.WriteString(strings.Join(q.partitionBy, ", "))
+	}
+	if q.window != "" {
+		b.WriteByte(' ')
+		b.WriteString(q.window)
+		args = append(args, q.windowArgs...)
+		if q.sliding != "" {
+			b.WriteString(" sliding(" + q.sliding + ")")
+		}
+		if q.fill != "" {
+			b.WriteString(" fill(" + q.fill + ")")
+		}
+	}
+	if len(q.orderBy) != 0 {
+		b.WriteString(" order by ")
+		b.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	writeLimit(b, "slimit", q.slimit, "soffset", q.soffset)
+	writeLimit(b, "limit", q.limit, "offset", q.offset)
+	return b.String(), args, nil
+}
+
+// BuildNamed is Build with the arguments for common.InterpolateParams.
+func (q *Query) BuildNamed() (string, []driver.NamedValue, error) {
+	sql, args, err := q.Build()
+	if err != nil {
+		return "", nil, err
+	}
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return sql, values, nil
+}
+
+func (q *Query) setErr(err error) *Query {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+func (q *Query) setWindow(window string, args []interface{}) *Query {
+	if q.window != "" {
+		return q.setErr(errors.New("sqlbuilder: query has more than one window clause"))
+	}
+	q.window = window
+	q.windowArgs = args
+	return q
+}
+
+func writeLimit(b *strings.Builder, limitKeyword string, limit int, offsetKeyword string, offset int) {
+	if limit < 0 {
+		return
+	}
+	b.WriteString(" " + limitKeyword + " " + strconv.Itoa(limit))
+	if offset >= 0 {
+		b.WriteString(" " + offsetKeyword + " " + strconv.Itoa(offset))
+	}
+}
+
+var durationUnits = []struct {
+	unit   time.Duration
+	suffix string
+}{
+	{7 * 24 * time.Hour, "w"},
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+	{time.Millisecond, "a"},
+	{time.Microsecond, "u"},
+	{time.Nanosecond, "b"},
+}
+
+// Duration formats d as a TDengine duration in its largest exact unit, such as 1m, 30s or 1500a.
+func Duration(d time.Duration) (string, error) {
+	if d <= 0 {
+		return "", fmt.Errorf("sqlbuilder: duration %s is not positive", d)
+	}
+	for _, u := range durationUnits {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix, nil
+		}
+	}
+	// unreachable, every duration is a whole number of nanoseconds
+	return "", nil
+}
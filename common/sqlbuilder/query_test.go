@@ -0,0 +1,126 @@
+package sqlbuilder
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+func TestQuery(t *testing.T) {
+	start := time.Unix(1700000000, 0).UTC()
+	end := start.Add(time.Hour)
+	tests := []struct {
+		name     string
+		query    *Query
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:    "select all",
+			query:   Select().From("meters"),
+			wantSQL: "select * from meters",
+		},
+		{
+			name: "interval",
+			query: Select(WStart, "avg(current)").
+				From("meters").
+				TimeRange("ts", start, end).
+				WhereTag("location", "California.SanFrancisco").
+				PartitionBy("tbname").
+				Interval(time.Minute).Sliding(30 * time.Second).Fill(FillPrev).
+				SLimit(10).SOffset(0),
+			wantSQL: "select _wstart, avg(current) from meters where (ts >= ? and ts < ?) and (location = ?)" +
+				" partition by tbname interval(1m) sliding(30s) fill(prev) slimit 10 soffset 0",
+			wantArgs: []interface{}{start, end, "California.SanFrancisco"},
+		},
+		{
+			name: "interval offset and fill value",
+			query: Select(WStart, WEnd, "max(voltage)", "min(voltage)").
+				From("d1001").
+				Interval(24*time.Hour, 8*time.Hour).FillValue(0, 1.5).
+				OrderBy(WStart + " desc").Limit(5).Offset(10),
+			wantSQL: "select _wstart, _wend, max(voltage), min(voltage) from d1001 interval(1d, 8h) fill(value, 0, 1.5)" +
+				" order by _wstart desc limit 5 offset 10",
+		},
+		{
+			name:     "state window",
+			query:    Select(WStart, WDuration, "count(*)").From("d1001").WhereTagIn("groupid", 1, 2).StateWindow("status"),
+			wantSQL:  "select _wstart, _wduration, count(*) from d1001 where groupid in (?, ?) state_window(status)",
+			wantArgs: []interface{}{1, 2},
+		},
+		{
+			name:    "session",
+			query:   Select(WStart, "count(*)").From("d1001").Session("ts", 1500*time.Millisecond),
+			wantSQL: "select _wstart, count(*) from d1001 session(ts, 1500a)",
+		},
+		{
+			name: "event window",
+			query: Select(WStart, "count(*)").From("meters").Where("voltage > ?", 200).PartitionBy("tbname").
+				EventWindow("current > ?", "current < ?", 10, 5),
+			wantSQL:  "select _wstart, count(*) from meters where voltage > ? partition by tbname event_window start with current > ? end with current < ?",
+			wantArgs: []interface{}{200, 10, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.query.Build()
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantSQL, sql)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for name, query := range map[string]*Query{
+		"no table":          Select(),
+		"sliding":           Select().From("t").Sliding(time.Second),
+		"fill":              Select().From("t").StateWindow("s").Fill(FillNull),
+		"two windows":       Select().From("t").Interval(time.Second).StateWindow("s"),
+		"slimit":            Select().From("t").SLimit(1),
+		"offset":            Select().From("t").Offset(1),
+		"negative duration": Select().From("t").Interval(-time.Second),
+		"two offsets":       Select().From("t").Interval(time.Minute, time.Second, time.Second),
+		"empty tag in":      Select().From("t").WhereTagIn("t1"),
+		"fill value":        Select().From("t").Interval(time.Second).FillValue(struct{}{}),
+	} {
+		_, _, err := query.Build()
+		assert.Error(t, err, name)
+	}
+}
+
+func TestQueryBuildNamed(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	sql, args, err := Select("count(*)").From("meters").TimeRange("ts", start, start.Add(time.Second)).BuildNamed()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []driver.NamedValue{{Ordinal: 1, Value: start}, {Ordinal: 2, Value: start.Add(time.Second)}}, args)
+	interpolated, err := common.InterpolateParamsWithPrecision(sql, args, "ms")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "select count(*) from meters where ts >= 1700000000000 and ts < 1700000001000", interpolated)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		2 * 7 * 24 * time.Hour:  "2w",
+		36 * time.Hour:          "36h",
+		90 * time.Minute:        "90m",
+		time.Minute:             "1m",
+		1500 * time.Millisecond: "1500a",
+		time.Microsecond:        "1u",
+		10:                      "10b",
+	} {
+		got, err := Duration(d)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := Duration(0)
+	assert.Error(t, err)
+}
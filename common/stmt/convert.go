@@ -0,0 +1,402 @@
+package stmt
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+	"github.com/taosdata/driver-go/v3/types/geometry"
+)
+
+// ConvertInsertValue converts the argument v of a prepared insert to the types.Taos* type of its column field,
+// it implements CheckNamedValue of the statements of taosSql and taosWS.
+func ConvertInsertValue(v *driver.NamedValue, field *StmtField) error {
+	if v.Value == nil {
+		return nil
+	}
+	switch field.FieldType {
+	case common.TSDB_DATA_TYPE_NULL:
+		v.Value = nil
+	case common.TSDB_DATA_TYPE_BOOL:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			v.Value = types.TaosBool(rv.Bool())
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosBool(rv.Float() == 1)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosBool(rv.Int() == 1)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosBool(rv.Uint() == 1)
+		case reflect.String:
+			vv, err := strconv.ParseBool(rv.String())
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosBool(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to bool", v)
+		}
+	case common.TSDB_DATA_TYPE_TINYINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosTinyint(1)
+			} else {
+				v.Value = types.TaosTinyint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosTinyint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosTinyint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosTinyint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseInt(rv.String(), 0, 8)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosTinyint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to tinyint", v)
+		}
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosSmallint(1)
+			} else {
+				v.Value = types.TaosSmallint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosSmallint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosSmallint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosSmallint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseInt(rv.String(), 0, 16)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosSmallint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to smallint", v)
+		}
+	case common.TSDB_DATA_TYPE_INT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosInt(1)
+			} else {
+				v.Value = types.TaosInt(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosInt(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosInt(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosInt(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseInt(rv.String(), 0, 32)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosInt(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to int", v)
+		}
+	case common.TSDB_DATA_TYPE_BIGINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosBigint(1)
+			} else {
+				v.Value = types.TaosBigint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosBigint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosBigint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosBigint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseInt(rv.String(), 0, 64)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosBigint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to bigint", v)
+		}
+	case common.TSDB_DATA_TYPE_FLOAT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosFloat(1)
+			} else {
+				v.Value = types.TaosFloat(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosFloat(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosFloat(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosFloat(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseFloat(rv.String(), 32)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosFloat(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to float", v)
+		}
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosDouble(1)
+			} else {
+				v.Value = types.TaosDouble(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosDouble(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosDouble(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosDouble(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseFloat(rv.String(), 64)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosDouble(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to double", v)
+		}
+	case common.TSDB_DATA_TYPE_BINARY:
+		switch v.Value.(type) {
+		case string:
+			v.Value = types.TaosBinary(v.Value.(string))
+		case []byte:
+			v.Value = types.TaosBinary(v.Value.([]byte))
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to binary", v)
+		}
+	case common.TSDB_DATA_TYPE_VARBINARY:
+		switch v.Value.(type) {
+		case string:
+			v.Value = types.TaosVarBinary(v.Value.(string))
+		case []byte:
+			v.Value = types.TaosVarBinary(v.Value.([]byte))
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to varbinary", v)
+		}
+
+	case common.TSDB_DATA_TYPE_GEOMETRY:
+		switch v.Value.(type) {
+		case string:
+			v.Value = types.TaosGeometry(v.Value.(string))
+		case []byte:
+			v.Value = types.TaosGeometry(v.Value.([]byte))
+		case geometry.Geometry:
+			v.Value = types.TaosGeometry(geometry.Marshal(v.Value.(geometry.Geometry)))
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to geometry", v)
+		}
+
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		t, is := v.Value.(time.Time)
+		if is {
+			v.Value = types.TaosTimestamp{
+				T:         t,
+				Precision: int(field.Precision),
+			}
+			return nil
+		}
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			t := common.TimestampConvertToTime(int64(rv.Float()), int(field.Precision))
+			v.Value = types.TaosTimestamp{
+				T:         t,
+				Precision: int(field.Precision),
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			t := common.TimestampConvertToTime(rv.Int(), int(field.Precision))
+			v.Value = types.TaosTimestamp{
+				T:         t,
+				Precision: int(field.Precision),
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			t := common.TimestampConvertToTime(int64(rv.Uint()), int(field.Precision))
+			v.Value = types.TaosTimestamp{
+				T:         t,
+				Precision: int(field.Precision),
+			}
+		case reflect.String:
+			t, err := time.Parse(time.RFC3339Nano, rv.String())
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosTimestamp{
+				T:         t,
+				Precision: int(field.Precision),
+			}
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to timestamp", v)
+		}
+	case common.TSDB_DATA_TYPE_NCHAR:
+		switch v.Value.(type) {
+		case string:
+			v.Value = types.TaosNchar(v.Value.(string))
+		case []byte:
+			v.Value = types.TaosNchar(v.Value.([]byte))
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to nchar", v)
+		}
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosUTinyint(1)
+			} else {
+				v.Value = types.TaosUTinyint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosUTinyint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosUTinyint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosUTinyint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseUint(rv.String(), 0, 8)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosUTinyint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to tinyint unsigned", v)
+		}
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosUSmallint(1)
+			} else {
+				v.Value = types.TaosUSmallint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosUSmallint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosUSmallint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosUSmallint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseUint(rv.String(), 0, 16)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosUSmallint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to smallint unsigned", v)
+		}
+	case common.TSDB_DATA_TYPE_UINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosUInt(1)
+			} else {
+				v.Value = types.TaosUInt(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosUInt(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosUInt(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosUInt(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseUint(rv.String(), 0, 32)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosUInt(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to int unsigned", v)
+		}
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		rv := reflect.ValueOf(v.Value)
+		switch rv.Kind() {
+		case reflect.Bool:
+			if rv.Bool() {
+				v.Value = types.TaosUBigint(1)
+			} else {
+				v.Value = types.TaosUBigint(0)
+			}
+		case reflect.Float32, reflect.Float64:
+			v.Value = types.TaosUBigint(rv.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.Value = types.TaosUBigint(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			v.Value = types.TaosUBigint(rv.Uint())
+		case reflect.String:
+			vv, err := strconv.ParseUint(rv.String(), 0, 64)
+			if err != nil {
+				return err
+			}
+			v.Value = types.TaosUBigint(vv)
+		default:
+			return fmt.Errorf("CheckNamedValue:%v can not convert to bigint unsigned", v)
+		}
+	}
+	return nil
+}
+
+// ConvertQueryValue converts the argument v of a prepared query to the types.Taos* type it is bound as.
+func ConvertQueryValue(v *driver.NamedValue) error {
+	if v.Value == nil {
+		return nil
+	}
+	t, is := v.Value.(time.Time)
+	if is {
+		v.Value = types.TaosBinary(t.Format(time.RFC3339Nano))
+		return nil
+	}
+	rv := reflect.ValueOf(v.Value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		v.Value = types.TaosBool(rv.Bool())
+	case reflect.Float32, reflect.Float64:
+		v.Value = types.TaosDouble(rv.Float())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.Value = types.TaosBigint(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.Value = types.TaosUBigint(rv.Uint())
+	case reflect.String:
+		v.Value = types.TaosBinary(rv.String())
+	case reflect.Slice:
+		ek := rv.Type().Elem().Kind()
+		if ek == reflect.Uint8 {
+			v.Value = types.TaosBinary(rv.Bytes())
+		} else {
+			return fmt.Errorf("CheckNamedValue: can not convert query value %v", v)
+		}
+	default:
+		return fmt.Errorf("CheckNamedValue: can not convert query value %v", v)
+	}
+	return nil
+}
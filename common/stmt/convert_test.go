@@ -0,0 +1,63 @@
+package stmt
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+func TestConvertInsertValue(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		field *StmtField
+		value driver.Value
+		want  driver.Value
+	}{
+		{"bool from int", &StmtField{FieldType: common.TSDB_DATA_TYPE_BOOL}, 1, types.TaosBool(true)},
+		{"tinyint from string", &StmtField{FieldType: common.TSDB_DATA_TYPE_TINYINT}, "-3", types.TaosTinyint(-3)},
+		{"ubigint from float", &StmtField{FieldType: common.TSDB_DATA_TYPE_UBIGINT}, 2.0, types.TaosUBigint(2)},
+		{"nchar from bytes", &StmtField{FieldType: common.TSDB_DATA_TYPE_NCHAR}, []byte("a"), types.TaosNchar("a")},
+		{
+			"timestamp from int",
+			&StmtField{FieldType: common.TSDB_DATA_TYPE_TIMESTAMP, Precision: common.PrecisionMicroSecond},
+			ts.UnixNano() / 1e3,
+			types.TaosTimestamp{T: ts, Precision: common.PrecisionMicroSecond},
+		},
+		{"null", &StmtField{FieldType: common.TSDB_DATA_TYPE_INT}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &driver.NamedValue{Ordinal: 1, Value: tt.value}
+			if assert.NoError(t, ConvertInsertValue(v, tt.field)) {
+				assert.Equal(t, tt.want, v.Value)
+			}
+		})
+	}
+	v := &driver.NamedValue{Ordinal: 1, Value: "x"}
+	assert.Error(t, ConvertInsertValue(v, &StmtField{FieldType: common.TSDB_DATA_TYPE_INT}))
+}
+
+func TestConvertQueryValue(t *testing.T) {
+	ts := time.Unix(1700000000, 0).UTC()
+	for _, tt := range []struct {
+		value driver.Value
+		want  driver.Value
+	}{
+		{int32(1), types.TaosBigint(1)},
+		{uint8(1), types.TaosUBigint(1)},
+		{float32(1.5), types.TaosDouble(1.5)},
+		{"a", types.TaosBinary("a")},
+		{ts, types.TaosBinary(ts.Format(time.RFC3339Nano))},
+	} {
+		v := &driver.NamedValue{Ordinal: 1, Value: tt.value}
+		if assert.NoError(t, ConvertQueryValue(v)) {
+			assert.Equal(t, tt.want, v.Value)
+		}
+	}
+	assert.Error(t, ConvertQueryValue(&driver.NamedValue{Ordinal: 1, Value: []int{1}}))
+}
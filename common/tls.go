@@ -0,0 +1,96 @@
+package common
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	tlsConfigLock     sync.RWMutex
+	tlsConfigRegistry map[string]*tls.Config
+)
+
+func isReservedTLSName(key string) bool {
+	switch strings.ToLower(key) {
+	case "", "true", "false", "skip-verify":
+		return true
+	}
+	return false
+}
+
+// RegisterTLSConfig registers a custom tls.Config to be used by the `tls` DSN parameter of taosWS and taosRestful,
+// and by the TLS option of ws/stmt, ws/tmq and ws/schemaless.
+// The names "true", "false" and "skip-verify" are reserved.
+func RegisterTLSConfig(key string, config *tls.Config) error {
+	if isReservedTLSName(key) {
+		return fmt.Errorf("key '%s' is reserved", key)
+	}
+	if config == nil {
+		return errors.New("tls config is nil")
+	}
+	tlsConfigLock.Lock()
+	if tlsConfigRegistry == nil {
+		tlsConfigRegistry = make(map[string]*tls.Config)
+	}
+	tlsConfigRegistry[key] = config
+	tlsConfigLock.Unlock()
+	return nil
+}
+
+// DeregisterTLSConfig removes the tls.Config registered with key.
+func DeregisterTLSConfig(key string) {
+	tlsConfigLock.Lock()
+	delete(tlsConfigRegistry, key)
+	tlsConfigLock.Unlock()
+}
+
+// GetTLSConfig resolves the value of a tls option. "true" verifies the server with the system roots,
+// "skip-verify" accepts any server certificate, "false" or empty disables the custom configuration,
+// any other value is the name of a tls.Config registered with RegisterTLSConfig.
+// The returned config is a clone and may be modified.
+func GetTLSConfig(value string) (*tls.Config, error) {
+	switch strings.ToLower(value) {
+	case "", "false":
+		return nil, nil
+	case "true":
+		return &tls.Config{}, nil
+	case "skip-verify":
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	tlsConfigLock.RLock()
+	config, exist := tlsConfigRegistry[value]
+	tlsConfigLock.RUnlock()
+	if !exist {
+		return nil, fmt.Errorf("tls config '%s' is not registered", value)
+	}
+	return config.Clone(), nil
+}
+
+// NewDialer returns a copy of DefaultDialer using tlsConfig, DefaultDialer is returned when tlsConfig is nil.
+func NewDialer(tlsConfig *tls.Config) *websocket.Dialer {
+	if tlsConfig == nil {
+		return &DefaultDialer
+	}
+	dialer := DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	return &dialer
+}
+
+// TLSScheme switches ws to wss and http to https when tlsConfig is set.
+func TLSScheme(scheme string, tlsConfig *tls.Config) string {
+	if tlsConfig == nil {
+		return scheme
+	}
+	switch scheme {
+	case "ws":
+		return "wss"
+	case "http":
+		return "https"
+	}
+	return scheme
+}
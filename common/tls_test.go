@@ -0,0 +1,47 @@
+package common
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfig(t *testing.T) {
+	assert.Error(t, RegisterTLSConfig("true", &tls.Config{}))
+	assert.Error(t, RegisterTLSConfig("skip-verify", &tls.Config{}))
+	assert.Error(t, RegisterTLSConfig("custom", nil))
+
+	config, err := GetTLSConfig("")
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+	config, err = GetTLSConfig("false")
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+	config, err = GetTLSConfig("true")
+	assert.NoError(t, err)
+	assert.False(t, config.InsecureSkipVerify)
+	config, err = GetTLSConfig("skip-verify")
+	assert.NoError(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+
+	_, err = GetTLSConfig("custom")
+	assert.Error(t, err)
+	registered := &tls.Config{ServerName: "adapter"}
+	assert.NoError(t, RegisterTLSConfig("custom", registered))
+	config, err = GetTLSConfig("custom")
+	assert.NoError(t, err)
+	assert.Equal(t, "adapter", config.ServerName)
+	// a clone is returned
+	config.ServerName = "changed"
+	assert.Equal(t, "adapter", registered.ServerName)
+	DeregisterTLSConfig("custom")
+	_, err = GetTLSConfig("custom")
+	assert.Error(t, err)
+
+	assert.Equal(t, "wss", TLSScheme("ws", registered))
+	assert.Equal(t, "https", TLSScheme("http", registered))
+	assert.Equal(t, "ws", TLSScheme("ws", nil))
+	assert.Same(t, &DefaultDialer, NewDialer(nil))
+	assert.Equal(t, registered, NewDialer(registered).TLSClientConfig)
+}
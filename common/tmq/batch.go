@@ -0,0 +1,32 @@
+package tmq
+
+import "time"
+
+// PollBatch calls poll until maxMessages events are returned or timeout has passed, each call waits for the rest
+// of timeout. It stops early when poll returns nil, nothing arrived in time, or an Error, which is the last event.
+// It implements PollBatch of the native consumer, the WebSocket consumer pipelines its polls instead.
+func PollBatch(poll func(timeoutMs int) Event, maxMessages int, timeout time.Duration) []Event {
+	if maxMessages <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(timeout)
+	var events []Event
+	for len(events) < maxMessages {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		ev := poll(int(remaining / time.Millisecond))
+		if ev == nil {
+			break
+		}
+		events = append(events, ev)
+		if _, ok := ev.(Error); ok {
+			break
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+	return events
+}
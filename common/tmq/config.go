@@ -3,6 +3,8 @@ package tmq
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/taosdata/driver-go/v3/common"
 )
 
 type ConfigValue interface{}
@@ -32,3 +34,18 @@ func (m ConfigMap) Clone() ConfigMap {
 	}
 	return m2
 }
+
+// ParseLogger returns the logger given by the value of the ConfigMap key: a common.Logger, or the name of
+// a logger registered with common.RegisterLogger. It is nil when the key is not set.
+func ParseLogger(key string, value ConfigValue) (common.Logger, error) {
+	switch l := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return common.GetRegisteredLogger(l)
+	case common.Logger:
+		return l, nil
+	default:
+		return nil, fmt.Errorf("%s requires string or common.Logger got %T", key, value)
+	}
+}
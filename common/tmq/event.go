@@ -8,35 +8,72 @@ import (
 	taosError "github.com/taosdata/driver-go/v3/errors"
 )
 
+// Data is a block of rows of a table, Fields describe the columns of each row.
 type Data struct {
 	TableName string
 	Data      [][]driver.Value
+	Fields    []*Field
+	Precision int // precision of the timestamp columns, common.PrecisionMilliSecond, PrecisionMicroSecond or PrecisionNanoSecond
 }
+
+// Field is the schema of a column of a Data block.
+type Field struct {
+	Name   string
+	Type   int   // common.TSDB_DATA_TYPE_*
+	Length int64 // the size in bytes of the column type, the maximum length of VARCHAR and NCHAR columns
+}
+
+// FieldIndex returns the index in each row of the column called name, -1 if there is none.
+func (d *Data) FieldIndex(name string) int {
+	for i, field := range d.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewFields returns the Fields of a block from the names, types and lengths of its columns.
+func NewFields(names []string, types []uint8, lengths []int64) []*Field {
+	fields := make([]*Field, len(types))
+	for i := range types {
+		fields[i] = &Field{Type: int(types[i])}
+		if i < len(names) {
+			fields[i].Name = names[i]
+		}
+		if i < len(lengths) {
+			fields[i].Length = lengths[i]
+		}
+	}
+	return fields
+}
+
 type Event interface {
 	String() string
 }
 
 type Error struct {
-	code int
-	str  string
+	code      int
+	str       string
+	fatal     bool
+	retriable bool
 }
 
 const ErrorOther = 0xffff
 
 func NewTMQError(code int, str string) Error {
 	return Error{
-		code: code,
-		str:  str,
+		code:      code,
+		str:       str,
+		fatal:     isFatalCode(code),
+		retriable: isRetriableCode(code),
 	}
 }
 
 func NewTMQErrorWithErr(err error) Error {
 	tErr, ok := err.(*taosError.TaosError)
 	if ok {
-		return Error{
-			code: int(tErr.Code),
-			str:  tErr.ErrStr,
-		}
+		return NewTMQError(int(tErr.Code), tErr.ErrStr)
 	} else {
 		return Error{
 			code: ErrorOther,
@@ -45,6 +82,47 @@ func NewTMQErrorWithErr(err error) Error {
 	}
 }
 
+// NewFatalTMQError returns an Error after which the consumer can not poll anymore and must be closed.
+func NewFatalTMQError(err error) Error {
+	e := NewTMQErrorWithErr(err)
+	e.fatal = true
+	e.retriable = false
+	return e
+}
+
+// NewRetriableTMQError returns an Error caused by a transient failure, polling again may succeed.
+func NewRetriableTMQError(err error) Error {
+	e := NewTMQErrorWithErr(err)
+	e.fatal = false
+	e.retriable = true
+	return e
+}
+
+func isFatalCode(code int) bool {
+	return int32(code)&0xffff == taosError.TSC_INVALID_CONNECTION
+}
+
+// the codes of taoserror.h for the transient failures of a poll
+const (
+	codeRPCNetworkUnavail       int32 = 0x000B // TSDB_CODE_RPC_NETWORK_UNAVAIL
+	codeRPCBrokenLink           int32 = 0x0018 // TSDB_CODE_RPC_BROKEN_LINK
+	codeRPCTimeout              int32 = 0x0019 // TSDB_CODE_RPC_TIMEOUT
+	codeRPCSomeNodeNotConnected int32 = 0x0020 // TSDB_CODE_RPC_SOMENODE_NOT_CONNECTED
+	codeSynNotLeader            int32 = 0x090C // TSDB_CODE_SYN_NOT_LEADER
+)
+
+func isRetriableCode(code int) bool {
+	switch int32(code) & 0xffff {
+	case codeRPCNetworkUnavail,
+		codeRPCBrokenLink,
+		codeRPCTimeout,
+		codeRPCSomeNodeNotConnected,
+		codeSynNotLeader:
+		return true
+	}
+	return false
+}
+
 func (e Error) String() string {
 	return fmt.Sprintf("[0x%x] %s", e.code, e.str)
 }
@@ -57,6 +135,49 @@ func (e Error) Code() int {
 	return e.code
 }
 
+// IsFatal reports whether the consumer is unusable after this error, the only thing left to do is Close.
+func (e Error) IsFatal() bool {
+	return e.fatal
+}
+
+// IsRetriable reports whether the error is transient and the same call may succeed when polled again.
+// An error that is neither fatal nor retriable is returned by the server for this request and is left to the caller.
+func (e Error) IsRetriable() bool {
+	return e.retriable
+}
+
+// Reconnected is returned by Poll after a consumer with automatic reconnection has reestablished
+// its connection. Cause is the error that broke the previous connection, Partitions holds the
+// assignment after the session was restored.
+type Reconnected struct {
+	Cause      error
+	Partitions []TopicPartition
+}
+
+func (r Reconnected) String() string {
+	return fmt.Sprintf("Reconnected: cause: %v, partitions: %v", r.Cause, r.Partitions)
+}
+
+// AssignedPartitions is passed to the RebalanceCb of a consumer when vgroups are assigned to it,
+// Partitions hold the position of each new vgroup.
+type AssignedPartitions struct {
+	Partitions []TopicPartition
+}
+
+func (e AssignedPartitions) String() string {
+	return fmt.Sprintf("AssignedPartitions: %v", e.Partitions)
+}
+
+// RevokedPartitions is passed to the RebalanceCb of a consumer before it loses vgroups, or once it has noticed they
+// were reassigned. Partitions hold the last known position of each vgroup.
+type RevokedPartitions struct {
+	Partitions []TopicPartition
+}
+
+func (e RevokedPartitions) String() string {
+	return fmt.Sprintf("RevokedPartitions: %v", e.Partitions)
+}
+
 type Message interface {
 	Topic() string
 	DBName() string
@@ -0,0 +1,74 @@
+package tmq
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// LagIntervalKey is the ConfigMap key of both consumers that makes Poll return a ConsumerLag event every
+// lag.interval.ms milliseconds, for example "60000". It is handled by the driver and not sent to the server.
+const LagIntervalKey = "lag.interval.ms"
+
+// PartitionLag is the progress of a consumer on a vgroup of a topic.
+type PartitionLag struct {
+	Topic     string
+	Partition int32
+	Committed Offset // the committed offset, OffsetInvalid when nothing has been committed
+	Position  Offset // the offset of the next message Poll returns
+	Begin     Offset // the low watermark, the first offset still stored
+	End       Offset // the high watermark, the offset of the next message written
+	Lag       int64  // the number of messages left to consume, End - Position
+}
+
+func (l PartitionLag) String() string {
+	return fmt.Sprintf("%s[%d]@%s(committed: %s, end: %s, lag: %d)", l.Topic, l.Partition, l.Position, l.Committed, l.End, l.Lag)
+}
+
+// NewPartitionLag returns the lag of a vgroup from its assignment and committed offset.
+func NewPartitionLag(topic string, assignment *Assignment, committed Offset) PartitionLag {
+	lag := assignment.End - assignment.Offset
+	if lag < 0 {
+		lag = 0
+	}
+	return PartitionLag{
+		Topic:     topic,
+		Partition: assignment.VGroupID,
+		Committed: committed,
+		Position:  Offset(assignment.Offset),
+		Begin:     Offset(assignment.Begin),
+		End:       Offset(assignment.End),
+		Lag:       lag,
+	}
+}
+
+// ConsumerLag is returned by Poll every lag.interval.ms milliseconds, see LagIntervalKey.
+// It holds the lag of every vgroup assigned to the consumer.
+type ConsumerLag struct {
+	Partitions []PartitionLag
+}
+
+// Total returns the number of messages left to consume on all vgroups.
+func (e ConsumerLag) Total() int64 {
+	var total int64
+	for _, p := range e.Partitions {
+		total += p.Lag
+	}
+	return total
+}
+
+func (e ConsumerLag) String() string {
+	return fmt.Sprintf("ConsumerLag: %v", e.Partitions)
+}
+
+// ParseLagInterval parses the value of LagIntervalKey in milliseconds, "" disables the ConsumerLag events.
+func ParseLagInterval(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return 0, errors.New(LagIntervalKey + " requires a non-negative integer")
+	}
+	return ms, nil
+}
@@ -0,0 +1,86 @@
+package tmq
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type topicVgroup struct {
+	topic    string
+	vgroupID int32
+}
+
+// AssignmentTracker remembers the assignment of a consumer to report how it changes, the consumers use it
+// to call their RebalanceCb with AssignedPartitions and RevokedPartitions events.
+type AssignmentTracker struct {
+	lock       sync.Mutex
+	partitions map[topicVgroup]TopicPartition
+	lastCheck  time.Time
+}
+
+// Due reports whether interval has passed since the assignment was last updated, it is always due after Reset.
+func (t *AssignmentTracker) Due(interval time.Duration) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.lastCheck.IsZero() || time.Since(t.lastCheck) >= interval
+}
+
+// Expire makes the next Due return true, the assignment is checked again after a subscription.
+func (t *AssignmentTracker) Expire() {
+	t.lock.Lock()
+	t.lastCheck = time.Time{}
+	t.lock.Unlock()
+}
+
+// Update replaces the remembered assignment with partitions and returns the vgroups that were added and removed,
+// both sorted by topic and vgroup.
+func (t *AssignmentTracker) Update(partitions []TopicPartition) (assigned []TopicPartition, revoked []TopicPartition) {
+	current := make(map[topicVgroup]TopicPartition, len(partitions))
+	for _, p := range partitions {
+		if p.Topic == nil {
+			continue
+		}
+		current[topicVgroup{topic: *p.Topic, vgroupID: p.Partition}] = p
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, p := range current {
+		if _, exist := t.partitions[key]; !exist {
+			assigned = append(assigned, p)
+		}
+	}
+	for key, p := range t.partitions {
+		if _, exist := current[key]; !exist {
+			revoked = append(revoked, p)
+		}
+	}
+	t.partitions = current
+	t.lastCheck = time.Now()
+	sortPartitions(assigned)
+	sortPartitions(revoked)
+	return assigned, revoked
+}
+
+// Reset forgets the assignment and returns it sorted by topic and vgroup, the next Due returns true.
+func (t *AssignmentTracker) Reset() []TopicPartition {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	partitions := make([]TopicPartition, 0, len(t.partitions))
+	for _, p := range t.partitions {
+		partitions = append(partitions, p)
+	}
+	t.partitions = nil
+	t.lastCheck = time.Time{}
+	sortPartitions(partitions)
+	return partitions
+}
+
+func sortPartitions(partitions []TopicPartition) {
+	sort.Slice(partitions, func(i, j int) bool {
+		if *partitions[i].Topic != *partitions[j].Topic {
+			return *partitions[i].Topic < *partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+}
@@ -2,7 +2,14 @@ package tmq
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	taosError "github.com/taosdata/driver-go/v3/errors"
 )
 
 const createJson = `{
@@ -66,3 +73,104 @@ func TestDropJson(t *testing.T) {
 	}
 	t.Log(obj)
 }
+
+func TestErrorClassification(t *testing.T) {
+	e := NewTMQErrorWithErr(taosError.NewError(int(codeRPCBrokenLink), "Conn is broken"))
+	assert.Equal(t, int(codeRPCBrokenLink), e.Code())
+	assert.True(t, e.IsRetriable())
+	assert.False(t, e.IsFatal())
+
+	e = NewTMQError(int(taosError.TSC_INVALID_CONNECTION), "Invalid connection")
+	assert.True(t, e.IsFatal())
+	assert.False(t, e.IsRetriable())
+
+	e = NewTMQError(0x2603, "Table does not exist")
+	assert.False(t, e.IsFatal())
+	assert.False(t, e.IsRetriable())
+
+	e = NewFatalTMQError(errors.New("connection closed"))
+	assert.Equal(t, ErrorOther, e.Code())
+	assert.True(t, e.IsFatal())
+	assert.False(t, e.IsRetriable())
+
+	e = NewRetriableTMQError(errors.New("message timeout"))
+	assert.True(t, e.IsRetriable())
+	assert.False(t, e.IsFatal())
+}
+
+func TestAssignmentTracker(t *testing.T) {
+	topic := "t"
+	partition := func(vgroupID int32, offset Offset) TopicPartition {
+		return TopicPartition{Topic: &topic, Partition: vgroupID, Offset: offset}
+	}
+	var tracker AssignmentTracker
+	assert.True(t, tracker.Due(time.Hour))
+	assigned, revoked := tracker.Update([]TopicPartition{partition(2, 0), partition(1, 0)})
+	assert.Equal(t, []TopicPartition{partition(1, 0), partition(2, 0)}, assigned)
+	assert.Empty(t, revoked)
+	assert.False(t, tracker.Due(time.Hour))
+	assert.True(t, tracker.Due(0))
+
+	// a new position is not a change of the assignment
+	assigned, revoked = tracker.Update([]TopicPartition{partition(2, 5), partition(3, 0)})
+	assert.Equal(t, []TopicPartition{partition(3, 0)}, assigned)
+	assert.Equal(t, []TopicPartition{partition(1, 0)}, revoked)
+
+	tracker.Expire()
+	assert.True(t, tracker.Due(time.Hour))
+	assert.Equal(t, []TopicPartition{partition(2, 5), partition(3, 0)}, tracker.Reset())
+	assert.Empty(t, tracker.Reset())
+	assert.Equal(t, "AssignedPartitions: [t[3]@0]", AssignedPartitions{Partitions: []TopicPartition{partition(3, 0)}}.String())
+}
+
+func TestDataFields(t *testing.T) {
+	data := &Data{Fields: NewFields([]string{"ts", "v"}, []uint8{9, 4}, []int64{8, 4})}
+	assert.Equal(t, []*Field{{Name: "ts", Type: 9, Length: 8}, {Name: "v", Type: 4, Length: 4}}, data.Fields)
+	assert.Equal(t, 0, data.FieldIndex("ts"))
+	assert.Equal(t, 1, data.FieldIndex("v"))
+	assert.Equal(t, -1, data.FieldIndex("missing"))
+}
+
+func TestPollBatch(t *testing.T) {
+	var timeouts []int
+	events := []Event{&DataMessage{}, nil, &DataMessage{}, NewTMQError(1, "failed"), &DataMessage{}}
+	poll := func(timeoutMs int) Event {
+		timeouts = append(timeouts, timeoutMs)
+		ev := events[0]
+		events = events[1:]
+		return ev
+	}
+	assert.Nil(t, PollBatch(poll, 0, time.Second))
+	assert.Len(t, PollBatch(poll, 10, time.Second), 1)
+	assert.Len(t, timeouts, 2)
+	assert.True(t, timeouts[0] > 900 && timeouts[0] <= 1000)
+	// an error ends the batch
+	batch := PollBatch(poll, 10, time.Second)
+	assert.Len(t, batch, 2)
+	assert.IsType(t, Error{}, batch[1])
+	assert.Len(t, PollBatch(poll, 1, time.Second), 1)
+	assert.Empty(t, events)
+	// without a timeout poll is called once
+	events = []Event{&DataMessage{}, &DataMessage{}}
+	timeouts = nil
+	assert.Len(t, PollBatch(poll, 10, 0), 1)
+	assert.Equal(t, []int{0}, timeouts)
+}
+
+func TestParseLogger(t *testing.T) {
+	l := common.NewTextLogger(io.Discard, common.LogLevelInfo)
+	assert.NoError(t, common.RegisterLogger("tmq_test", l))
+	defer common.DeregisterLogger("tmq_test")
+	for _, value := range []ConfigValue{"tmq_test", l} {
+		got, err := ParseLogger("logger", value)
+		assert.NoError(t, err)
+		assert.Equal(t, l, got)
+	}
+	got, err := ParseLogger("logger", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	_, err = ParseLogger("logger", "unknown")
+	assert.Error(t, err)
+	_, err = ParseLogger("logger", 1)
+	assert.EqualError(t, err, "logger requires string or common.Logger got int")
+}
@@ -0,0 +1,45 @@
+package tmq
+
+import (
+	"context"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// StartPollSpan starts tracing a consumer Poll, see common.Tracer.
+func StartPollSpan() common.Span {
+	_, span := common.StartSpan(context.Background(), common.OpPoll)
+	return span
+}
+
+// EndPollSpan ends the span of a Poll with the event it returned, a nil event means no message was received.
+func EndPollSpan(span common.Span, ev Event) {
+	var err error
+	switch e := ev.(type) {
+	case Error:
+		err = e
+	case *DataMessage:
+		span.SetAttributes(
+			common.Attribute{Key: common.AttrTopic, Value: e.topic},
+			common.Attribute{Key: common.AttrRows, Value: countRows(e.data)},
+		)
+	case *MetaMessage:
+		span.SetAttributes(common.Attribute{Key: common.AttrTopic, Value: e.topic})
+	case *MetaDataMessage:
+		span.SetAttributes(common.Attribute{Key: common.AttrTopic, Value: e.topic})
+		if e.metaData != nil {
+			span.SetAttributes(common.Attribute{Key: common.AttrRows, Value: countRows(e.metaData.Data)})
+		}
+	}
+	common.EndSpan(span, err)
+}
+
+func countRows(data []*Data) int {
+	rows := 0
+	for _, d := range data {
+		if d != nil {
+			rows += len(d.Data)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// Operations traced by the drivers, passed to Tracer.Start.
+const (
+	OpQuery      = "query"
+	OpExec       = "exec"
+	OpStmtExec   = "stmt_exec"
+	OpSchemaless = "schemaless_insert"
+	OpPoll       = "tmq_poll"
+)
+
+// Keys of the span attributes set by the drivers.
+const (
+	AttrSQL          = "db.statement"
+	AttrReqID        = "db.taos.req_id"
+	AttrRows         = "db.taos.rows"          // rows read from a query result or received by a poll
+	AttrAffectedRows = "db.taos.affected_rows" // rows written by an exec, stmt execute or schemaless insert
+	AttrErrorCode    = "db.taos.error_code"    // the code of a failed operation, 0xffff for errors not returned by the server
+	AttrEndpoint     = "server.address"        // host:port the operation was sent to
+	AttrTopic        = "messaging.destination"
+	// AttrSchemalessProtocol is the protocol of a schemaless insert, 1 influxdb line, 2 opentsdb telnet, 3 opentsdb json
+	AttrSchemalessProtocol = "db.taos.schemaless.protocol"
+)
+
+// Attribute is a key value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer is invoked by every driver around queries, execs, stmt executes, schemaless inserts and tmq polls,
+// it can be implemented on top of a tracing library such as OpenTelemetry.
+type Tracer interface {
+	// Start is called when an operation starts, the returned context is used for the rest of the operation.
+	Start(ctx context.Context, operation string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is an operation started by a Tracer.
+type Span interface {
+	// SetAttributes adds attributes while the operation runs.
+	SetAttributes(attrs ...Attribute)
+	// End is called once when the operation finishes, err is nil on success.
+	// A query ends when its rows are read to the end or closed.
+	End(err error)
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Value
+
+// SetTracer installs the Tracer used by all drivers, nil disables tracing.
+func SetTracer(tracer Tracer) {
+	globalTracer.Store(tracerHolder{tracer: tracer})
+}
+
+// GetTracer returns the Tracer installed with SetTracer, nil if there is none.
+func GetTracer() Tracer {
+	holder, _ := globalTracer.Load().(tracerHolder)
+	return holder.tracer
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) End(error) {}
+
+// StartSpan starts an operation with the installed Tracer, the returned Span does nothing when there is none.
+func StartSpan(ctx context.Context, operation string, attrs ...Attribute) (context.Context, Span) {
+	tracer := GetTracer()
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, operation, attrs...)
+}
+
+// EndSpan ends span with err, setting the AttrErrorCode attribute when err is not nil.
+func EndSpan(span Span, err error) {
+	if err != nil {
+		code := int32(taosErrors.UNKNOWN)
+		var taosErr *taosErrors.TaosError
+		var codeErr interface{ Code() int }
+		if errors.As(err, &taosErr) {
+			code = taosErr.Code
+		} else if errors.As(err, &codeErr) {
+			// tmq.Error
+			code = int32(codeErr.Code())
+		}
+		span.SetAttributes(Attribute{Key: AttrErrorCode, Value: code})
+	}
+	span.End(err)
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+type testSpan struct {
+	attrs []Attribute
+	err   error
+	ended int
+}
+
+func (s *testSpan) SetAttributes(attrs ...Attribute) {
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.ended++
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, operation string, attrs ...Attribute) (context.Context, Span) {
+	span := &testSpan{attrs: append([]Attribute{{Key: "operation", Value: operation}}, attrs...)}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestStartSpan(t *testing.T) {
+	_, span := StartSpan(context.Background(), OpQuery)
+	assert.Equal(t, noopSpan{}, span)
+
+	tracer := &testTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+	_, span = StartSpan(context.Background(), OpExec, Attribute{Key: AttrSQL, Value: "create database test"})
+	EndSpan(span, nil)
+	if assert.Len(t, tracer.spans, 1) {
+		assert.Equal(t, []Attribute{
+			{Key: "operation", Value: OpExec},
+			{Key: AttrSQL, Value: "create database test"},
+		}, tracer.spans[0].attrs)
+		assert.Equal(t, 1, tracer.spans[0].ended)
+		assert.NoError(t, tracer.spans[0].err)
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		code int32
+	}{
+		{err: taosErrors.NewError(0x2662, "Table does not exist"), code: 0x2662},
+		{err: fmt.Errorf("query: %w", taosErrors.NewError(0x216, "syntax error")), code: 0x216},
+		{err: errors.New("connection reset"), code: taosErrors.UNKNOWN},
+	} {
+		span := &testSpan{}
+		EndSpan(span, c.err)
+		assert.Equal(t, []Attribute{{Key: AttrErrorCode, Value: c.code}}, span.attrs)
+		assert.Equal(t, c.err, span.err)
+	}
+}
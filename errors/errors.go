@@ -8,6 +8,7 @@ import "fmt"
 type TaosError struct {
 	Code   int32
 	ErrStr string
+	ReqID  int64 // the request ID sent with the failed request, 0 if unknown
 }
 
 const (
@@ -36,3 +37,12 @@ func NewError(code int, errStr string) error {
 		ErrStr: errStr,
 	}
 }
+
+// NewErrorWithReqID is NewError for an error returned to the request with the given request ID
+func NewErrorWithReqID(code int, errStr string, reqID int64) error {
+	return &TaosError{
+		Code:   int32(code) & 0xffff,
+		ErrStr: errStr,
+		ReqID:  reqID,
+	}
+}
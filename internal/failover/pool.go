@@ -0,0 +1,153 @@
+// Package failover chooses the taosAdapter endpoint of a multi-endpoint DSN for taosWS and taosRestful.
+package failover
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LoadBalanceRoundRobin  = "roundRobin"
+	LoadBalanceLeastLoaded = "leastLoaded"
+)
+
+const (
+	DefaultBackoff = time.Second
+	MaxBackoff     = time.Minute
+)
+
+// Endpoint is one taosAdapter address of a multi-endpoint DSN.
+type Endpoint struct {
+	Addr      string // host:port
+	active    int    // open connections of taosWS, requests in flight of taosRestful
+	failures  int    // consecutive failures
+	downUntil time.Time
+}
+
+// Pool chooses the endpoint a connection dials or a request is sent to. Endpoints that fail are
+// skipped until their backoff expires, the backoff doubles with every consecutive failure.
+type Pool struct {
+	lock      sync.Mutex
+	endpoints []*Endpoint
+	next      int
+	policy    string
+	backoff   time.Duration
+}
+
+// pools shares endpoint health between every connector using the same endpoints.
+var pools sync.Map
+
+// GetPool returns the Pool shared by the connectors of scope, the network of their DSN, using addrs with
+// the same policy and backoff.
+func GetPool(scope string, addrs []string, policy string, backoff time.Duration) *Pool {
+	key := fmt.Sprintf("%s|%s|%s|%s", scope, strings.Join(addrs, ","), policy, backoff)
+	if pool, ok := pools.Load(key); ok {
+		return pool.(*Pool)
+	}
+	pool, _ := pools.LoadOrStore(key, NewPool(addrs, policy, backoff))
+	return pool.(*Pool)
+}
+
+// NewPool returns a Pool of addrs, DefaultBackoff is used when backoff is not positive.
+func NewPool(addrs []string, policy string, backoff time.Duration) *Pool {
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	endpoints := make([]*Endpoint, len(addrs))
+	for i, addr := range addrs {
+		endpoints[i] = &Endpoint{Addr: addr}
+	}
+	return &Pool{
+		endpoints: endpoints,
+		policy:    policy,
+		backoff:   backoff,
+	}
+}
+
+// Endpoints returns the endpoints in the order of the DSN.
+func (p *Pool) Endpoints() []*Endpoint {
+	return p.endpoints
+}
+
+// Candidates returns the endpoints in the order they should be tried, healthy endpoints first
+// ordered by the load balancing policy, then unhealthy endpoints ordered by the end of their backoff.
+func (p *Pool) Candidates() []*Endpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := time.Now()
+	n := len(p.endpoints)
+	healthy := make([]*Endpoint, 0, n)
+	var unhealthy []*Endpoint
+	for i := 0; i < n; i++ {
+		e := p.endpoints[(p.next+i)%n]
+		if e.downUntil.After(now) {
+			unhealthy = append(unhealthy, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	p.next = (p.next + 1) % n
+	if p.policy == LoadBalanceLeastLoaded {
+		sort.SliceStable(healthy, func(i, j int) bool {
+			return healthy[i].active < healthy[j].active
+		})
+	}
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		return unhealthy[i].downUntil.Before(unhealthy[j].downUntil)
+	})
+	return append(healthy, unhealthy...)
+}
+
+// MarkFailed skips e until its backoff expires.
+func (p *Pool) MarkFailed(e *Endpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	backoff := p.backoff
+	for i := 0; i < e.failures && backoff < MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxBackoff {
+		backoff = MaxBackoff
+	}
+	e.failures += 1
+	e.downUntil = time.Now().Add(backoff)
+}
+
+// MarkHealthy resets the backoff of e.
+func (p *Pool) MarkHealthy(e *Endpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	e.failures = 0
+	e.downUntil = time.Time{}
+}
+
+// Acquire counts a connection or request using e, for LoadBalanceLeastLoaded.
+func (p *Pool) Acquire(e *Endpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	e.active += 1
+}
+
+// Release ends a connection or request counted by Acquire.
+func (p *Pool) Release(e *Endpoint) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	e.active -= 1
+}
+
+// Active returns the number of connections or requests using e.
+func (p *Pool) Active(e *Endpoint) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return e.active
+}
+
+// Failures returns the number of consecutive failures of e.
+func (p *Pool) Failures(e *Endpoint) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return e.failures
+}
@@ -0,0 +1,68 @@
+package failover
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addrsOf(endpoints []*Endpoint) []string {
+	addrs := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		addrs[i] = e.Addr
+	}
+	return addrs
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	pool := NewPool([]string{"a:1", "b:1", "c:1"}, LoadBalanceRoundRobin, time.Minute)
+	assert.Equal(t, []string{"a:1", "b:1", "c:1"}, addrsOf(pool.Candidates()))
+	assert.Equal(t, []string{"b:1", "c:1", "a:1"}, addrsOf(pool.Candidates()))
+	pool.MarkFailed(pool.endpoints[2])
+	// unhealthy endpoints are tried last
+	assert.Equal(t, []string{"a:1", "b:1", "c:1"}, addrsOf(pool.Candidates()))
+	assert.Equal(t, []string{"a:1", "b:1", "c:1"}, addrsOf(pool.Candidates()))
+	pool.MarkHealthy(pool.endpoints[2])
+	assert.Equal(t, []string{"b:1", "c:1", "a:1"}, addrsOf(pool.Candidates()))
+}
+
+func TestPoolLeastLoaded(t *testing.T) {
+	pool := NewPool([]string{"a:1", "b:1", "c:1"}, LoadBalanceLeastLoaded, time.Minute)
+	pool.Acquire(pool.endpoints[0])
+	pool.Acquire(pool.endpoints[0])
+	pool.Acquire(pool.endpoints[1])
+	assert.Equal(t, []string{"c:1", "b:1", "a:1"}, addrsOf(pool.Candidates()))
+	pool.Release(pool.endpoints[0])
+	pool.Release(pool.endpoints[0])
+	pool.Acquire(pool.endpoints[2])
+	assert.Equal(t, "a:1", pool.Candidates()[0].Addr)
+}
+
+func TestPoolBackoff(t *testing.T) {
+	pool := NewPool([]string{"a:1", "b:1"}, LoadBalanceRoundRobin, time.Second)
+	e := pool.endpoints[0]
+	pool.MarkFailed(e)
+	first := time.Until(e.downUntil)
+	pool.MarkFailed(e)
+	second := time.Until(e.downUntil)
+	assert.True(t, first <= time.Second && first > 0)
+	assert.True(t, second > time.Second && second <= 2*time.Second)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b:1", pool.Candidates()[0].Addr)
+	}
+	for i := 0; i < 10; i++ {
+		pool.MarkFailed(e)
+	}
+	assert.True(t, time.Until(e.downUntil) <= MaxBackoff)
+	pool.MarkHealthy(e)
+	assert.Equal(t, 0, pool.Failures(e))
+	assert.True(t, e.downUntil.IsZero())
+	assert.Equal(t, 2, len(pool.Candidates()))
+}
+
+func TestGetPool(t *testing.T) {
+	a := GetPool("ws", []string{"a:1", "b:1"}, LoadBalanceRoundRobin, time.Second)
+	assert.Same(t, a, GetPool("ws", []string{"a:1", "b:1"}, LoadBalanceRoundRobin, time.Second))
+	assert.NotSame(t, a, GetPool("http", []string{"a:1", "b:1"}, LoadBalanceRoundRobin, time.Second))
+}
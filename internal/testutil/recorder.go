@@ -0,0 +1,138 @@
+// Package testutil records what the drivers report to the common.Tracer, common.Metrics and common.Logger
+// hooks, for the tests of taosWS and ws/tmq.
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// Span is a span started by a Tracer.
+type Span struct {
+	Operation string
+	Attrs     map[string]interface{}
+	Err       error
+	Ended     bool
+}
+
+func (s *Span) SetAttributes(attrs ...common.Attribute) {
+	for _, attr := range attrs {
+		s.Attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *Span) End(err error) {
+	s.Err = err
+	s.Ended = true
+}
+
+// Tracer is a common.Tracer keeping every span it starts.
+type Tracer struct {
+	lock  sync.Mutex
+	spans []*Span
+}
+
+func (t *Tracer) Start(ctx context.Context, operation string, attrs ...common.Attribute) (context.Context, common.Span) {
+	span := &Span{Operation: operation, Attrs: map[string]interface{}{}}
+	span.SetAttributes(attrs...)
+	t.lock.Lock()
+	t.spans = append(t.spans, span)
+	t.lock.Unlock()
+	return ctx, span
+}
+
+// Spans returns the spans started so far.
+func (t *Tracer) Spans() []*Span {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]*Span(nil), t.spans...)
+}
+
+// Metrics is a common.Metrics keeping the requests it observes as "driver action".
+type Metrics struct {
+	lock       sync.Mutex
+	requests   []string
+	errors     int
+	bytes      map[string]int
+	reconnects int
+}
+
+func (m *Metrics) ObserveRequest(driver string, action string, duration time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.requests = append(m.requests, driver+" "+action)
+	if err != nil {
+		m.errors += 1
+	}
+}
+
+func (m *Metrics) AddBytes(driver string, direction string, n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.bytes == nil {
+		m.bytes = map[string]int{}
+	}
+	m.bytes[direction] += n
+}
+
+func (m *Metrics) IncReconnect(driver string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.reconnects += 1
+}
+
+// Requests returns the requests observed so far.
+func (m *Metrics) Requests() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+// Errors returns the number of failed requests.
+func (m *Metrics) Errors() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.errors
+}
+
+// Bytes returns the number of bytes of direction, common.BytesSent or common.BytesReceived.
+func (m *Metrics) Bytes(direction string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.bytes[direction]
+}
+
+// Reconnects returns the number of reconnects.
+func (m *Metrics) Reconnects() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.reconnects
+}
+
+// Logger is a common.Logger keeping its entries as "LEVEL msg", followed by the SQL of the entries with one.
+type Logger struct {
+	lock    sync.Mutex
+	entries []string
+}
+
+func (l *Logger) Log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	entry := level.String() + " " + msg
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == common.LogKeySQL {
+			entry += " " + keyvals[i+1].(string)
+		}
+	}
+	l.lock.Lock()
+	l.entries = append(l.entries, entry)
+	l.lock.Unlock()
+}
+
+// Entries returns the entries logged so far.
+func (l *Logger) Entries() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]string(nil), l.entries...)
+}
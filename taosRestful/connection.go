@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -12,12 +13,15 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/internal/failover"
 )
 
 var jsonI = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -28,6 +32,12 @@ type taosConn struct {
 	url            *url.URL
 	header         map[string][]string
 	readBufferSize int
+	pool           *failover.Pool
+	tx             *common.BatchTx // the current transaction
+}
+
+func getEndpointPool(cfg *config) *failover.Pool {
+	return failover.GetPool(cfg.net, cfg.hostAddrs(), cfg.loadBalance, cfg.endpointBackoff)
 }
 
 func newTaosConn(cfg *config) (*taosConn, error) {
@@ -35,7 +45,7 @@ func newTaosConn(cfg *config) (*taosConn, error) {
 	if readBufferSize <= 0 {
 		readBufferSize = 4 << 10
 	}
-	tc := &taosConn{cfg: cfg, readBufferSize: readBufferSize}
+	tc := &taosConn{cfg: cfg, readBufferSize: readBufferSize, pool: getEndpointPool(cfg)}
 	tc.client = &http.Client{
 		Transport: &http.Transport{
 			Proxy: http.ProxyFromEnvironment,
@@ -47,15 +57,16 @@ func newTaosConn(cfg *config) (*taosConn, error) {
 			TLSHandshakeTimeout:   10 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
 			DisableCompression:    cfg.disableCompression,
+			TLSClientConfig:       cfg.tlsConfig,
 		},
 	}
 	path := "/rest/sql"
 	if len(cfg.dbName) != 0 {
 		path = fmt.Sprintf("%s/%s", path, cfg.dbName)
 	}
+	// Host is filled in per request by the endpoint pool
 	tc.url = &url.URL{
-		Scheme: cfg.net,
-		Host:   fmt.Sprintf("%s:%d", cfg.addr, cfg.port),
+		Scheme: common.TLSScheme(cfg.net, cfg.tlsConfig),
 		Path:   path,
 	}
 	tc.header = map[string][]string{
@@ -74,7 +85,7 @@ func newTaosConn(cfg *config) (*taosConn, error) {
 }
 
 func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support transaction"}
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
 }
 
 func (tc *taosConn) Close() (err error) {
@@ -82,6 +93,7 @@ func (tc *taosConn) Close() (err error) {
 	tc.url = nil
 	tc.cfg = nil
 	tc.header = nil
+	tc.pool = nil
 	return nil
 }
 
@@ -89,11 +101,34 @@ func (tc *taosConn) Prepare(query string) (driver.Stmt, error) {
 	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support stmt"}
 }
 
+// CheckNamedValue keeps geometry arguments for common.WriteValue, see common.CheckNamedValue.
+func (tc *taosConn) CheckNamedValue(v *driver.NamedValue) error {
+	return common.CheckNamedValue(v)
+}
+
+// flush executes the statements buffered by the transaction before a statement that is not buffered runs
+func (tc *taosConn) flush(ctx context.Context) error {
+	if tc.tx == nil {
+		return nil
+	}
+	return tc.tx.Flush(ctx)
+}
+
 func (tc *taosConn) Exec(query string, args []driver.Value) (driver.Result, error) {
 	return tc.ExecContext(context.Background(), query, common.ValueArgsToNamedValueArgs(args))
 }
 
 func (tc *taosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	if tc.tx != nil {
+		buffered, err := tc.tx.Add(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		if buffered {
+			// the rows are inserted when the transaction commits, their number is not known yet
+			return driver.RowsAffected(0), nil
+		}
+	}
 	return tc.execCtx(ctx, query, args)
 }
 
@@ -103,20 +138,28 @@ func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.Nam
 			return nil, driver.ErrSkip
 		}
 		// try to interpolate the parameters to save extra round trips for preparing and closing a statement
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
-	result, err := tc.taosQuery(ctx, query, 512)
+	reqID := common.GetReqIDFromContext(ctx)
+	ctx, span := startSpan(ctx, common.OpExec, query, reqID)
+	start := time.Now()
+	result, err := tc.taosQuery(ctx, span, query, reqID, 512)
+	tc.logSlowQuery(start, query, reqID)
+	if err == nil && (len(result.Data) != 1 || len(result.Data[0]) != 1) {
+		err = errors.New("wrong result")
+	}
 	if err != nil {
+		common.EndSpan(span, err)
 		return nil, err
 	}
-	if len(result.Data) != 1 || len(result.Data[0]) != 1 {
-		return nil, errors.New("wrong result")
-	}
-	return driver.RowsAffected(result.Data[0][0].(int32)), nil
+	affected := result.Data[0][0].(int32)
+	span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: int(affected)})
+	common.EndSpan(span, nil)
+	return driver.RowsAffected(affected), nil
 }
 
 func (tc *taosConn) Query(query string, args []driver.Value) (driver.Rows, error) {
@@ -125,27 +168,19 @@ func (tc *taosConn) Query(query string, args []driver.Value) (driver.Rows, error
 			return nil, driver.ErrSkip
 		}
 		// try client-side prepare to reduce round trip
-		prepared, err := common.InterpolateParams(query, common.ValueArgsToNamedValueArgs(args))
+		prepared, err := common.InterpolateParamsWithPrecision(query, common.ValueArgsToNamedValueArgs(args), tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
-	result, err := tc.taosQuery(context.TODO(), query, tc.readBufferSize)
-	if err != nil {
-		return nil, err
-	}
-	if result == nil {
-		return nil, errors.New("wrong result")
-	}
-	// Read Result
-	rs := &rows{
-		result: result,
-	}
-	return rs, err
+	return tc.query(context.TODO(), query)
 }
 
 func (tc *taosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	if err := tc.flush(ctx); err != nil {
+		return nil, err
+	}
 	return tc.queryCtx(ctx, query, args)
 }
 
@@ -155,76 +190,263 @@ func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.Na
 			return nil, driver.ErrSkip
 		}
 		// try client-side prepare to reduce round trip
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
-	result, err := tc.taosQuery(ctx, query, tc.readBufferSize)
+	return tc.query(ctx, query)
+}
+
+// query starts streaming the rows of query, the span of the query ends when the rows are closed
+func (tc *taosConn) query(ctx context.Context, query string) (driver.Rows, error) {
+	reqID := common.GetReqIDFromContext(ctx)
+	ctx, span := startSpan(ctx, common.OpQuery, query, reqID)
+	start := time.Now()
+	rs, err := tc.taosQueryStream(ctx, span, query, reqID, tc.readBufferSize)
+	tc.logSlowQuery(start, query, reqID)
 	if err != nil {
+		common.EndSpan(span, err)
 		return nil, err
 	}
-	if result == nil {
-		return nil, errors.New("wrong result")
+	rs.span = span
+	if rs.done {
+		rs.endSpan()
 	}
-	// Read Result
-	rs := &rows{
-		result: result,
+	return rs, nil
+}
+
+// startSpan starts tracing an operation, see common.Tracer
+func startSpan(ctx context.Context, operation string, sql string, reqID int64) (context.Context, common.Span) {
+	return common.StartSpan(ctx, operation,
+		common.Attribute{Key: common.AttrSQL, Value: sql},
+		common.Attribute{Key: common.AttrReqID, Value: reqID},
+	)
+}
+
+// log reports the failed requests, undecodable responses and slow queries to the logger of the DSN
+func (tc *taosConn) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(tc.cfg.logger, level, msg, keyvals...)
+}
+
+// logSlowQuery logs query when it took longer than the slowQueryThreshold of the DSN since start
+func (tc *taosConn) logSlowQuery(start time.Time, query string, reqID int64) {
+	if tc.cfg.slowQueryThreshold <= 0 {
+		return
+	}
+	if duration := time.Since(start); duration >= tc.cfg.slowQueryThreshold {
+		tc.log(common.LogLevelWarn, "slow query",
+			common.LogKeySQL, query,
+			common.LogKeyReqID, reqID,
+			common.LogKeyDuration, duration,
+		)
 	}
-	return rs, err
 }
 
 func (tc *taosConn) Ping(ctx context.Context) (err error) {
 	return nil
 }
 
+// BeginTx implements driver.ConnBeginTx interface. TDengine has no transactions, the INSERT statements executed in
+// the transaction are buffered and merged into multi-table INSERT statements, see common.BatchTx.
 func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "restful does not support transaction"}
+	tc.tx = common.NewBatchTx(ctx, tc.cfg.timePrecision, func(ctx context.Context, query string) error {
+		_, err := tc.execCtx(ctx, query, nil)
+		return err
+	})
+	return tc.tx, nil
 }
 
-func (tc *taosConn) taosQuery(ctx context.Context, sql string, bufferSize int) (*common.TDEngineRestfulResp, error) {
-	body := ioutil.NopCloser(strings.NewReader(sql))
+// taosQuery sends sql and decodes the whole response.
+func (tc *taosConn) taosQuery(ctx context.Context, span common.Span, sql string, reqID int64, bufferSize int) (*common.TDEngineRestfulResp, error) {
+	body, err := tc.request(ctx, span, sql, reqID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := marshalBody(body, bufferSize)
+	if err != nil {
+		tc.log(common.LogLevelError, "decode response failed", common.LogKeyReqID, reqID, common.LogKeyError, err)
+		return nil, err
+	}
+	if data.Code != 0 {
+		return nil, taosErrors.NewErrorWithReqID(data.Code, data.Desc, reqID)
+	}
+	return data, nil
+}
+
+// taosQueryStream sends sql and decodes the response up to the first row, the rows are decoded from the body as
+// rows.Next is called.
+func (tc *taosConn) taosQueryStream(ctx context.Context, span common.Span, sql string, reqID int64, bufferSize int) (*rows, error) {
+	body, err := tc.request(ctx, span, sql, reqID)
+	if err != nil {
+		return nil, err
+	}
+	iter := jsonI.BorrowIterator(make([]byte, bufferSize))
+	iter.Reset(body)
+	result := &common.TDEngineRestfulResp{}
+	inData, err := readFields(iter, result)
+	if err != nil {
+		tc.log(common.LogLevelError, "decode response failed", common.LogKeyReqID, reqID, common.LogKeyError, err)
+	} else if result.Code != 0 {
+		err = taosErrors.NewErrorWithReqID(result.Code, result.Desc, reqID)
+	}
+	if err != nil {
+		jsonI.ReturnIterator(iter)
+		body.Close()
+		return nil, err
+	}
+	rs := &rows{
+		result: result,
+		body:   body,
+		iter:   iter,
+		reqID:  reqID,
+		logger: tc.cfg.logger,
+	}
+	if !inData {
+		rs.finish()
+	}
+	return rs, nil
+}
+
+// request sends sql to the hosts in the order chosen by the endpoint pool and returns the body of the first
+// successful response. A host that fails with a connection error or a 5xx response is marked unhealthy,
+// idempotent statements (SELECT, SHOW, DESCRIBE) are then retried on the next host.
+// reqID is sent as the req_id query parameter, the host of every attempt is recorded in span.
+func (tc *taosConn) request(ctx context.Context, span common.Span, sql string, reqID int64) (io.ReadCloser, error) {
+	retry := isIdempotent(sql)
+	var err error
+	for _, e := range tc.pool.Candidates() {
+		span.SetAttributes(common.Attribute{Key: common.AttrEndpoint, Value: e.Addr})
+		var body io.ReadCloser
+		var hostFailed bool
+		body, hostFailed, err = tc.doRequest(ctx, e, sql, reqID)
+		if err != nil && ctx != nil && ctx.Err() != nil {
+			return nil, err
+		}
+		if !hostFailed {
+			tc.pool.MarkHealthy(e)
+			return body, err
+		}
+		tc.pool.MarkFailed(e)
+		tc.log(common.LogLevelWarn, "request to endpoint failed",
+			common.LogKeyEndpoint, e.Addr,
+			common.LogKeyReqID, reqID,
+			common.LogKeyError, err,
+		)
+		if !retry {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// doRequest sends sql to one host, hostFailed reports a connection error or a 5xx response.
+// The host counts as busy until the returned body is closed.
+func (tc *taosConn) doRequest(ctx context.Context, e *failover.Endpoint, sql string, reqID int64) (body io.ReadCloser, hostFailed bool, err error) {
+	start := time.Now()
+	tc.pool.Acquire(e)
+	defer func() {
+		if body == nil {
+			tc.pool.Release(e)
+		}
+		common.RecordRequest(tc.cfg.metrics, common.DriverTaosRestful, common.ActionSQL, start, err)
+	}()
+	u := *tc.url
+	u.Host = e.Addr
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += "req_id=" + strconv.FormatInt(reqID, 10)
 	req := &http.Request{
 		Method:     http.MethodPost,
-		URL:        tc.url,
+		URL:        &u,
 		Proto:      "HTTP/1.1",
 		ProtoMajor: 1,
 		ProtoMinor: 1,
 		Header:     tc.header,
-		Body:       body,
-		Host:       tc.url.Host,
+		Body:       ioutil.NopCloser(strings.NewReader(sql)),
+		Host:       u.Host,
 	}
 	if ctx != nil {
 		req = req.WithContext(ctx)
 	}
+	common.RecordBytes(tc.cfg.metrics, common.DriverTaosRestful, common.BytesSent, len(sql))
 	resp, err := tc.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, true, err
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, true, err
 		}
-		return nil, fmt.Errorf("server response: %s - %s", resp.Status, string(body))
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("server response: %s - %s", resp.Status, string(body))
 	}
-	respBody := resp.Body
+	counter := &countingReader{Reader: resp.Body}
+	var respBody io.Reader = counter
 	if !tc.cfg.disableCompression && EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-		respBody, err = gzip.NewReader(resp.Body)
+		respBody, err = gzip.NewReader(counter)
 		if err != nil {
-			return nil, err
+			resp.Body.Close()
+			return nil, false, err
 		}
 	}
-	data, err := marshalBody(respBody, bufferSize)
-	if err != nil {
-		return nil, err
+	release := func() {
+		tc.pool.Release(e)
+		common.RecordBytes(tc.cfg.metrics, common.DriverTaosRestful, common.BytesReceived, counter.n)
 	}
-	if data.Code != 0 {
-		return nil, taosErrors.NewError(data.Code, data.Desc)
+	return &responseBody{Reader: respBody, body: resp.Body, release: release}, false, nil
+}
+
+// countingReader counts the bytes read from the response body before they are decompressed
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+// responseBody closes the http response body and releases the host once
+type responseBody struct {
+	io.Reader
+	body    io.Closer
+	release func()
+	once    sync.Once
+}
+
+func (b *responseBody) Close() (err error) {
+	b.once.Do(func() {
+		err = b.body.Close()
+		b.release()
+	})
+	return err
+}
+
+var idempotentKeywords = []string{"select", "show", "describe", "desc"}
+
+// isIdempotent reports whether sql only reads and may be sent to another host after a failure
+func isIdempotent(sql string) bool {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	for _, keyword := range idempotentKeywords {
+		if len(sql) < len(keyword) || !EqualFold(sql[:len(keyword)], keyword) {
+			continue
+		}
+		if len(sql) == len(keyword) {
+			return true
+		}
+		switch sql[len(keyword)] {
+		case ' ', '\t', '\r', '\n', '*', '(':
+			return true
+		}
 	}
-	return data, nil
+	return false
 }
 
 func marshalBody(body io.Reader, bufferSize int) (*common.TDEngineRestfulResp, error) {
@@ -232,9 +454,32 @@ func marshalBody(body io.Reader, bufferSize int) (*common.TDEngineRestfulResp, e
 	iter := jsonI.BorrowIterator(make([]byte, bufferSize))
 	defer jsonI.ReturnIterator(iter)
 	iter.Reset(body)
-	timeFormat := time.RFC3339Nano
-	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
-		switch s {
+	for {
+		inData, err := readFields(iter, &result)
+		if err != nil {
+			return nil, err
+		}
+		if !inData {
+			return &result, nil
+		}
+		for iter.ReadArray() {
+			var row = make([]driver.Value, len(result.ColTypes))
+			if err = readRow(iter, result.ColTypes, row); err != nil {
+				return nil, err
+			}
+			result.Data = append(result.Data, row)
+		}
+		if iter.Error != nil && iter.Error != io.EOF {
+			return nil, iter.Error
+		}
+	}
+}
+
+// readFields reads the fields of the response object into result until the "data" field, inData reports that
+// iter is positioned at the start of the data array.
+func readFields(iter *jsoniter.Iterator, result *common.TDEngineRestfulResp) (inData bool, err error) {
+	for field := iter.ReadObject(); field != ""; field = iter.ReadObject() {
+		switch field {
 		case "code":
 			result.Code = iter.ReadInt()
 		case "desc":
@@ -265,84 +510,101 @@ func marshalBody(body io.Reader, bufferSize int) (*common.TDEngineRestfulResp, e
 				return true
 			})
 		case "data":
-			columnCount := len(result.ColTypes)
-			column := 0
-			iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-				column = 0
-				var row = make([]driver.Value, columnCount)
-				iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
-					defer func() {
-						column += 1
-					}()
-					columnType := result.ColTypes[column]
-					if columnType == common.TSDB_DATA_TYPE_JSON {
-						row[column] = iter.SkipAndReturnBytes()
-						return true
-					}
-					if iter.ReadNil() {
-						row[column] = nil
-						return true
-					}
-					var err error
-					switch columnType {
-					case common.TSDB_DATA_TYPE_NULL:
-						iter.Skip()
-						row[column] = nil
-					case common.TSDB_DATA_TYPE_BOOL:
-						row[column] = iter.ReadAny().ToBool()
-					case common.TSDB_DATA_TYPE_TINYINT:
-						row[column] = iter.ReadInt8()
-					case common.TSDB_DATA_TYPE_SMALLINT:
-						row[column] = iter.ReadInt16()
-					case common.TSDB_DATA_TYPE_INT:
-						row[column] = iter.ReadInt32()
-					case common.TSDB_DATA_TYPE_BIGINT:
-						row[column] = iter.ReadInt64()
-					case common.TSDB_DATA_TYPE_FLOAT:
-						row[column] = iter.ReadFloat32()
-					case common.TSDB_DATA_TYPE_DOUBLE:
-						row[column] = iter.ReadFloat64()
-					case common.TSDB_DATA_TYPE_BINARY:
-						row[column] = iter.ReadString()
-					case common.TSDB_DATA_TYPE_TIMESTAMP:
-						b := iter.ReadString()
-						row[column], err = time.Parse(timeFormat, b)
-						if err != nil {
-							iter.ReportError("parse time", err.Error())
-						}
-					case common.TSDB_DATA_TYPE_NCHAR:
-						row[column] = iter.ReadString()
-					case common.TSDB_DATA_TYPE_UTINYINT:
-						row[column] = iter.ReadUint8()
-					case common.TSDB_DATA_TYPE_USMALLINT:
-						row[column] = iter.ReadUint16()
-					case common.TSDB_DATA_TYPE_UINT:
-						row[column] = iter.ReadUint32()
-					case common.TSDB_DATA_TYPE_UBIGINT:
-						row[column] = iter.ReadUint64()
-					default:
-						row[column] = nil
-						iter.Skip()
-					}
-					return iter.Error == nil
-				})
-				if iter.Error != nil {
-					return false
-				}
-				result.Data = append(result.Data, row)
-				return true
-			})
+			return true, nil
 		case "rows":
 			result.Rows = iter.ReadInt()
 		default:
 			iter.Skip()
 		}
-		return iter.Error == nil
-	})
+		if iter.Error != nil {
+			break
+		}
+	}
 	if iter.Error != nil && iter.Error != io.EOF {
-		return nil, iter.Error
+		return false, iter.Error
 	}
-	return &result, nil
+	return false, nil
+}
+
+// readRow decodes one element of the data array into row
+func readRow(iter *jsoniter.Iterator, colTypes []int, row []driver.Value) error {
+	column := 0
+	for iter.ReadArray() {
+		if column >= len(colTypes) {
+			iter.ReportError("read row", "more values than columns")
+			break
+		}
+		row[column] = readValue(iter, colTypes[column])
+		if iter.Error != nil {
+			break
+		}
+		column += 1
+	}
+	return iter.Error
+}
+
+func readValue(iter *jsoniter.Iterator, columnType int) driver.Value {
+	if columnType == common.TSDB_DATA_TYPE_JSON {
+		return iter.SkipAndReturnBytes()
+	}
+	if iter.ReadNil() {
+		return nil
+	}
+	switch columnType {
+	case common.TSDB_DATA_TYPE_NULL:
+		iter.Skip()
+		return nil
+	case common.TSDB_DATA_TYPE_BOOL:
+		return iter.ReadAny().ToBool()
+	case common.TSDB_DATA_TYPE_TINYINT:
+		return iter.ReadInt8()
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		return iter.ReadInt16()
+	case common.TSDB_DATA_TYPE_INT:
+		return iter.ReadInt32()
+	case common.TSDB_DATA_TYPE_BIGINT:
+		return iter.ReadInt64()
+	case common.TSDB_DATA_TYPE_FLOAT:
+		return iter.ReadFloat32()
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		return iter.ReadFloat64()
+	case common.TSDB_DATA_TYPE_BINARY:
+		return iter.ReadString()
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		b := iter.ReadString()
+		t, err := time.Parse(time.RFC3339Nano, b)
+		if err != nil {
+			iter.ReportError("parse time", err.Error())
+			return nil
+		}
+		return t
+	case common.TSDB_DATA_TYPE_NCHAR:
+		return iter.ReadString()
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		return iter.ReadUint8()
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		return iter.ReadUint16()
+	case common.TSDB_DATA_TYPE_UINT:
+		return iter.ReadUint32()
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		return iter.ReadUint64()
+	case common.TSDB_DATA_TYPE_VARBINARY, common.TSDB_DATA_TYPE_GEOMETRY:
+		b, err := decodeBinaryValue(iter.ReadString())
+		if err != nil {
+			iter.ReportError("decode binary", err.Error())
+			return nil
+		}
+		return b
+	default:
+		iter.Skip()
+		return nil
+	}
+}
+
+// decodeBinaryValue decodes a VARBINARY or GEOMETRY value, taosAdapter sends them hex encoded
+// (optionally prefixed with \x).
+func decodeBinaryValue(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(s, "\\x"))
 }
 
 // EqualFold is strings.EqualFold, ASCII only. It reports whether s and t
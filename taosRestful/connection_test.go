@@ -0,0 +1,262 @@
+package taosRestful
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/metrics"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/taosRestful/restfultest"
+	"github.com/taosdata/driver-go/v3/types/geometry"
+)
+
+func TestMarshalBodyBinary(t *testing.T) {
+	body := `{"code":0,"column_meta":[["vb","VARBINARY",10],["geo","GEOMETRY",100]],` +
+		`"data":[["0102ff","010100000000000000000000000000000000000040"],["\\x0a0b",null],["0102",""],[null,null]],"rows":4}`
+	result, err := marshalBody(strings.NewReader(body), 64)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 4, len(result.Data))
+	assert.Equal(t, []byte{0x01, 0x02, 0xff}, result.Data[0][0])
+	assert.Equal(t, []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40}, result.Data[0][1])
+	assert.Equal(t, []byte{0x0a, 0x0b}, result.Data[1][0])
+	assert.Nil(t, result.Data[1][1])
+	assert.Equal(t, []byte{0x01, 0x02}, result.Data[2][0])
+	assert.Equal(t, []byte{}, result.Data[2][1])
+	assert.Nil(t, result.Data[3][0])
+	assert.Nil(t, result.Data[3][1])
+
+	for _, value := range []string{"not binary!", "AQI="} {
+		_, err = marshalBody(strings.NewReader(`{"code":0,"column_meta":[["vb","VARBINARY",10]],"data":[["`+value+`"]],"rows":1}`), 64)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestStreamRows(t *testing.T) {
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"column_meta":[["ts","TIMESTAMP",8],["v","INT",4]],"data":[`))
+		for i := 0; i < 10; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `["2023-10-01T00:00:00.00%dZ",%d]`, i, i)
+		}
+		w.(http.Flusher).Flush()
+		// the rest of the result never arrives, the client has to stop reading on its own
+		select {
+		case <-r.Context().Done():
+			close(closed)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/", strings.TrimPrefix(server.URL, "http://")))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	rows, err := db.Query("select ts, v from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 5; i++ {
+		if !assert.True(t, rows.Next()) {
+			return
+		}
+		var ts time.Time
+		var v int32
+		assert.NoError(t, rows.Scan(&ts, &v))
+		assert.Equal(t, int32(i), v)
+	}
+	assert.NoError(t, rows.Close())
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("response body not closed")
+	}
+}
+
+func TestStreamRowsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/sql":
+			w.Write([]byte(`{"code":9730,"desc":"Table does not exist"}`))
+		default:
+			w.Write([]byte(`{"code":0,"column_meta":[["v","INT",4]],"data":[[1],["x"]],"rows":2}`))
+		}
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = db.Query("select v from t")
+	assert.EqualError(t, err, "[0x2602] Table does not exist")
+
+	db2, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/db", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db2.Close()
+	rows, err := db2.Query("select v from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	assert.True(t, rows.Next())
+	assert.False(t, rows.Next())
+	assert.Error(t, rows.Err())
+}
+
+func TestReqIDFromContext(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	s.HandleQuery("^select \\* from missing", restfultest.ErrorResult(0x2662, "Table does not exist"))
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ctx := context.WithValue(context.Background(), common.ReqIDKey, int64(0x1234))
+	_, err = db.ExecContext(ctx, "create database if not exists test")
+	assert.NoError(t, err)
+	_, err = db.QueryContext(ctx, "select * from missing")
+	var taosErr *taosErrors.TaosError
+	if assert.ErrorAs(t, err, &taosErr) {
+		assert.Equal(t, int32(0x2662), taosErr.Code)
+		assert.Equal(t, int64(0x1234), taosErr.ReqID)
+	}
+	_, err = db.Exec("create database if not exists test")
+	assert.NoError(t, err)
+
+	requests := s.Requests()
+	if assert.Len(t, requests, 3) {
+		assert.Equal(t, "4660", requests[0].Query.Get("req_id"))
+		assert.Equal(t, "4660", requests[1].Query.Get("req_id"))
+		assert.NotEqual(t, "", requests[2].Query.Get("req_id"))
+		assert.NotEqual(t, "4660", requests[2].Query.Get("req_id"))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	p := metrics.NewPrometheus()
+	err := common.RegisterMetrics("taosRestful_test", p)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer common.DeregisterMetrics("taosRestful_test")
+	s := restfultest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/?metrics=taosRestful_test", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	for i := 0; i < 2; i++ {
+		_, err = db.Exec("create database if not exists test")
+		assert.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `taos_driver_requests_total{driver="taosRestful",action="sql",result="success"} 2`+"\n")
+	assert.Contains(t, body, `taos_driver_bytes_total{driver="taosRestful",direction="sent"} 68`+"\n")
+	assert.Contains(t, body, `taos_driver_bytes_total{driver="taosRestful",direction="received"}`)
+}
+
+func TestWriteBatch(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	var lock sync.Mutex
+	var inserts []string
+	s.HandleQueryFunc("^insert", func(sql string) *restfultest.Result {
+		lock.Lock()
+		inserts = append(inserts, sql)
+		lock.Unlock()
+		return &restfultest.Result{AffectedRows: 3}
+	})
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/?interpolateParams=false", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Exec("insert into t1 values(now, 1)")
+	assert.NoError(t, err)
+	_, err = tx.Exec("insert into t2 values(?, ?)", 1700000000000, "a")
+	assert.NoError(t, err)
+	lock.Lock()
+	assert.Empty(t, inserts)
+	lock.Unlock()
+	// any other statement runs after the buffered inserts
+	_, err = tx.Exec("create database if not exists test")
+	assert.NoError(t, err)
+	lock.Lock()
+	assert.Equal(t, []string{"insert into t1 values(now, 1)\nt2 values(1700000000000, 'a')"}, inserts)
+	inserts = nil
+	lock.Unlock()
+	_, err = tx.Exec("insert into t3 values(now, 3)")
+	assert.NoError(t, err)
+	if !assert.NoError(t, tx.Commit()) {
+		return
+	}
+	lock.Lock()
+	assert.Equal(t, []string{"insert into t3 values(now, 3)"}, inserts)
+	inserts = nil
+	lock.Unlock()
+
+	tx, err = db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Exec("insert into t1 values(now, 2)")
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+	_, err = db.Exec("insert into t1 values(now, 3)")
+	assert.NoError(t, err)
+	lock.Lock()
+	assert.Equal(t, []string{"insert into t1 values(now, 3)"}, inserts)
+	lock.Unlock()
+}
+
+func TestInterpolateGeometry(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	var lock sync.Mutex
+	var inserts []string
+	s.HandleQueryFunc("^insert", func(sql string) *restfultest.Result {
+		lock.Lock()
+		inserts = append(inserts, sql)
+		lock.Unlock()
+		return &restfultest.Result{AffectedRows: 1}
+	})
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = db.Exec("insert into t values(?, ?, ?)", 1700000000000, geometry.Point{X: 1, Y: 2}, geometry.NullGeometry{})
+	assert.NoError(t, err)
+	lock.Lock()
+	assert.Equal(t, []string{"insert into t values(1700000000000, 'POINT(1 2)', NULL)"}, inserts)
+	lock.Unlock()
+}
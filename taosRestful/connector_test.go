@@ -1,13 +1,19 @@
 package taosRestful
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/types"
 )
 
@@ -638,3 +644,36 @@ func TestAllTypeQueryNullWithoutJson(t *testing.T) {
 
 	}
 }
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"column_meta":[["v","INT",4]],"data":[[1]],"rows":1}`))
+	}))
+	defer server.Close()
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(server.Certificate())
+	err := common.RegisterTLSConfig("test_restful_tls", tlsConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer common.DeregisterTLSConfig("test_restful_tls")
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/?tls=test_restful_tls", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	var v int32
+	err = db.QueryRow("select v from t").Scan(&v)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	db2, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/?tls=true", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db2.Close()
+	err = db2.QueryRow("select v from t").Scan(&v)
+	assert.Error(t, err)
+}
@@ -266,7 +266,7 @@ func TestChinese(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	_, err = db.Exec(`INSERT INTO test_chinese_rest.chinese (ts, v) VALUES (?, ?)`, "1641010332000", "'阴天'")
+	_, err = db.Exec(`INSERT INTO test_chinese_rest.chinese (ts, v) VALUES (?, ?)`, int64(1641010332000), "阴天")
 	if err != nil {
 		t.Error(err)
 		return
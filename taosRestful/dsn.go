@@ -1,11 +1,17 @@
 package taosRestful
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/internal/failover"
 )
 
 var (
@@ -13,6 +19,13 @@ var (
 	errInvalidDSNAddr      = &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: network address not terminated (missing closing brace)"}
 	errInvalidDSNPort      = &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: network port is not a valid number"}
 	errInvalidDSNNoSlash   = &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: missing the slash separating the database name"}
+	errInvalidLoadBalance  = &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: loadBalance must be roundRobin or leastLoaded"}
+)
+
+// Load balancing policies of the loadBalance DSN parameter
+const (
+	LoadBalanceRoundRobin  = failover.LoadBalanceRoundRobin
+	LoadBalanceLeastLoaded = failover.LoadBalanceLeastLoaded
 )
 
 // Config is a configuration parsed from a DSN string.
@@ -24,12 +37,20 @@ type config struct {
 	net                string // Network type
 	addr               string // Network address (requires Net)
 	port               int
+	addrs              []string          // host:port of every host when more than one address is given
 	dbName             string            // Database name
 	params             map[string]string // Connection parameters
 	interpolateParams  bool              // Interpolate placeholders into query string
+	timePrecision      string            // precision of the time.Time values interpolated into queries, RFC 3339 strings when empty
 	disableCompression bool
 	readBufferSize     int
-	token              string // cloud platform token
+	token              string         // cloud platform token
+	loadBalance        string         // how a request chooses among addrs
+	endpointBackoff    time.Duration  // initial time a failing host is skipped
+	tlsConfig          *tls.Config    // TLS configuration, nil unless the tls param is set
+	metrics            common.Metrics // metrics of this connector, the global one when nil
+	logger             common.Logger  // logger of this connector, the global one when nil
+	slowQueryThreshold time.Duration  // queries slower than this are logged, 0 disables it
 }
 
 // NewConfig creates a new Config and sets default values.
@@ -46,7 +67,7 @@ func parseDSN(dsn string) (cfg *config, err error) {
 	// New config with some default values
 	cfg = newConfig()
 
-	// [user[:password]@][net[(addr)]]/dbname[?param1=value1&paramN=valueN]
+	// [user[:password]@][net[(addr1[,addr2...])]]/dbname[?param1=value1&paramN=valueN]
 	// Find the last '/' (since the password or the net addr might contain a '/')
 	foundSlash := false
 	for i := len(dsn) - 1; i >= 0; i-- {
@@ -85,6 +106,13 @@ func parseDSN(dsn string) (cfg *config, err error) {
 							}
 							//return nil, errInvalidDSNAddr
 						}
+						addrList := strings.Split(dsn[k+1:i-1], ",")
+						if len(addrList) > 1 {
+							if err = parseDSNAddrs(cfg, addrList); err != nil {
+								return nil, err
+							}
+							break
+						}
 						strList := strings.Split(dsn[k+1:i-1], ":")
 						if len(strList) == 1 {
 							return nil, errInvalidDSNAddr
@@ -125,6 +153,45 @@ func parseDSN(dsn string) (cfg *config, err error) {
 	return
 }
 
+// parseDSNAddrs parses the comma separated host list of a multi-host DSN, a missing host or
+// port falls back to the default, the first host is also kept in addr and port.
+func parseDSNAddrs(cfg *config, addrList []string) error {
+	cfg.addrs = make([]string, 0, len(addrList))
+	for _, address := range addrList {
+		host, portStr, err := net.SplitHostPort(strings.TrimSpace(address))
+		if err != nil {
+			return errInvalidDSNAddr
+		}
+		if len(host) == 0 {
+			host = "127.0.0.1"
+		}
+		port := 0
+		if len(portStr) != 0 {
+			port, err = strconv.Atoi(portStr)
+			if err != nil {
+				return errInvalidDSNPort
+			}
+		}
+		if port == 0 {
+			port = common.DefaultHttpPort
+		}
+		if len(cfg.addrs) == 0 {
+			cfg.addr = host
+			cfg.port = port
+		}
+		cfg.addrs = append(cfg.addrs, fmt.Sprintf("%s:%d", host, port))
+	}
+	return nil
+}
+
+// hostAddrs returns host:port of every host requests are sent to
+func (cfg *config) hostAddrs() []string {
+	if len(cfg.addrs) == 0 {
+		return []string{fmt.Sprintf("%s:%d", cfg.addr, cfg.port)}
+	}
+	return cfg.addrs
+}
+
 // parseDSNParams parses the DSN "query string"
 // Values must be url.QueryEscape'ed
 func parseDSNParams(cfg *config, params string) (err error) {
@@ -154,6 +221,41 @@ func parseDSNParams(cfg *config, params string) (err error) {
 			}
 		case "token":
 			cfg.token = value
+		case "tls":
+			cfg.tlsConfig, err = common.GetTLSConfig(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+		case "metrics":
+			cfg.metrics, err = common.GetRegisteredMetrics(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+		case "logger":
+			cfg.logger, err = common.GetRegisteredLogger(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+		case "slowQueryThreshold":
+			cfg.slowQueryThreshold, err = time.ParseDuration(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid duration value: " + value}
+			}
+		case "timePrecision":
+			if _, err = common.ParsePrecision(value); err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+			cfg.timePrecision = value
+		case "loadBalance":
+			if value != LoadBalanceRoundRobin && value != LoadBalanceLeastLoaded {
+				return errInvalidLoadBalance
+			}
+			cfg.loadBalance = value
+		case "endpointBackoff":
+			cfg.endpointBackoff, err = time.ParseDuration(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid duration value: " + value}
+			}
 		default:
 			// lazy init
 			if cfg.params == nil {
@@ -19,6 +19,7 @@ func TestParseDsn(t *testing.T) {
 		port   int
 		dbName string
 		token  string
+		addrs  []string
 	}{{},
 		{dsn: "abcd", errs: "invalid DSN: missing the slash separating the database name"},
 		{dsn: "user:passwd@http(fqdn:6041)/dbname", user: "user", passwd: "passwd", net: "http", addr: "fqdn", port: 6041, dbName: "dbname"},
@@ -28,6 +29,10 @@ func TestParseDsn(t *testing.T) {
 		{dsn: "user:passwd@https(:0)/", user: "user", passwd: "passwd", net: "https"},
 		{dsn: "user:passwd@https(:0)/?interpolateParams=false&test=1", user: "user", passwd: "passwd", net: "https"},
 		{dsn: "user:passwd@https(:0)/?interpolateParams=false&token=token", user: "user", passwd: "passwd", net: "https", token: "token"},
+		{dsn: "user:passwd@http(host1:6041,host2:6042)/dbname", user: "user", passwd: "passwd", net: "http", addr: "host1", port: 6041, addrs: []string{"host1:6041", "host2:6042"}, dbName: "dbname"},
+		{dsn: "user:passwd@http(host1:,:6042)/?loadBalance=leastLoaded", user: "user", passwd: "passwd", net: "http", addr: "host1", port: 6041, addrs: []string{"host1:6041", "127.0.0.1:6042"}},
+		{dsn: "user:passwd@http(host1:6041,host2)/", errs: "invalid DSN: network address not terminated (missing closing brace)"},
+		{dsn: "user:passwd@http(host1:6041,host2:6041)/?loadBalance=random", errs: "invalid DSN: loadBalance must be roundRobin or leastLoaded"},
 	}
 	for i, tc := range tcs {
 		name := fmt.Sprintf("%d - %s", i, tc.dsn)
@@ -45,7 +50,8 @@ func TestParseDsn(t *testing.T) {
 				cfg.passwd != tc.passwd ||
 				cfg.net != tc.net ||
 				cfg.addr != tc.addr ||
-				cfg.port != tc.port {
+				cfg.port != tc.port ||
+				fmt.Sprint(cfg.addrs) != fmt.Sprint(tc.addrs) {
 				t.Fatal(cfg)
 			}
 		})
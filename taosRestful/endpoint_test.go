@@ -0,0 +1,89 @@
+package taosRestful
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/internal/failover"
+)
+
+func TestIsIdempotent(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"select * from t":                     true,
+		"  SELECT 1":                          true,
+		"(select ts from t)":                  true,
+		"show databases":                      true,
+		"describe t":                          true,
+		"DESC t":                              true,
+		"insert into t values(1,1)":           false,
+		"create table t(ts timestamp, v int)": false,
+		"selected":                            false,
+		"":                                    false,
+	} {
+		assert.Equal(t, want, isIdempotent(sql), sql)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	var failed, served int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&failed, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		w.Write([]byte(`{"code":0,"column_meta":[["v","INT",4]],"data":[[1]],"rows":1}`))
+	}))
+	defer up.Close()
+	downAddr := strings.TrimPrefix(down.URL, "http://")
+	upAddr := strings.TrimPrefix(up.URL, "http://")
+
+	cfg, err := parseDSN("root:taosdata@http(" + downAddr + "," + upAddr + ")/?endpointBackoff=1m")
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := &connector{cfg: cfg}
+	conn, err := c.Connect(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	tc := conn.(*taosConn)
+
+	// the first request hits the failing host and is retried on the other one
+	data, err := taosQuery(tc, "select v from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int32(1), data.Data[0][0])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failed))
+	// the failing host is skipped during its backoff
+	for i := 0; i < 3; i++ {
+		_, err = taosQuery(tc, "show databases")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failed))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&served))
+
+	// writes are never retried on another host
+	pool := failover.NewPool([]string{downAddr, upAddr}, failover.LoadBalanceRoundRobin, time.Minute)
+	tc.pool = pool
+	_, err = taosQuery(tc, "insert into t values(now, 1)")
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failed))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&served))
+	assert.Equal(t, 1, pool.Failures(pool.Endpoints()[0]))
+}
+
+func taosQuery(tc *taosConn, sql string) (*common.TDEngineRestfulResp, error) {
+	ctx, span := startSpan(context.Background(), common.OpExec, sql, 0)
+	return tc.taosQuery(ctx, span, sql, common.GetReqID(), 512)
+}
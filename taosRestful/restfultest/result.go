@@ -0,0 +1,148 @@
+package restfultest
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// Error codes returned by the server.
+const (
+	CodeInvalidPara = 0x0118
+	CodeSyntaxError = 0x2600
+)
+
+// Field is a column of a result.
+type Field struct {
+	Name   string
+	Type   uint8 // common.TSDB_DATA_TYPE_*
+	Length int64 // bytes of the column, as reported in column_meta
+}
+
+// Result is the response to a statement. A Result with Fields is a query result, otherwise AffectedRows are
+// reported the way taosAdapter does, as a single affected_rows column. A non-zero Code is returned as an error.
+//
+// Row values are encoded according to the type of their column: time.Time (or int64 in Precision) for TIMESTAMP,
+// []byte for VARBINARY and GEOMETRY (sent hex encoded), []byte or string holding raw JSON for JSON columns,
+// and the corresponding Go types for the others.
+type Result struct {
+	Fields       []Field
+	Rows         [][]driver.Value
+	AffectedRows int
+	Precision    int // common.PrecisionMilliSecond, common.PrecisionMicroSecond or common.PrecisionNanoSecond
+	Code         int
+	Desc         string
+}
+
+// ErrorResult returns a result failing with code and desc.
+func ErrorResult(code int, desc string) *Result {
+	return &Result{Code: code, Desc: desc}
+}
+
+func errorResult(code int, format string, args ...interface{}) *Result {
+	return ErrorResult(code, fmt.Sprintf(format, args...))
+}
+
+// encode returns the JSON response in chunks: the head up to the data array, one chunk per row and the tail
+func (r *Result) encode() ([][]byte, error) {
+	if r.Code != 0 {
+		return [][]byte{r.mustEncode()}, nil
+	}
+	fields, rows := r.Fields, r.Rows
+	if len(fields) == 0 {
+		fields = []Field{{Name: "affected_rows", Type: common.TSDB_DATA_TYPE_INT, Length: 4}}
+		rows = [][]driver.Value{{int32(r.AffectedRows)}}
+	}
+	meta := make([][]interface{}, len(fields))
+	for i, field := range fields {
+		name, exist := common.TypeNameMap[int(field.Type)]
+		if !exist {
+			return nil, fmt.Errorf("unsupported type %d of column %s", field.Type, field.Name)
+		}
+		meta[i] = []interface{}{field.Name, name, field.Length}
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, len(rows)+2)
+	chunks = append(chunks, []byte(`{"code":0,"column_meta":`+string(metaJSON)+`,"data":[`))
+	for i, row := range rows {
+		if len(row) != len(fields) {
+			return nil, fmt.Errorf("row %d has %d values, want %d", i, len(row), len(fields))
+		}
+		var b bytes.Buffer
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('[')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			value, err := encodeValue(fields[j].Type, v, r.Precision)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", fields[j].Name, err)
+			}
+			b.Write(value)
+		}
+		b.WriteByte(']')
+		chunks = append(chunks, b.Bytes())
+	}
+	chunks = append(chunks, []byte(fmt.Sprintf(`],"rows":%d}`, len(rows))))
+	return chunks, nil
+}
+
+// mustEncode encodes an error result
+func (r *Result) mustEncode() []byte {
+	b, _ := json.Marshal(map[string]interface{}{"code": r.Code, "desc": r.Desc})
+	return b
+}
+
+// encodeValue encodes v the way taosAdapter sends a value of type t
+func encodeValue(t uint8, v driver.Value, precision int) ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	switch t {
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		switch ts := v.(type) {
+		case time.Time:
+			v = ts.Format(time.RFC3339Nano)
+		case int64:
+			v = common.TimestampConvertToTime(ts, precision).Format(time.RFC3339Nano)
+		case string:
+		default:
+			return nil, fmt.Errorf("invalid timestamp %T", v)
+		}
+	case common.TSDB_DATA_TYPE_VARBINARY, common.TSDB_DATA_TYPE_GEOMETRY:
+		switch b := v.(type) {
+		case []byte:
+			v = hex.EncodeToString(b)
+		case string:
+			v = hex.EncodeToString([]byte(b))
+		default:
+			return nil, fmt.Errorf("invalid binary %T", v)
+		}
+	case common.TSDB_DATA_TYPE_JSON:
+		var raw []byte
+		switch b := v.(type) {
+		case []byte:
+			raw = b
+		case string:
+			raw = []byte(b)
+		default:
+			return nil, fmt.Errorf("invalid json %T", v)
+		}
+		if !json.Valid(raw) {
+			return nil, fmt.Errorf("invalid json %q", raw)
+		}
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,304 @@
+// Package restfultest provides an in-process fake of the taosAdapter /rest/sql endpoint for hermetic tests of
+// taosRestful.
+//
+// Responses have the JSON shape taosAdapter sends (code, desc, column_meta, data and rows) and are gzip compressed
+// when the client asks for it. Results are registered per SQL pattern with HandleQuery, statements without a handler
+// succeed with no affected rows. 5xx responses, slow bodies and malformed JSON are injected with InjectFault.
+package restfultest
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultUser     = "root"
+	DefaultPassword = "taosdata"
+)
+
+// Request is a statement received by the server.
+type Request struct {
+	DB     string     // the database in the path of /rest/sql/<db>, empty for /rest/sql
+	SQL    string     // the request body
+	Query  url.Values // the url query, e.g. token
+	Header http.Header
+}
+
+// Fault replaces or disturbs the response to a statement.
+type Fault struct {
+	Delay      time.Duration // wait before responding
+	StatusCode int           // respond with this http status and Body instead of handling the statement
+	Body       string        // body sent with StatusCode
+	RowDelay   time.Duration // flush the response and wait before each row of data
+	Malformed  bool          // cut the JSON response in half
+}
+
+type fault struct {
+	pattern *regexp.Regexp
+	Fault
+}
+
+type queryHandler struct {
+	pattern *regexp.Regexp
+	handle  func(sql string) *Result
+}
+
+// Server is a fake taosAdapter serving /rest/sql.
+type Server struct {
+	server *httptest.Server
+
+	lock        sync.Mutex
+	user        string
+	password    string
+	token       string
+	compression bool
+	handlers    []*queryHandler
+	faults      []*fault
+	requests    []Request
+}
+
+// NewServer starts a fake taosAdapter accepting the default user root with password taosdata.
+func NewServer() *Server {
+	s := newServer()
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts a fake taosAdapter serving https, see httptest.NewTLSServer.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer() *Server {
+	return &Server{
+		user:        DefaultUser,
+		password:    DefaultPassword,
+		compression: true,
+	}
+}
+
+// URL returns the base url of the server, e.g. http://127.0.0.1:12345
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Addr returns host:port of the server, for use in a taosRestful DSN.
+func (s *Server) Addr() string {
+	return s.server.Listener.Addr().String()
+}
+
+// HTTPServer returns the underlying httptest.Server, e.g. to get the certificate of a TLS server.
+func (s *Server) HTTPServer() *httptest.Server {
+	return s.server
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetAuth changes the accepted user and password.
+func (s *Server) SetAuth(user, password string) {
+	s.lock.Lock()
+	s.user, s.password = user, password
+	s.lock.Unlock()
+}
+
+// SetToken makes the server accept the cloud token passed as ?token= in addition to basic authentication.
+func (s *Server) SetToken(token string) {
+	s.lock.Lock()
+	s.token = token
+	s.lock.Unlock()
+}
+
+// SetCompression controls whether responses are gzip compressed for clients sending Accept-Encoding: gzip,
+// enabled by default.
+func (s *Server) SetCompression(enabled bool) {
+	s.lock.Lock()
+	s.compression = enabled
+	s.lock.Unlock()
+}
+
+// HandleQuery makes every statement matching pattern (a case-insensitive regular expression) return result.
+// Handlers are tried in the order they are registered.
+func (s *Server) HandleQuery(pattern string, result *Result) {
+	s.HandleQueryFunc(pattern, func(string) *Result {
+		return result
+	})
+}
+
+// HandleQueryFunc is like HandleQuery but the result is computed by f for each matching statement.
+func (s *Server) HandleQueryFunc(pattern string, f func(sql string) *Result) {
+	re := regexp.MustCompile("(?is)" + pattern)
+	s.lock.Lock()
+	s.handlers = append(s.handlers, &queryHandler{pattern: re, handle: f})
+	s.lock.Unlock()
+}
+
+// InjectFault applies f to the next statement matching pattern (a case-insensitive regular expression),
+// an empty pattern matches any. Faults are consumed in the order they are injected.
+func (s *Server) InjectFault(pattern string, f Fault) {
+	re := regexp.MustCompile("(?is)" + pattern)
+	s.lock.Lock()
+	s.faults = append(s.faults, &fault{pattern: re, Fault: f})
+	s.lock.Unlock()
+}
+
+// Requests returns the statements received so far.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var db string
+	switch {
+	case r.URL.Path == "/rest/sql":
+	case strings.HasPrefix(r.URL.Path, "/rest/sql/"):
+		db = strings.TrimPrefix(r.URL.Path, "/rest/sql/")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sql := strings.TrimSpace(string(body))
+	s.lock.Lock()
+	s.requests = append(s.requests, Request{DB: db, SQL: sql, Query: r.URL.Query(), Header: r.Header.Clone()})
+	authorized := s.authorized(r)
+	compress := s.compression && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	f := s.takeFault(sql)
+	s.lock.Unlock()
+
+	if f != nil && f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if f != nil && f.StatusCode != 0 {
+		w.WriteHeader(f.StatusCode)
+		io.WriteString(w, f.Body)
+		return
+	}
+	if !authorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"code":65535,"desc":"Authentication failure"}`)
+		return
+	}
+	if sql == "" {
+		writeResponse(w, r, compress, nil, errorResult(CodeInvalidPara, "no sql command"))
+		return
+	}
+	writeResponse(w, r, compress, f, s.query(sql))
+}
+
+// authorized checks the basic authentication or token of r, must be called with the server locked
+func (s *Server) authorized(r *http.Request) bool {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return s.token != "" && token == s.token
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Basic ") {
+		return false
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return false
+	}
+	return string(b) == s.user+":"+s.password
+}
+
+// takeFault removes and returns the first fault matching sql, must be called with the server locked
+func (s *Server) takeFault(sql string) *fault {
+	for i, f := range s.faults {
+		if f.pattern.MatchString(sql) {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			return f
+		}
+	}
+	return nil
+}
+
+// query returns the result of the first handler matching sql, a statement without a handler succeeds
+func (s *Server) query(sql string) *Result {
+	s.lock.Lock()
+	handlers := s.handlers
+	s.lock.Unlock()
+	for _, h := range handlers {
+		if h.pattern.MatchString(sql) {
+			if result := h.handle(sql); result != nil {
+				return result
+			}
+		}
+	}
+	return &Result{}
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, compress bool, f *fault, result *Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	var out io.Writer = w
+	var flush func()
+	if compress {
+		w.Header().Set("Content-Encoding", "gzip")
+		gw := gzip.NewWriter(w)
+		defer gw.Close()
+		out = gw
+		flush = func() {
+			gw.Flush()
+			w.(http.Flusher).Flush()
+		}
+	} else {
+		flush = w.(http.Flusher).Flush
+	}
+	w.WriteHeader(http.StatusOK)
+	var rowDelay time.Duration
+	if f != nil {
+		rowDelay = f.RowDelay
+	}
+	chunks, err := result.encode()
+	if err != nil {
+		chunks = [][]byte{errorResult(CodeInvalidPara, "%s", err).mustEncode()}
+	}
+	if f != nil && f.Malformed {
+		var body []byte
+		for _, c := range chunks {
+			body = append(body, c...)
+		}
+		chunks = [][]byte{body[:len(body)/2]}
+	}
+	for i, c := range chunks {
+		// chunks after the first start with a row
+		if i > 0 && i < len(chunks)-1 && rowDelay > 0 {
+			flush()
+			select {
+			case <-time.After(rowDelay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if _, err := out.Write(c); err != nil {
+			return
+		}
+	}
+}
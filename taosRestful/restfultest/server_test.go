@@ -0,0 +1,159 @@
+package restfultest_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	_ "github.com/taosdata/driver-go/v3/taosRestful"
+	"github.com/taosdata/driver-go/v3/taosRestful/restfultest"
+)
+
+func openDB(t *testing.T, s *restfultest.Server, params string) *sql.DB {
+	db, err := sql.Open("taosRestful", fmt.Sprintf("root:taosdata@http(%s)/test?%s", s.Addr(), params))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var meters = &restfultest.Result{
+	Fields: []restfultest.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		{Name: "current", Type: common.TSDB_DATA_TYPE_FLOAT, Length: 4},
+		{Name: "location", Type: common.TSDB_DATA_TYPE_BINARY, Length: 20},
+		{Name: "raw", Type: common.TSDB_DATA_TYPE_VARBINARY, Length: 20},
+		{Name: "info", Type: common.TSDB_DATA_TYPE_JSON, Length: 4095},
+	},
+	Rows: [][]driver.Value{
+		{int64(1700000000000), float32(1.5), "beijing", []byte{0x01, 0x02}, `{"a":1}`},
+		{int64(1700000000001), nil, nil, nil, nil},
+	},
+}
+
+func TestQuery(t *testing.T) {
+	for _, params := range []string{"disableCompression=true", "disableCompression=false"} {
+		t.Run(params, func(t *testing.T) {
+			s := restfultest.NewServer()
+			defer s.Close()
+			s.HandleQuery(`^select \* from meters`, meters)
+			s.HandleQuery(`^insert`, &restfultest.Result{AffectedRows: 2})
+			db := openDB(t, s, params)
+
+			result, err := db.Exec("insert into meters values(now, 1.5, 'beijing', '\\x0102', '{}')(now+1s, null, null, null, null)")
+			require.NoError(t, err)
+			affected, err := result.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), affected)
+
+			rows, err := db.Query("select * from meters")
+			require.NoError(t, err)
+			defer rows.Close()
+			columns, err := rows.Columns()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"ts", "current", "location", "raw", "info"}, columns)
+			var got [][]interface{}
+			for rows.Next() {
+				var (
+					ts       time.Time
+					current  sql.NullFloat64
+					location sql.NullString
+					raw      []byte
+					info     []byte
+				)
+				require.NoError(t, rows.Scan(&ts, &current, &location, &raw, &info))
+				got = append(got, []interface{}{ts.UnixNano() / 1e6, current, location, raw, string(info)})
+			}
+			require.NoError(t, rows.Err())
+			assert.Equal(t, [][]interface{}{
+				{int64(1700000000000), sql.NullFloat64{Float64: 1.5, Valid: true}, sql.NullString{String: "beijing", Valid: true}, []byte{0x01, 0x02}, `{"a":1}`},
+				{int64(1700000000001), sql.NullFloat64{}, sql.NullString{}, []byte(nil), "null"},
+			}, got)
+
+			requests := s.Requests()
+			require.Len(t, requests, 2)
+			assert.Equal(t, "test", requests[1].DB)
+			assert.Equal(t, "select * from meters", requests[1].SQL)
+			assert.Equal(t, params == "disableCompression=false", strings.Contains(requests[1].Header.Get("Accept-Encoding"), "gzip"))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	s.HandleQuery(`^select .* from missing`, restfultest.ErrorResult(0x2662, "Table does not exist"))
+	db := openDB(t, s, "")
+
+	// statements without a handler succeed
+	_, err := db.Exec("create database if not exists test")
+	require.NoError(t, err)
+
+	_, err = db.Query("select * from missing")
+	var taosErr *taosErrors.TaosError
+	require.ErrorAs(t, err, &taosErr)
+	assert.Equal(t, int32(0x2662), taosErr.Code)
+	assert.Equal(t, "Table does not exist", taosErr.ErrStr)
+
+	s.SetAuth("root", "other")
+	_, err = db.Exec("create database if not exists test")
+	assert.Error(t, err)
+}
+
+func TestFault(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	s.HandleQuery(`^select`, meters)
+	db := openDB(t, s, "disableCompression=false")
+
+	s.InjectFault("", restfultest.Fault{StatusCode: 503, Body: "unavailable"})
+	_, err := db.Exec("create database test")
+	assert.EqualError(t, err, "server response: 503 Service Unavailable - unavailable")
+
+	s.InjectFault("^select", restfultest.Fault{Malformed: true})
+	rows, err := db.Query("select * from meters")
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+	}
+	assert.Error(t, err)
+
+	// the column meta arrives at once, the query is canceled while waiting for a slow row
+	s.InjectFault("^select", restfultest.Fault{RowDelay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	rows, err = db.QueryContext(ctx, "select * from meters")
+	require.NoError(t, err)
+	assert.False(t, rows.Next())
+	assert.Error(t, rows.Err())
+	rows.Close()
+	assert.True(t, time.Since(start) < time.Second)
+
+	s.InjectFault("", restfultest.Fault{Delay: time.Second})
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = db.ExecContext(ctx, "create database test")
+	assert.Error(t, err)
+}
+
+func TestToken(t *testing.T) {
+	s := restfultest.NewServer()
+	defer s.Close()
+	s.SetToken("secret")
+	db, err := sql.Open("taosRestful", fmt.Sprintf("http(%s)/?token=secret", s.Addr()))
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec("create database test")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", s.Requests()[0].Query.Get("token"))
+}
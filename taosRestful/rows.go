@@ -5,12 +5,22 @@ import (
 	"io"
 	"reflect"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
 )
 
 type rows struct {
 	result   *common.TDEngineRestfulResp
 	rowIndex int
+	// body and iter are set when rows are decoded from the response body as Next is called
+	body io.ReadCloser
+	iter *jsoniter.Iterator
+	done bool
+	span common.Span // the span of the query, ended when the body is done
+	err  error       // the error that ended Next early
+	// reqID and logger report the errors decoding the body
+	reqID  int64
+	logger common.Logger
 }
 
 func (rs *rows) Columns() []string {
@@ -33,15 +43,70 @@ func (rs *rows) ColumnTypeScanType(i int) reflect.Type {
 	return t
 }
 
+// Close closes the response body, a result that has not been read to the end is discarded.
 func (rs *rows) Close() error {
+	rs.finish()
 	return nil
 }
 
 func (rs *rows) Next(dest []driver.Value) error {
-	if rs.rowIndex >= len(rs.result.Data) {
+	if rs.iter == nil {
+		if rs.rowIndex >= len(rs.result.Data) {
+			return io.EOF
+		}
+		copy(dest, rs.result.Data[rs.rowIndex])
+		rs.rowIndex += 1
+		return nil
+	}
+	if !rs.iter.ReadArray() {
+		err := rs.iter.Error
+		if err != nil && err != io.EOF {
+			common.Log(rs.logger, common.LogLevelError, "decode rows failed", common.LogKeyReqID, rs.reqID, common.LogKeyError, err)
+			rs.err = err
+			rs.finish()
+			return err
+		}
+		rs.finish()
 		return io.EOF
 	}
-	copy(dest, rs.result.Data[rs.rowIndex])
+	for i := range dest {
+		dest[i] = nil
+	}
+	if err := readRow(rs.iter, rs.result.ColTypes, dest); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		common.Log(rs.logger, common.LogLevelError, "decode rows failed", common.LogKeyReqID, rs.reqID, common.LogKeyError, err)
+		rs.err = err
+		rs.finish()
+		return err
+	}
 	rs.rowIndex += 1
 	return nil
 }
+
+func (rs *rows) finish() {
+	if rs.done {
+		return
+	}
+	rs.done = true
+	rs.endSpan()
+	if rs.iter != nil {
+		jsonI.ReturnIterator(rs.iter)
+		rs.iter = nil
+	}
+	if rs.body != nil {
+		rs.body.Close()
+		rs.body = nil
+	}
+}
+
+// endSpan ends the span of the query once the rows have been read or closed
+func (rs *rows) endSpan() {
+	if rs.span == nil {
+		return
+	}
+	rs.span.SetAttributes(common.Attribute{Key: common.AttrRows, Value: rs.rowIndex})
+	common.EndSpan(rs.span, rs.err)
+	rs.span = nil
+}
@@ -3,6 +3,8 @@ package taosSql
 import (
 	"context"
 	"database/sql/driver"
+	"fmt"
+	"time"
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
@@ -14,10 +16,11 @@ import (
 type taosConn struct {
 	taos unsafe.Pointer
 	cfg  *config
+	tx   *common.BatchTx // the current transaction
 }
 
 func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &errors.TaosError{Code: 0xffff, ErrStr: "taosSql does not support transaction"}
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
 }
 
 func (tc *taosConn) Close() (err error) {
@@ -25,6 +28,7 @@ func (tc *taosConn) Close() (err error) {
 		locker.Lock()
 		wrapper.TaosClose(tc.taos)
 		locker.Unlock()
+		tc.log(common.LogLevelInfo, "connection closed", common.LogKeyEndpoint, tc.addr())
 	}
 	tc.taos = nil
 	return nil
@@ -34,9 +38,13 @@ func (tc *taosConn) Prepare(query string) (driver.Stmt, error) {
 	if tc.taos == nil {
 		return nil, errors.ErrTscInvalidConnection
 	}
+	rewritten, params, err := common.ParseParams(query)
+	if err != nil {
+		return nil, err
+	}
 	locker.Lock()
 	stmtP := wrapper.TaosStmtInit(tc.taos)
-	code := wrapper.TaosStmtPrepare(stmtP, query)
+	code := wrapper.TaosStmtPrepare(stmtP, rewritten)
 	locker.Unlock()
 	if code != 0 {
 		errStr := wrapper.TaosStmtErrStr(stmtP)
@@ -62,10 +70,24 @@ func (tc *taosConn) Prepare(query string) (driver.Stmt, error) {
 		pSql:     query,
 		stmt:     stmtP,
 		isInsert: isInsert,
+		params:   params,
 	}
 	return stmt, nil
 }
 
+// CheckNamedValue keeps geometry arguments for common.WriteValue, see common.CheckNamedValue.
+func (tc *taosConn) CheckNamedValue(v *driver.NamedValue) error {
+	return common.CheckNamedValue(v)
+}
+
+// flush executes the statements buffered by the transaction before a statement that is not buffered runs
+func (tc *taosConn) flush(ctx context.Context) error {
+	if tc.tx == nil {
+		return nil
+	}
+	return tc.tx.Flush(ctx)
+}
+
 func (tc *taosConn) Exec(query string, args []driver.Value) (driver.Result, error) {
 	return tc.ExecContext(context.Background(), query, common.ValueArgsToNamedValueArgs(args))
 }
@@ -74,35 +96,85 @@ func (tc *taosConn) ExecContext(ctx context.Context, query string, args []driver
 	if tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
+	if tc.tx != nil {
+		buffered, err := tc.tx.Add(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		if buffered {
+			// the rows are inserted when the transaction commits, their number is not known yet
+			return driver.RowsAffected(0), nil
+		}
+	}
 
 	return tc.execCtx(ctx, query, args)
 }
 
 func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	var reqIDValue int64
-	reqID := ctx.Value(common.ReqIDKey)
-	if reqID != nil {
-		reqIDValue, _ = reqID.(int64)
-	}
+	reqID := common.GetReqIDFromContext(ctx)
 
 	if len(args) != 0 {
 		if !tc.cfg.interpolateParams {
 			return nil, driver.ErrSkip
 		}
 		// try to interpolate the parameters to save extra round trips for preparing and closing a statement
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
+	_, span := tc.startSpan(ctx, common.OpExec, query, reqID)
+	start := time.Now()
 	h := asyncHandlerPool.Get()
 	defer asyncHandlerPool.Put(h)
-	result := tc.taosQuery(query, h, reqIDValue)
-	return tc.processExecResult(result)
+	result := tc.taosQuery(query, h, reqID)
+	res, err := tc.processExecResult(result, reqID)
+	common.RecordRequest(tc.cfg.metrics, common.DriverTaosSql, common.ActionQuery, start, err)
+	tc.logSlowQuery(start, query, reqID)
+	if err == nil {
+		affected, _ := res.RowsAffected()
+		span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: int(affected)})
+	}
+	common.EndSpan(span, err)
+	return res, err
+}
+
+// startSpan starts tracing an operation on this connection, see common.Tracer
+func (tc *taosConn) startSpan(ctx context.Context, operation string, sql string, reqID int64) (context.Context, common.Span) {
+	return common.StartSpan(ctx, operation,
+		common.Attribute{Key: common.AttrSQL, Value: sql},
+		common.Attribute{Key: common.AttrReqID, Value: reqID},
+		common.Attribute{Key: common.AttrEndpoint, Value: tc.addr()},
+	)
 }
 
-func (tc *taosConn) processExecResult(result *handler.AsyncResult) (driver.Result, error) {
+// addr returns host:port of the connected server
+func (tc *taosConn) addr() string {
+	return fmt.Sprintf("%s:%d", tc.cfg.addr, tc.cfg.port)
+}
+
+// log reports connects, closes and slow queries of the native connection to the logger of its DSN
+func (tc *taosConn) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(tc.cfg.logger, level, msg, keyvals...)
+}
+
+// logSlowQuery logs query when it took longer than the slowQueryThreshold of the DSN since start
+func (tc *taosConn) logSlowQuery(start time.Time, query string, reqID int64) {
+	if tc.cfg.slowQueryThreshold <= 0 {
+		return
+	}
+	if duration := time.Since(start); duration >= tc.cfg.slowQueryThreshold {
+		tc.log(common.LogLevelWarn, "slow query",
+			common.LogKeySQL, query,
+			common.LogKeyReqID, reqID,
+			common.LogKeyDuration, duration,
+			common.LogKeyEndpoint, tc.addr(),
+		)
+	}
+}
+
+func (tc *taosConn) processExecResult(result *handler.AsyncResult, reqID int64) (driver.Result, error) {
 	defer func() {
 		if result != nil && result.Res != nil {
 			locker.Lock()
@@ -114,7 +186,7 @@ func (tc *taosConn) processExecResult(result *handler.AsyncResult) (driver.Resul
 	code := wrapper.TaosError(res)
 	if code != int(errors.SUCCESS) {
 		errStr := wrapper.TaosErrorStr(res)
-		return nil, errors.NewError(code, errStr)
+		return nil, errors.NewErrorWithReqID(code, errStr, reqID)
 	}
 	affectRows := wrapper.TaosAffectedRows(res)
 	return driver.RowsAffected(affectRows), nil
@@ -128,32 +200,42 @@ func (tc *taosConn) QueryContext(ctx context.Context, query string, args []drive
 	if tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
+	if err := tc.flush(ctx); err != nil {
+		return nil, err
+	}
 	return tc.queryCtx(ctx, query, args)
 }
 
 func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	var reqIDValue int64
-	reqID := ctx.Value(common.ReqIDKey)
-	if reqID != nil {
-		reqIDValue, _ = reqID.(int64)
-	}
+	reqID := common.GetReqIDFromContext(ctx)
 	if len(args) != 0 {
 		if !tc.cfg.interpolateParams {
 			return nil, driver.ErrSkip
 		}
 		// try client-side prepare to reduce round trip
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
+	_, span := tc.startSpan(ctx, common.OpQuery, query, reqID)
+	start := time.Now()
 	h := asyncHandlerPool.Get()
-	result := tc.taosQuery(query, h, reqIDValue)
-	return tc.processRows(result, h)
+	result := tc.taosQuery(query, h, reqID)
+	rs, err := tc.processRows(result, h, reqID)
+	common.RecordRequest(tc.cfg.metrics, common.DriverTaosSql, common.ActionQuery, start, err)
+	tc.logSlowQuery(start, query, reqID)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	// the span ends when the rows are closed
+	rs.span = span
+	return rs, nil
 }
 
-func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler) (driver.Rows, error) {
+func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler, reqID int64) (*rows, error) {
 	res := result.Res
 	code := wrapper.TaosError(res)
 	if code != int(errors.SUCCESS) {
@@ -162,7 +244,7 @@ func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler)
 		locker.Lock()
 		wrapper.TaosFreeResult(result.Res)
 		locker.Unlock()
-		return nil, errors.NewError(code, errStr)
+		return nil, errors.NewErrorWithReqID(code, errStr, reqID)
 	}
 	numFields := wrapper.TaosNumFields(res)
 	rowsHeader, err := wrapper.ReadColumn(res, numFields)
@@ -176,6 +258,7 @@ func (tc *taosConn) processRows(result *handler.AsyncResult, h *handler.Handler)
 		rowsHeader: rowsHeader,
 		result:     res,
 		precision:  precision,
+		metrics:    tc.cfg.metrics,
 	}
 	return rs, nil
 }
@@ -188,9 +271,17 @@ func (tc *taosConn) Ping(ctx context.Context) (err error) {
 	return errors.ErrTscInvalidConnection
 }
 
-// BeginTx implements driver.ConnBeginTx interface
+// BeginTx implements driver.ConnBeginTx interface. TDengine has no transactions, the INSERT statements executed in
+// the transaction are buffered and merged into multi-table INSERT statements, see common.BatchTx.
 func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, &errors.TaosError{Code: 0xffff, ErrStr: "taosSql does not support transaction"}
+	if tc.taos == nil {
+		return nil, driver.ErrBadConn
+	}
+	tc.tx = common.NewBatchTx(ctx, tc.cfg.timePrecision, func(ctx context.Context, query string) error {
+		_, err := tc.execCtx(ctx, query, nil)
+		return err
+	})
+	return tc.tx, nil
 }
 
 func (tc *taosConn) taosQuery(sqlStr string, handler *handler.Handler, reqID int64) *handler.AsyncResult {
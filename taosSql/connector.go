@@ -53,8 +53,10 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 	tc.taos, err = wrapper.TaosConnect(tc.cfg.addr, tc.cfg.user, tc.cfg.passwd, tc.cfg.dbName, tc.cfg.port)
 	locker.Unlock()
 	if err != nil {
+		tc.log(common.LogLevelWarn, "connect failed", common.LogKeyEndpoint, tc.addr(), common.LogKeyError, err)
 		return nil, err
 	}
+	tc.log(common.LogLevelInfo, "connected", common.LogKeyEndpoint, tc.addr())
 
 	return tc, nil
 }
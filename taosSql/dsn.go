@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/errors"
 )
 
@@ -45,9 +46,13 @@ type config struct {
 	params                  map[string]string // Connection parameters
 	loc                     *time.Location    // Location for time.Time values
 	interpolateParams       bool              // Interpolate placeholders into query string
+	timePrecision           string            // precision of the time.Time values interpolated into queries, RFC 3339 strings when empty
 	configPath              string
 	cgoThread               int
 	cgoAsyncHandlerPoolSize int
+	metrics                 common.Metrics // metrics of this connector, the global one when nil
+	logger                  common.Logger  // logger of this connector, the global one when nil
+	slowQueryThreshold      time.Duration  // queries slower than this are logged, 0 disables it
 }
 
 // NewConfig creates a new Config and sets default values.
@@ -191,6 +196,30 @@ func parseDSNParams(cfg *config, params string) (err error) {
 				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid cgoAsyncHandlerPoolSize value: " + value}
 			}
 
+		case "metrics":
+			cfg.metrics, err = common.GetRegisteredMetrics(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+
+		case "logger":
+			cfg.logger, err = common.GetRegisteredLogger(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+
+		case "slowQueryThreshold":
+			cfg.slowQueryThreshold, err = time.ParseDuration(value)
+			if err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid duration value: " + value}
+			}
+
+		case "timePrecision":
+			if _, err = common.ParsePrecision(value); err != nil {
+				return &errors.TaosError{Code: 0xffff, ErrStr: "invalid DSN: " + err.Error()}
+			}
+			cfg.timePrecision = value
+
 		default:
 			// lazy init
 			if cfg.params == nil {
@@ -4,8 +4,10 @@ import (
 	"database/sql/driver"
 	"io"
 	"reflect"
+	"time"
 	"unsafe"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/parser"
 	"github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/wrapper"
@@ -23,6 +25,10 @@ type rows struct {
 	result      unsafe.Pointer
 	precision   int
 	isStmt      bool
+	span        common.Span // the span of the query, ended by Close
+	rowsRead    int
+	err         error // the error that ended Next early
+	metrics     common.Metrics
 }
 
 func (rs *rows) Columns() []string {
@@ -53,6 +59,11 @@ func (rs *rows) Close() error {
 	}
 	rs.result = nil
 	rs.block = nil
+	if rs.span != nil {
+		rs.span.SetAttributes(common.Attribute{Key: common.AttrRows, Value: rs.rowsRead})
+		common.EndSpan(rs.span, rs.err)
+		rs.span = nil
+	}
 	return nil
 }
 
@@ -68,6 +79,7 @@ func (rs *rows) Next(dest []driver.Value) error {
 	if rs.block == nil {
 		err := rs.taosFetchBlock()
 		if err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -79,6 +91,7 @@ func (rs *rows) Next(dest []driver.Value) error {
 	if rs.blockOffset >= rs.blockSize {
 		err := rs.taosFetchBlock()
 		if err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -88,10 +101,15 @@ func (rs *rows) Next(dest []driver.Value) error {
 	}
 	parser.ReadRow(dest, rs.block, rs.blockSize, rs.blockOffset, rs.rowsHeader.ColTypes, rs.precision)
 	rs.blockOffset++
+	rs.rowsRead++
 	return nil
 }
 
-func (rs *rows) taosFetchBlock() error {
+func (rs *rows) taosFetchBlock() (err error) {
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(rs.metrics, common.DriverTaosSql, common.ActionFetchBlock, start, err)
+	}()
 	//rs.blockSize, rs.block = wrapper.TaosFetchBlock(rs.result)
 	//return nil
 	result := rs.asyncFetchRows()
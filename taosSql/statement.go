@@ -1,17 +1,15 @@
 package taosSql
 
 import (
+	"context"
 	"database/sql/driver"
 	"fmt"
-	"reflect"
-	"strconv"
 	"time"
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
 	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
 	"github.com/taosdata/driver-go/v3/errors"
-	"github.com/taosdata/driver-go/v3/types"
 	"github.com/taosdata/driver-go/v3/wrapper"
 )
 
@@ -24,6 +22,7 @@ type Stmt struct {
 	pSql     string
 	isInsert bool
 	cols     []*stmtCommon.StmtField
+	params   *common.Params // the placeholders of a query prepared with @name, :name or $N, nil for ?
 }
 
 func (stmt *Stmt) Close() error {
@@ -37,6 +36,10 @@ func (stmt *Stmt) Close() error {
 }
 
 func (stmt *Stmt) NumInput() int {
+	if stmt.params != nil {
+		// a parameter may be used more than once
+		return -1
+	}
 	if stmt.cols != nil {
 		return len(stmt.cols)
 	}
@@ -44,12 +47,32 @@ func (stmt *Stmt) NumInput() int {
 }
 
 func (stmt *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.ExecContext(context.Background(), common.ValueArgsToNamedValueArgs(args))
+}
+
+// ExecContext implements driver.StmtExecContext interface
+func (stmt *Stmt) ExecContext(ctx context.Context, namedArgs []driver.NamedValue) (result driver.Result, err error) {
 	if stmt.tc == nil || stmt.tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
+	if err = stmt.tc.flush(ctx); err != nil {
+		return nil, err
+	}
+	namedArgs, err = stmt.bindParams(namedArgs)
+	if err != nil {
+		return nil, err
+	}
+	args := namedValueToValue(namedArgs)
 	if len(args) != len(stmt.cols) {
 		return nil, fmt.Errorf("stmt exec error: wrong number of parameters")
 	}
+	span := stmt.startSpan()
+	start := time.Now()
+	defer func() {
+		common.RecordRequest(stmt.tc.cfg.metrics, common.DriverTaosSql, common.ActionStmtExec, start, err)
+		stmt.tc.logSlowQuery(start, stmt.pSql, 0)
+		common.EndSpan(span, err)
+	}()
 	locker.Lock()
 	defer locker.Unlock()
 	code := wrapper.TaosStmtBindParam(stmt.stmt, args)
@@ -68,13 +91,51 @@ func (stmt *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 		return nil, errors.NewError(code, errStr)
 	}
 	affectRows := wrapper.TaosStmtAffectedRowsOnce(stmt.stmt)
+	span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: affectRows})
 	return driver.RowsAffected(affectRows), nil
 }
 
+// startSpan starts tracing an execution of the statement, see common.Tracer
+func (stmt *Stmt) startSpan() common.Span {
+	_, span := common.StartSpan(context.Background(), common.OpStmtExec,
+		common.Attribute{Key: common.AttrSQL, Value: stmt.pSql},
+		common.Attribute{Key: common.AttrEndpoint, Value: stmt.tc.addr()},
+	)
+	return span
+}
+
 func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.QueryContext(context.Background(), common.ValueArgsToNamedValueArgs(args))
+}
+
+// QueryContext implements driver.StmtQueryContext interface
+func (stmt *Stmt) QueryContext(ctx context.Context, namedArgs []driver.NamedValue) (driver.Rows, error) {
 	if stmt.tc == nil || stmt.tc.taos == nil {
 		return nil, driver.ErrBadConn
 	}
+	if err := stmt.tc.flush(ctx); err != nil {
+		return nil, err
+	}
+	namedArgs, err := stmt.bindParams(namedArgs)
+	if err != nil {
+		return nil, err
+	}
+	args := namedValueToValue(namedArgs)
+	span := stmt.startSpan()
+	start := time.Now()
+	rs, err := stmt.query(args)
+	common.RecordRequest(stmt.tc.cfg.metrics, common.DriverTaosSql, common.ActionStmtExec, start, err)
+	stmt.tc.logSlowQuery(start, stmt.pSql, 0)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	// the span ends when the rows are closed
+	rs.span = span
+	return rs, nil
+}
+
+func (stmt *Stmt) query(args []driver.Value) (*rows, error) {
 	locker.Lock()
 	defer locker.Unlock()
 	code := wrapper.TaosStmtBindParam(stmt.stmt, args)
@@ -106,404 +167,53 @@ func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 		result:     res,
 		precision:  precision,
 		isStmt:     true,
+		metrics:    stmt.tc.cfg.metrics,
 	}
 	return rs, nil
 }
 
-func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
-	if stmt.isInsert {
-		if stmt.cols == nil {
-			locker.Lock()
-			code, num, fieldsP := wrapper.TaosStmtGetColFields(stmt.stmt)
-			locker.Unlock()
-			if code != 0 {
-				errStr := wrapper.TaosStmtErrStr(stmt.stmt)
-				return errors.NewError(code, errStr)
-			}
-			defer wrapper.TaosStmtReclaimFields(stmt.stmt, fieldsP)
-			stmt.cols = wrapper.StmtParseFields(num, fieldsP)
-		}
-		if v.Ordinal > len(stmt.cols) {
-			return nil
-		}
-		if v.Value == nil {
-			return nil
-		}
-		switch stmt.cols[v.Ordinal-1].FieldType {
-		case common.TSDB_DATA_TYPE_NULL:
-			v.Value = nil
-		case common.TSDB_DATA_TYPE_BOOL:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				v.Value = types.TaosBool(rv.Bool())
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosBool(rv.Float() == 1)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosBool(rv.Int() == 1)
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosBool(rv.Uint() == 1)
-			case reflect.String:
-				vv, err := strconv.ParseBool(rv.String())
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosBool(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to bool", v)
-			}
-		case common.TSDB_DATA_TYPE_TINYINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosTinyint(1)
-				} else {
-					v.Value = types.TaosTinyint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosTinyint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosTinyint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosTinyint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseInt(rv.String(), 0, 8)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosTinyint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to tinyint", v)
-			}
-		case common.TSDB_DATA_TYPE_SMALLINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosSmallint(1)
-				} else {
-					v.Value = types.TaosSmallint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosSmallint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosSmallint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosSmallint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseInt(rv.String(), 0, 16)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosSmallint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to smallint", v)
-			}
-		case common.TSDB_DATA_TYPE_INT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosInt(1)
-				} else {
-					v.Value = types.TaosInt(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosInt(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosInt(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosInt(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseInt(rv.String(), 0, 32)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosInt(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to int", v)
-			}
-		case common.TSDB_DATA_TYPE_BIGINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosBigint(1)
-				} else {
-					v.Value = types.TaosBigint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosBigint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosBigint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosBigint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseInt(rv.String(), 0, 64)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosBigint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to bigint", v)
-			}
-		case common.TSDB_DATA_TYPE_FLOAT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosFloat(1)
-				} else {
-					v.Value = types.TaosFloat(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosFloat(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosFloat(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosFloat(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseFloat(rv.String(), 32)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosFloat(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to float", v)
-			}
-		case common.TSDB_DATA_TYPE_DOUBLE:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosDouble(1)
-				} else {
-					v.Value = types.TaosDouble(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosDouble(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosDouble(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosDouble(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseFloat(rv.String(), 64)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosDouble(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to double", v)
-			}
-		case common.TSDB_DATA_TYPE_BINARY:
-			switch v.Value.(type) {
-			case string:
-				v.Value = types.TaosBinary(v.Value.(string))
-			case []byte:
-				v.Value = types.TaosBinary(v.Value.([]byte))
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to binary", v)
-			}
-		case common.TSDB_DATA_TYPE_VARBINARY:
-			switch v.Value.(type) {
-			case string:
-				v.Value = types.TaosVarBinary(v.Value.(string))
-			case []byte:
-				v.Value = types.TaosVarBinary(v.Value.([]byte))
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to varbinary", v)
-			}
+// bindParams orders args as the ? of the prepared query
+func (stmt *Stmt) bindParams(args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if stmt.params == nil {
+		return args, nil
+	}
+	return stmt.params.Bind(args)
+}
 
-		case common.TSDB_DATA_TYPE_GEOMETRY:
-			switch v.Value.(type) {
-			case string:
-				v.Value = types.TaosGeometry(v.Value.(string))
-			case []byte:
-				v.Value = types.TaosGeometry(v.Value.([]byte))
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to geometry", v)
-			}
+// column returns the index of the column bound to v
+func (stmt *Stmt) column(v *driver.NamedValue) int {
+	if stmt.params != nil {
+		return stmt.params.Position(*v)
+	}
+	return v.Ordinal - 1
+}
 
-		case common.TSDB_DATA_TYPE_TIMESTAMP:
-			t, is := v.Value.(time.Time)
-			if is {
-				v.Value = types.TaosTimestamp{
-					T:         t,
-					Precision: int(stmt.cols[v.Ordinal-1].Precision),
-				}
-				return nil
-			}
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Float32, reflect.Float64:
-				t := common.TimestampConvertToTime(int64(rv.Float()), int(stmt.cols[v.Ordinal-1].Precision))
-				v.Value = types.TaosTimestamp{
-					T:         t,
-					Precision: int(stmt.cols[v.Ordinal-1].Precision),
-				}
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				t := common.TimestampConvertToTime(rv.Int(), int(stmt.cols[v.Ordinal-1].Precision))
-				v.Value = types.TaosTimestamp{
-					T:         t,
-					Precision: int(stmt.cols[v.Ordinal-1].Precision),
-				}
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				t := common.TimestampConvertToTime(int64(rv.Uint()), int(stmt.cols[v.Ordinal-1].Precision))
-				v.Value = types.TaosTimestamp{
-					T:         t,
-					Precision: int(stmt.cols[v.Ordinal-1].Precision),
-				}
-			case reflect.String:
-				t, err := time.Parse(time.RFC3339Nano, rv.String())
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosTimestamp{
-					T:         t,
-					Precision: int(stmt.cols[v.Ordinal-1].Precision),
-				}
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to timestamp", v)
-			}
-		case common.TSDB_DATA_TYPE_NCHAR:
-			switch v.Value.(type) {
-			case string:
-				v.Value = types.TaosNchar(v.Value.(string))
-			case []byte:
-				v.Value = types.TaosNchar(v.Value.([]byte))
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to nchar", v)
-			}
-		case common.TSDB_DATA_TYPE_UTINYINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosUTinyint(1)
-				} else {
-					v.Value = types.TaosUTinyint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosUTinyint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosUTinyint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosUTinyint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseUint(rv.String(), 0, 8)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosUTinyint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to tinyint unsigned", v)
-			}
-		case common.TSDB_DATA_TYPE_USMALLINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosUSmallint(1)
-				} else {
-					v.Value = types.TaosUSmallint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosUSmallint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosUSmallint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosUSmallint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseUint(rv.String(), 0, 16)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosUSmallint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to smallint unsigned", v)
-			}
-		case common.TSDB_DATA_TYPE_UINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosUInt(1)
-				} else {
-					v.Value = types.TaosUInt(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosUInt(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosUInt(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosUInt(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseUint(rv.String(), 0, 32)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosUInt(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to int unsigned", v)
-			}
-		case common.TSDB_DATA_TYPE_UBIGINT:
-			rv := reflect.ValueOf(v.Value)
-			switch rv.Kind() {
-			case reflect.Bool:
-				if rv.Bool() {
-					v.Value = types.TaosUBigint(1)
-				} else {
-					v.Value = types.TaosUBigint(0)
-				}
-			case reflect.Float32, reflect.Float64:
-				v.Value = types.TaosUBigint(rv.Float())
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				v.Value = types.TaosUBigint(rv.Int())
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				v.Value = types.TaosUBigint(rv.Uint())
-			case reflect.String:
-				vv, err := strconv.ParseUint(rv.String(), 0, 64)
-				if err != nil {
-					return err
-				}
-				v.Value = types.TaosUBigint(vv)
-			default:
-				return fmt.Errorf("CheckNamedValue:%v can not convert to bigint unsigned", v)
-			}
-		}
-		return nil
-	} else {
-		if v.Value == nil {
-			return nil
-		}
-		t, is := v.Value.(time.Time)
-		if is {
-			v.Value = types.TaosBinary(t.Format(time.RFC3339Nano))
-			return nil
-		}
-		rv := reflect.ValueOf(v.Value)
-		switch rv.Kind() {
-		case reflect.Bool:
-			v.Value = types.TaosBool(rv.Bool())
-		case reflect.Float32, reflect.Float64:
-			v.Value = types.TaosDouble(rv.Float())
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			v.Value = types.TaosBigint(rv.Int())
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			v.Value = types.TaosUBigint(rv.Uint())
-		case reflect.String:
-			v.Value = types.TaosBinary(rv.String())
-		case reflect.Slice:
-			ek := rv.Type().Elem().Kind()
-			if ek == reflect.Uint8 {
-				v.Value = types.TaosBinary(rv.Bytes())
-			} else {
-				return fmt.Errorf("CheckNamedValue: can not convert query value %v", v)
-			}
-		default:
-			return fmt.Errorf("CheckNamedValue: can not convert query value %v", v)
+func namedValueToValue(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
+	if !stmt.isInsert {
+		return stmtCommon.ConvertQueryValue(v)
+	}
+	if stmt.cols == nil {
+		locker.Lock()
+		code, num, fieldsP := wrapper.TaosStmtGetColFields(stmt.stmt)
+		locker.Unlock()
+		if code != 0 {
+			errStr := wrapper.TaosStmtErrStr(stmt.stmt)
+			return errors.NewError(code, errStr)
 		}
+		defer wrapper.TaosStmtReclaimFields(stmt.stmt, fieldsP)
+		stmt.cols = wrapper.StmtParseFields(num, fieldsP)
+	}
+	col := stmt.column(v)
+	if col < 0 || col >= len(stmt.cols) {
 		return nil
 	}
+	return stmtCommon.ConvertInsertValue(v, stmt.cols[col])
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"database/sql/driver"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,7 +16,11 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/serializer"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/internal/failover"
 )
 
 var jsonI = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -26,6 +31,19 @@ const (
 	WSFetch      = "fetch"
 	WSFetchBlock = "fetch_block"
 	WSFreeResult = "free_result"
+
+	STMTInit         = "stmt_init"
+	STMTPrepare      = "stmt_prepare"
+	STMTGetColFields = "stmt_get_col_fields"
+	STMTBind         = "stmt_bind" // reported to common.Metrics, binding is a binary message without an action
+	STMTAddBatch     = "stmt_add_batch"
+	STMTExec         = "stmt_exec"
+	STMTUseResult    = "stmt_use_result"
+	STMTClose        = "stmt_close"
+)
+
+const (
+	BindMessage = 2
 )
 
 var (
@@ -36,30 +54,80 @@ var (
 type taosConn struct {
 	buf          *bytes.Buffer
 	client       *websocket.Conn
-	requestID    uint64
 	readTimeout  time.Duration
 	writeTimeout time.Duration
 	cfg          *config
 	endpoint     string
+	bad          uint32
+	pool         *failover.Pool
+	poolEndpoint *failover.Endpoint
+	tx           *common.BatchTx // the current transaction
 }
 
-func (tc *taosConn) generateReqID() uint64 {
-	return atomic.AddUint64(&tc.requestID, 1)
+// getReqID returns the request ID stored in ctx under common.ReqIDKey, or a new one.
+func getReqID(ctx context.Context) uint64 {
+	return uint64(common.GetReqIDFromContext(ctx))
 }
 
-func newTaosConn(cfg *config) (*taosConn, error) {
+func getEndpointPool(cfg *config) *failover.Pool {
+	return failover.GetPool(cfg.net, cfg.endpointAddrs(), cfg.loadBalance, cfg.endpointBackoff)
+}
+
+// newTaosConn dials the endpoints of cfg in the order chosen by its endpoint pool,
+// an endpoint that can not be dialed is marked unhealthy and the next one is tried.
+func newTaosConn(ctx context.Context, cfg *config) (*taosConn, error) {
+	pool := getEndpointPool(cfg)
+	var err error
+	for _, e := range pool.Candidates() {
+		var ws *websocket.Conn
+		var endpoint string
+		ws, endpoint, err = dialEndpoint(ctx, cfg, e.Addr)
+		if err != nil {
+			common.Log(cfg.logger, common.LogLevelWarn, "dial endpoint failed", common.LogKeyEndpoint, e.Addr, common.LogKeyError, err)
+			pool.MarkFailed(e)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		pool.MarkHealthy(e)
+		pool.Acquire(e)
+		tc := &taosConn{
+			buf:          &bytes.Buffer{},
+			client:       ws,
+			readTimeout:  cfg.readTimeout,
+			writeTimeout: cfg.writeTimeout,
+			cfg:          cfg,
+			endpoint:     endpoint,
+			pool:         pool,
+			poolEndpoint: e,
+		}
+
+		err = tc.connect(ctx)
+		if err != nil {
+			tc.log(common.LogLevelWarn, "connect failed", common.LogKeyEndpoint, e.Addr, common.LogKeyError, err)
+			tc.Close()
+			return nil, err
+		}
+		tc.log(common.LogLevelInfo, "connected", common.LogKeyEndpoint, e.Addr)
+		return tc, nil
+	}
+	return nil, err
+}
+
+func dialEndpoint(ctx context.Context, cfg *config, addr string) (*websocket.Conn, string, error) {
 	endpointUrl := &url.URL{
-		Scheme: cfg.net,
-		Host:   fmt.Sprintf("%s:%d", cfg.addr, cfg.port),
+		Scheme: common.TLSScheme(cfg.net, cfg.tlsConfig),
+		Host:   addr,
 		Path:   "/rest/ws",
 	}
 	if cfg.token != "" {
 		endpointUrl.RawQuery = fmt.Sprintf("token=%s", cfg.token)
 	}
 	endpoint := endpointUrl.String()
-	ws, _, err := common.DefaultDialer.Dial(endpoint, nil)
+	ws, _, err := common.NewDialer(cfg.tlsConfig).DialContext(ctx, endpoint, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ws.SetReadLimit(common.BufferSize4M)
 	ws.SetReadDeadline(time.Now().Add(common.DefaultPongWait))
@@ -67,30 +135,34 @@ func newTaosConn(cfg *config) (*taosConn, error) {
 		ws.SetReadDeadline(time.Now().Add(common.DefaultPongWait))
 		return nil
 	})
-	tc := &taosConn{
-		buf:          &bytes.Buffer{},
-		client:       ws,
-		requestID:    0,
-		readTimeout:  cfg.readTimeout,
-		writeTimeout: cfg.writeTimeout,
-		cfg:          cfg,
-		endpoint:     endpoint,
-	}
-
-	err = tc.connect()
-	if err != nil {
-		tc.Close()
-	}
-	return tc, nil
+	return ws, endpoint, nil
 }
 
 func (tc *taosConn) Begin() (driver.Tx, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "websocket does not support transaction"}
+	return tc.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx interface. TDengine has no transactions, the INSERT statements executed in
+// the transaction are buffered and merged into multi-table INSERT statements, see common.BatchTx.
+func (tc *taosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if tc.isBad() {
+		return nil, driver.ErrBadConn
+	}
+	tc.tx = common.NewBatchTx(ctx, tc.cfg.timePrecision, func(ctx context.Context, query string) error {
+		_, err := tc.execCtx(ctx, query, nil)
+		return err
+	})
+	return tc.tx, nil
 }
 
 func (tc *taosConn) Close() (err error) {
 	if tc.client != nil {
 		err = tc.client.Close()
+		tc.log(common.LogLevelInfo, "connection closed", common.LogKeyEndpoint, tc.addr())
+	}
+	if tc.poolEndpoint != nil {
+		tc.pool.Release(tc.poolEndpoint)
+		tc.poolEndpoint = nil
 	}
 	tc.client = nil
 	tc.cfg = nil
@@ -99,7 +171,50 @@ func (tc *taosConn) Close() (err error) {
 }
 
 func (tc *taosConn) Prepare(query string) (driver.Stmt, error) {
-	return nil, &taosErrors.TaosError{Code: 0xffff, ErrStr: "websocket does not support stmt"}
+	return tc.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext interface
+func (tc *taosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if tc.isBad() {
+		return nil, driver.ErrBadConn
+	}
+	rewritten, params, err := common.ParseParams(query)
+	if err != nil {
+		return nil, err
+	}
+	stmtID, err := tc.stmtInit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isInsert, err := tc.stmtPrepare(ctx, stmtID, rewritten)
+	if err != nil {
+		if !tc.isBad() {
+			tc.stmtClose(stmtID)
+		}
+		return nil, err
+	}
+	stmt := &Stmt{
+		conn:     tc,
+		stmtID:   stmtID,
+		pSql:     query,
+		isInsert: isInsert,
+		params:   params,
+	}
+	return stmt, nil
+}
+
+// CheckNamedValue keeps geometry arguments for common.WriteValue, see common.CheckNamedValue.
+func (tc *taosConn) CheckNamedValue(v *driver.NamedValue) error {
+	return common.CheckNamedValue(v)
+}
+
+// flush executes the statements buffered by the transaction before a statement that is not buffered runs
+func (tc *taosConn) flush(ctx context.Context) error {
+	if tc.tx == nil {
+		return nil
+	}
+	return tc.tx.Flush(ctx)
 }
 
 func (tc *taosConn) Exec(query string, args []driver.Value) (driver.Result, error) {
@@ -107,22 +222,41 @@ func (tc *taosConn) Exec(query string, args []driver.Value) (driver.Result, erro
 }
 
 func (tc *taosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	if tc.tx != nil {
+		buffered, err := tc.tx.Add(ctx, query, args)
+		if err != nil {
+			return nil, err
+		}
+		if buffered {
+			// the rows are inserted when the transaction commits, their number is not known yet
+			return driver.RowsAffected(0), nil
+		}
+	}
 	return tc.execCtx(ctx, query, args)
 }
 
-func (tc *taosConn) execCtx(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+func (tc *taosConn) execCtx(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	if tc.isBad() {
+		return nil, driver.ErrBadConn
+	}
 	if len(args) != 0 {
 		if !tc.cfg.interpolateParams {
 			return nil, driver.ErrSkip
 		}
 		// try to interpolate the parameters to save extra round trips for preparing and closing a statement
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
-	reqID := tc.generateReqID()
+	reqID := getReqID(ctx)
+	ctx, span := tc.startSpan(ctx, common.OpExec, query, reqID)
+	defer func() {
+		common.EndSpan(span, err)
+	}()
+	defer tc.observe(WSQuery, time.Now(), &err)
+	defer tc.logSlowQuery(time.Now(), query, reqID)
 	req := &WSQueryReq{
 		ReqID: reqID,
 		SQL:   query,
@@ -140,42 +274,106 @@ func (tc *taosConn) execCtx(_ context.Context, query string, args []driver.Named
 	if err != nil {
 		return nil, err
 	}
-	err = tc.writeText(tc.buf.Bytes())
+	err = tc.writeText(ctx, tc.buf.Bytes())
 	if err != nil {
 		return nil, err
 	}
 	var resp WSQueryResp
-	err = tc.readTo(&resp)
+	err = tc.readTo(ctx, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if resp.Code != 0 {
-		return nil, taosErrors.NewError(resp.Code, resp.Message)
+		return nil, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
 	}
+	span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: resp.AffectedRows})
 	return driver.RowsAffected(resp.AffectedRows), nil
 }
 
+// startSpan starts tracing an operation on this connection, see common.Tracer
+func (tc *taosConn) startSpan(ctx context.Context, operation string, sql string, reqID uint64) (context.Context, common.Span) {
+	return common.StartSpan(ctx, operation,
+		common.Attribute{Key: common.AttrSQL, Value: sql},
+		common.Attribute{Key: common.AttrReqID, Value: int64(reqID)},
+		common.Attribute{Key: common.AttrEndpoint, Value: tc.addr()},
+	)
+}
+
+// addr returns host:port of the connected taosAdapter
+func (tc *taosConn) addr() string {
+	if tc.poolEndpoint == nil {
+		return ""
+	}
+	return tc.poolEndpoint.Addr
+}
+
+// observe reports a request started at start to the metrics of the connection, err is read when it returns
+func (tc *taosConn) observe(action string, start time.Time, err *error) {
+	common.RecordRequest(tc.cfg.metrics, common.DriverTaosWS, action, start, *err)
+}
+
+// log reports the failover connects, read timeouts and slow queries of the connection to the logger of its DSN
+func (tc *taosConn) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(tc.cfg.logger, level, msg, keyvals...)
+}
+
+// logSlowQuery logs query when it took longer than the slowQueryThreshold of the DSN since start
+func (tc *taosConn) logSlowQuery(start time.Time, query string, reqID uint64) {
+	if tc.cfg.slowQueryThreshold <= 0 {
+		return
+	}
+	if duration := time.Since(start); duration >= tc.cfg.slowQueryThreshold {
+		tc.log(common.LogLevelWarn, "slow query",
+			common.LogKeySQL, query,
+			common.LogKeyReqID, int64(reqID),
+			common.LogKeyDuration, duration,
+			common.LogKeyEndpoint, tc.addr(),
+		)
+	}
+}
+
 func (tc *taosConn) Query(query string, args []driver.Value) (driver.Rows, error) {
 	return tc.QueryContext(context.Background(), query, common.ValueArgsToNamedValueArgs(args))
 }
 
 func (tc *taosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	if err := tc.flush(ctx); err != nil {
+		return nil, err
+	}
 	return tc.queryCtx(ctx, query, args)
 }
 
-func (tc *taosConn) queryCtx(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+func (tc *taosConn) queryCtx(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if tc.isBad() {
+		return nil, driver.ErrBadConn
+	}
 	if len(args) != 0 {
 		if !tc.cfg.interpolateParams {
 			return nil, driver.ErrSkip
 		}
 		// try client-side prepare to reduce round trip
-		prepared, err := common.InterpolateParams(query, args)
+		prepared, err := common.InterpolateParamsWithPrecision(query, args, tc.cfg.timePrecision)
 		if err != nil {
 			return nil, err
 		}
 		query = prepared
 	}
-	reqID := tc.generateReqID()
+	reqID := getReqID(ctx)
+	ctx, span := tc.startSpan(ctx, common.OpQuery, query, reqID)
+	start := time.Now()
+	rs, err := tc.query(ctx, query, reqID)
+	tc.logSlowQuery(start, query, reqID)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	// the span ends when the rows are closed
+	rs.span = span
+	return rs, nil
+}
+
+func (tc *taosConn) query(ctx context.Context, query string, reqID uint64) (rs *rows, err error) {
+	defer tc.observe(WSQuery, time.Now(), &err)
 	req := &WSQueryReq{
 		ReqID: reqID,
 		SQL:   query,
@@ -193,24 +391,26 @@ func (tc *taosConn) queryCtx(_ context.Context, query string, args []driver.Name
 	if err != nil {
 		return nil, err
 	}
-	err = tc.writeText(tc.buf.Bytes())
+	err = tc.writeText(ctx, tc.buf.Bytes())
 	if err != nil {
 		return nil, err
 	}
 	var resp WSQueryResp
-	err = tc.readTo(&resp)
+	err = tc.readTo(ctx, &resp)
 	if err != nil {
 		return nil, err
 	}
 	if resp.Code != 0 {
-		return nil, taosErrors.NewError(resp.Code, resp.Message)
+		return nil, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
 	}
 	if resp.IsUpdate {
 		return nil, NotQueryError
 	}
-	rs := &rows{
+	rs = &rows{
+		ctx:           ctx,
 		buf:           &bytes.Buffer{},
 		conn:          tc,
+		reqID:         reqID,
 		resultID:      resp.ID,
 		fieldsCount:   resp.FieldsCount,
 		fieldsNames:   resp.FieldsNames,
@@ -222,12 +422,29 @@ func (tc *taosConn) queryCtx(_ context.Context, query string, args []driver.Name
 }
 
 func (tc *taosConn) Ping(ctx context.Context) (err error) {
+	if tc.isBad() {
+		return driver.ErrBadConn
+	}
 	return nil
 }
 
-func (tc *taosConn) connect() error {
+// ResetSession implements driver.SessionResetter interface
+func (tc *taosConn) ResetSession(ctx context.Context) error {
+	if tc.isBad() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (tc *taosConn) isBad() bool {
+	return tc.client == nil || atomic.LoadUint32(&tc.bad) == 1
+}
+
+func (tc *taosConn) connect(ctx context.Context) (err error) {
+	defer tc.observe(WSConnect, time.Now(), &err)
+	reqID := getReqID(ctx)
 	req := &WSConnectReq{
-		ReqID:    0,
+		ReqID:    reqID,
 		User:     tc.cfg.user,
 		Password: tc.cfg.passwd,
 		DB:       tc.cfg.dbName,
@@ -245,33 +462,256 @@ func (tc *taosConn) connect() error {
 	if err != nil {
 		return err
 	}
-	err = tc.writeText(tc.buf.Bytes())
+	err = tc.writeText(ctx, tc.buf.Bytes())
 	if err != nil {
 		return err
 	}
 	var resp WSConnectResp
-	err = tc.readTo(&resp)
+	err = tc.readTo(ctx, &resp)
 	if err != nil {
 		return err
 	}
 	if resp.Code != 0 {
-		return taosErrors.NewError(resp.Code, resp.Message)
+		return taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
 	}
 	return nil
 }
 
-func (tc *taosConn) writeText(data []byte) error {
-	tc.client.SetWriteDeadline(time.Now().Add(tc.writeTimeout))
-	err := tc.client.WriteMessage(websocket.TextMessage, data)
+func (tc *taosConn) stmtInit(ctx context.Context) (stmtID uint64, err error) {
+	defer tc.observe(STMTInit, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtInitReq{
+		ReqID: reqID,
+	}
+	err = tc.writeAction(ctx, STMTInit, req)
+	if err != nil {
+		return 0, err
+	}
+	var resp WSStmtInitResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Code != 0 {
+		return 0, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return resp.StmtID, nil
+}
+
+func (tc *taosConn) stmtPrepare(ctx context.Context, stmtID uint64, sql string) (isInsert bool, err error) {
+	defer tc.observe(STMTPrepare, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtPrepareReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+		SQL:    sql,
+	}
+	err = tc.writeAction(ctx, STMTPrepare, req)
+	if err != nil {
+		return false, err
+	}
+	var resp WSStmtPrepareResp
+	err = tc.readTo(ctx, &resp)
 	if err != nil {
-		return NewBadConnErrorWithCtx(err, string(data))
+		return false, err
 	}
+	if resp.Code != 0 {
+		return false, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return resp.IsInsert, nil
+}
+
+func (tc *taosConn) stmtGetColFields(ctx context.Context, stmtID uint64) (fields []*stmtCommon.StmtField, err error) {
+	defer tc.observe(STMTGetColFields, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtGetColFieldsReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	err = tc.writeAction(ctx, STMTGetColFields, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp WSStmtGetColFieldsResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return resp.Fields, nil
+}
+
+func (tc *taosConn) stmtBindParam(ctx context.Context, stmtID uint64, params []*param.Param, bindType *param.ColumnType) (err error) {
+	defer tc.observe(STMTBind, time.Now(), &err)
+	block, err := serializer.SerializeRawBlock(params, bindType)
+	if err != nil {
+		return err
+	}
+	reqID := getReqID(ctx)
+	reqData := make([]byte, 24, 24+len(block))
+	binary.LittleEndian.PutUint64(reqData, reqID)
+	binary.LittleEndian.PutUint64(reqData[8:], stmtID)
+	binary.LittleEndian.PutUint64(reqData[16:], BindMessage)
+	reqData = append(reqData, block...)
+	err = tc.writeBinary(ctx, reqData)
+	if err != nil {
+		return err
+	}
+	var resp WSStmtBindResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return nil
+}
+
+func (tc *taosConn) stmtAddBatch(ctx context.Context, stmtID uint64) (err error) {
+	defer tc.observe(STMTAddBatch, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtAddBatchReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	err = tc.writeAction(ctx, STMTAddBatch, req)
+	if err != nil {
+		return err
+	}
+	var resp WSStmtAddBatchResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Code != 0 {
+		return taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return nil
+}
+
+func (tc *taosConn) stmtExec(ctx context.Context, stmtID uint64) (affected int, err error) {
+	defer tc.observe(STMTExec, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtExecReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	err = tc.writeAction(ctx, STMTExec, req)
+	if err != nil {
+		return 0, err
+	}
+	var resp WSStmtExecResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Code != 0 {
+		return 0, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	return resp.Affected, nil
+}
+
+func (tc *taosConn) stmtUseResult(ctx context.Context, stmtID uint64) (rs *rows, err error) {
+	defer tc.observe(STMTUseResult, time.Now(), &err)
+	reqID := getReqID(ctx)
+	req := &WSStmtUseResultReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	err = tc.writeAction(ctx, STMTUseResult, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp WSStmtUseResultResp
+	err = tc.readTo(ctx, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
+	}
+	rs = &rows{
+		ctx:           ctx,
+		buf:           &bytes.Buffer{},
+		conn:          tc,
+		reqID:         reqID,
+		resultID:      resp.ResultID,
+		fieldsCount:   resp.FieldsCount,
+		fieldsNames:   resp.FieldsNames,
+		fieldsTypes:   resp.FieldsTypes,
+		fieldsLengths: resp.FieldsLengths,
+		precision:     resp.Precision,
+	}
+	return rs, nil
+}
+
+// stmtClose releases the server side stmt, the server does not respond to this action.
+func (tc *taosConn) stmtClose(stmtID uint64) error {
+	reqID := getReqID(context.Background())
+	req := &WSStmtCloseReq{
+		ReqID:  reqID,
+		StmtID: stmtID,
+	}
+	return tc.writeAction(context.Background(), STMTClose, req)
+}
+
+func (tc *taosConn) writeAction(ctx context.Context, action string, req interface{}) error {
+	args, err := jsonI.Marshal(req)
+	if err != nil {
+		return err
+	}
+	tc.buf.Reset()
+	err = jsonI.NewEncoder(tc.buf).Encode(&WSAction{
+		Action: action,
+		Args:   args,
+	})
+	if err != nil {
+		return err
+	}
+	return tc.writeText(ctx, tc.buf.Bytes())
+}
+
+func (tc *taosConn) writeBinary(ctx context.Context, data []byte) error {
+	return tc.write(ctx, websocket.BinaryMessage, data)
+}
+
+func (tc *taosConn) writeText(ctx context.Context, data []byte) error {
+	return tc.write(ctx, websocket.TextMessage, data)
+}
+
+func (tc *taosConn) write(ctx context.Context, messageType int, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(tc.writeTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	tc.client.SetWriteDeadline(deadline)
+	err := tc.client.WriteMessage(messageType, data)
+	if err != nil {
+		// a failed write leaves the websocket in an undefined state
+		atomic.StoreUint32(&tc.bad, 1)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if messageType == websocket.TextMessage {
+			return NewBadConnErrorWithCtx(err, string(data))
+		}
+		return NewBadConnError(err)
+	}
+	common.RecordBytes(tc.cfg.metrics, common.DriverTaosWS, common.BytesSent, len(data))
 	return nil
 }
 
-func (tc *taosConn) readTo(to interface{}) error {
+func (tc *taosConn) readTo(ctx context.Context, to interface{}) error {
 	var outErr error
 	done := make(chan struct{})
+	// the read may outlive the connection when it is abandoned
+	cfg := tc.cfg
 	go func() {
 		defer func() {
 			close(done)
@@ -281,30 +721,46 @@ func (tc *taosConn) readTo(to interface{}) error {
 			outErr = NewBadConnError(err)
 			return
 		}
+		common.RecordBytes(cfg.metrics, common.DriverTaosWS, common.BytesReceived, len(respBytes))
 		if mt != websocket.TextMessage {
 			outErr = NewBadConnErrorWithCtx(fmt.Errorf("readTo: got wrong message type %d", mt), formatBytes(respBytes))
 			return
 		}
 		err = jsonI.Unmarshal(respBytes, to)
 		if err != nil {
-			outErr = NewBadConnErrorWithCtx(err, string(respBytes))
+			common.Log(cfg.logger, common.LogLevelError, "decode response failed",
+				common.LogKeyReqID, jsonI.Get(respBytes, "req_id").ToInt64(),
+				common.LogKeyError, err,
+			)
+			outErr = NewBadConnErrorWithCtx(err, formatBytes(respBytes))
 			return
 		}
 	}()
-	ctx, cancel := context.WithTimeout(context.Background(), tc.readTimeout)
+	readCtx, cancel := context.WithTimeout(ctx, tc.readTimeout)
 	defer cancel()
 	select {
 	case <-done:
+		if outErr != nil {
+			atomic.StoreUint32(&tc.bad, 1)
+		}
 		return outErr
-	case <-ctx.Done():
+	case <-readCtx.Done():
+		tc.abandon(done, func() uint64 {
+			return abandonedResultID(to)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tc.log(common.LogLevelWarn, "read timeout, connection abandoned", common.LogKeyEndpoint, tc.addr())
 		return NewBadConnError(ReadTimeoutError)
 	}
 }
 
-func (tc *taosConn) readBytes() ([]byte, error) {
+func (tc *taosConn) readBytes(ctx context.Context) ([]byte, error) {
 	var respBytes []byte
 	var outErr error
 	done := make(chan struct{})
+	cfg := tc.cfg
 	go func() {
 		defer func() {
 			close(done)
@@ -314,22 +770,68 @@ func (tc *taosConn) readBytes() ([]byte, error) {
 			outErr = NewBadConnError(err)
 			return
 		}
+		common.RecordBytes(cfg.metrics, common.DriverTaosWS, common.BytesReceived, len(message))
 		if mt != websocket.BinaryMessage {
-			outErr = NewBadConnErrorWithCtx(fmt.Errorf("readBytes: got wrong message type %d", mt), string(respBytes))
+			outErr = NewBadConnErrorWithCtx(fmt.Errorf("readBytes: got wrong message type %d", mt), formatBytes(message))
 			return
 		}
 		respBytes = message
 	}()
-	ctx, cancel := context.WithTimeout(context.Background(), tc.readTimeout)
+	readCtx, cancel := context.WithTimeout(ctx, tc.readTimeout)
 	defer cancel()
 	select {
 	case <-done:
+		if outErr != nil {
+			atomic.StoreUint32(&tc.bad, 1)
+		}
 		return respBytes, outErr
-	case <-ctx.Done():
+	case <-readCtx.Done():
+		tc.abandon(done, nil)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		tc.log(common.LogLevelWarn, "read timeout, connection abandoned", common.LogKeyEndpoint, tc.addr())
 		return nil, NewBadConnError(ReadTimeoutError)
 	}
 }
 
+// abandon gives up the connection while a response is still in flight.
+// The response will arrive on the socket anyway, so the connection can not be reused:
+// it is marked bad, and once the pending read finishes, the result it created (if any) is freed
+// and the websocket is closed, which also releases everything else the connection holds on the server.
+func (tc *taosConn) abandon(done <-chan struct{}, resultID func() uint64) {
+	atomic.StoreUint32(&tc.bad, 1)
+	ws := tc.client
+	writeTimeout := tc.writeTimeout
+	go func() {
+		<-done
+		if resultID != nil {
+			if id := resultID(); id != 0 {
+				args, _ := jsonI.Marshal(&WSFreeResultReq{ID: id})
+				action, _ := jsonI.Marshal(&WSAction{Action: WSFreeResult, Args: args})
+				ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+				ws.WriteMessage(websocket.TextMessage, action)
+			}
+		}
+		ws.Close()
+	}()
+}
+
+// abandonedResultID returns the result ID carried by a successful response that nobody is waiting for.
+func abandonedResultID(resp interface{}) uint64 {
+	switch r := resp.(type) {
+	case *WSQueryResp:
+		if r.Code == 0 && !r.IsUpdate {
+			return r.ID
+		}
+	case *WSStmtUseResultResp:
+		if r.Code == 0 {
+			return r.ResultID
+		}
+	}
+	return 0
+}
+
 func formatBytes(bs []byte) string {
 	if len(bs) == 0 {
 		return ""
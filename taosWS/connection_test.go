@@ -1,9 +1,21 @@
 package taosWS
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/internal/testutil"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
 )
 
 // @author: xftan
@@ -46,3 +58,360 @@ func Test_formatBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryContextCancel(t *testing.T) {
+	queryReceived := make(chan struct{})
+	release := make(chan struct{})
+	s := wstest.NewServer()
+	defer s.Close()
+	s.HandleQueryFunc("^select 1$", func(string) *wstest.Result {
+		close(queryReceived)
+		<-release
+		return &wstest.Result{
+			Fields: []wstest.Field{{Name: "1", Type: common.TSDB_DATA_TYPE_INT}},
+			Rows:   [][]driver.Value{{int32(1)}},
+		}
+	})
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		<-queryReceived
+		<-ctx.Done()
+		close(release)
+	}()
+	start := time.Now()
+	_, err = db.QueryContext(ctx, "select 1")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	// the result of the abandoned query, the first one the server created, is freed once it arrives
+	assert.Eventually(t, func() bool {
+		for _, req := range s.Requests() {
+			if req.Action == WSFreeResult {
+				var args WSFreeResultReq
+				return json.Unmarshal(req.Args, &args) == nil && args.ID == 1
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond, "abandoned result was not freed")
+}
+
+func TestTLS(t *testing.T) {
+	s := wstest.NewTLSServer()
+	defer s.Close()
+	server := s.HTTPServer()
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(server.Certificate())
+	err := common.RegisterTLSConfig("test_ws_tls", tlsConfig)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer common.DeregisterTLSConfig("test_ws_tls")
+	addr := s.Addr()
+
+	// without the server certificate the handshake fails
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?tls=true", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Error(t, db.Ping())
+	db.Close()
+
+	db, err = sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?tls=test_ws_tls", addr))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	assert.NoError(t, db.Ping())
+	if assert.Len(t, s.Requests(), 1) {
+		assert.Equal(t, WSConnect, s.Requests()[0].Action)
+	}
+
+	_, err = sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?tls=not_registered", addr))
+	assert.Error(t, err)
+}
+
+func TestReqIDFromContext(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	s.HandleQuery("^select \\* from missing", &wstest.Result{Code: 0x2662, Message: "Table does not exist"})
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ctx := context.WithValue(context.Background(), common.ReqIDKey, int64(0x1234))
+	_, err = db.ExecContext(ctx, "create database if not exists test")
+	assert.NoError(t, err)
+	_, err = db.QueryContext(ctx, "select * from missing")
+	var taosErr *taosErrors.TaosError
+	if assert.ErrorAs(t, err, &taosErr) {
+		assert.Equal(t, int32(0x2662), taosErr.Code)
+		assert.Equal(t, int64(0x1234), taosErr.ReqID)
+	}
+	// without a request ID in the context one is generated for every statement
+	_, err = db.Exec("create database if not exists test")
+	assert.NoError(t, err)
+
+	var reqIDs []uint64
+	for _, req := range s.Requests() {
+		if req.Action == WSQuery {
+			reqIDs = append(reqIDs, req.ReqID)
+		}
+	}
+	if assert.Len(t, reqIDs, 3) {
+		assert.Equal(t, []uint64{0x1234, 0x1234}, reqIDs[:2])
+		assert.NotEqual(t, uint64(0), reqIDs[2])
+		assert.NotEqual(t, uint64(0x1234), reqIDs[2])
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testutil.Tracer{}
+	common.SetTracer(tracer)
+	defer common.SetTracer(nil)
+	s := wstest.NewServer()
+	defer s.Close()
+	s.HandleQuery("^insert", &wstest.Result{AffectedRows: 2})
+	s.HandleQuery("^select v from t", &wstest.Result{
+		Fields: []wstest.Field{{Name: "v", Type: common.TSDB_DATA_TYPE_INT}},
+		Rows:   [][]driver.Value{{int32(1)}, {int32(2)}, {int32(3)}},
+	})
+	s.HandleQuery("^select \\* from missing", &wstest.Result{Code: 0x2662, Message: "Table does not exist"})
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ctx := common.WithReqID(context.Background(), 0x1234)
+	_, err = db.ExecContext(ctx, "insert into t values(now, 1)(now+1s, 2)")
+	assert.NoError(t, err)
+	rows, err := db.QueryContext(ctx, "select v from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for rows.Next() {
+	}
+	assert.NoError(t, rows.Close())
+	_, err = db.Query("select * from missing")
+	assert.Error(t, err)
+
+	spans := tracer.Spans()
+	if !assert.Len(t, spans, 3) {
+		return
+	}
+	exec, query, failed := spans[0], spans[1], spans[2]
+	assert.Equal(t, common.OpExec, exec.Operation)
+	assert.True(t, exec.Ended)
+	assert.Equal(t, "insert into t values(now, 1)(now+1s, 2)", exec.Attrs[common.AttrSQL])
+	assert.Equal(t, int64(0x1234), exec.Attrs[common.AttrReqID])
+	assert.Equal(t, s.Addr(), exec.Attrs[common.AttrEndpoint])
+	assert.Equal(t, 2, exec.Attrs[common.AttrAffectedRows])
+
+	assert.Equal(t, common.OpQuery, query.Operation)
+	assert.True(t, query.Ended)
+	assert.NoError(t, query.Err)
+	assert.Equal(t, 3, query.Attrs[common.AttrRows])
+
+	assert.Equal(t, common.OpQuery, failed.Operation)
+	assert.True(t, failed.Ended)
+	assert.Error(t, failed.Err)
+	assert.Equal(t, int32(0x2662), failed.Attrs[common.AttrErrorCode])
+}
+
+func TestMetrics(t *testing.T) {
+	m := &testutil.Metrics{}
+	err := common.RegisterMetrics("taosWS_test", m)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer common.DeregisterMetrics("taosWS_test")
+	s := wstest.NewServer()
+	defer s.Close()
+	s.HandleQuery("^select v from t", &wstest.Result{
+		Fields: []wstest.Field{{Name: "v", Type: common.TSDB_DATA_TYPE_INT}},
+		Rows:   [][]driver.Value{{int32(1)}, {int32(2)}},
+	})
+	s.HandleQuery("^select \\* from missing", &wstest.Result{Code: 0x2662, Message: "Table does not exist"})
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?metrics=taosWS_test", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec("create database if not exists test")
+	assert.NoError(t, err)
+	var count int
+	rows, err := db.Query("select v from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for rows.Next() {
+		count += 1
+	}
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, 2, count)
+	_, err = db.Query("select * from missing")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{
+		"taosWS conn",
+		"taosWS query",
+		"taosWS query",
+		"taosWS fetch",
+		"taosWS fetch_block",
+		"taosWS fetch",
+		"taosWS query",
+	}, m.Requests())
+	assert.Equal(t, 1, m.Errors())
+	assert.True(t, m.Bytes(common.BytesSent) > 0)
+	assert.True(t, m.Bytes(common.BytesReceived) > 0)
+
+	_, err = parseDSN(fmt.Sprintf("root:taosdata@ws(%s)/?metrics=missing", s.Addr()))
+	assert.Error(t, err)
+}
+
+func TestLogger(t *testing.T) {
+	l := &testutil.Logger{}
+	err := common.RegisterLogger("taosWS_test", l)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer common.DeregisterLogger("taosWS_test")
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?logger=taosWS_test&slowQueryThreshold=1ns", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = db.Exec("create database if not exists test")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	assert.Equal(t, []string{
+		"INFO connected",
+		"WARN slow query create database if not exists test",
+		"INFO connection closed",
+	}, l.Entries())
+
+	_, err = parseDSN(fmt.Sprintf("root:taosdata@ws(%s)/?logger=missing", s.Addr()))
+	assert.Error(t, err)
+	_, err = parseDSN(fmt.Sprintf("root:taosdata@ws(%s)/?slowQueryThreshold=1", s.Addr()))
+	assert.Error(t, err)
+}
+
+func TestInterpolateParams(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?timePrecision=ms", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = db.Exec("create table t (ts timestamp, v nchar(32))")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ts := time.Unix(1700000000, 123000000)
+	value := "it's a '?' \\ -- not a comment"
+	_, err = db.Exec("insert into t values(?, ?)", ts, value)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var gotTs time.Time
+	var got string
+	err = db.QueryRow("select * from t").Scan(&gotTs, &got)
+	if assert.NoError(t, err) {
+		assert.True(t, ts.Equal(gotTs), gotTs)
+		assert.Equal(t, value, got)
+	}
+
+	_, err = sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?timePrecision=s", s.Addr()))
+	assert.Error(t, err)
+}
+
+func TestWriteBatch(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	for _, query := range []string{
+		"create stable st (ts timestamp, v int) tags (t1 int)",
+		"create table t1 using st tags(1)",
+	} {
+		_, err = db.Exec(query)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+	count := func(table string) int {
+		var n int
+		assert.NoError(t, db.QueryRow("select count(*) from "+table).Scan(&n))
+		return n
+	}
+
+	tx, err := db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Exec("insert into t1 values(?, ?)", 1700000000000, 1)
+	assert.NoError(t, err)
+	_, err = tx.Exec("insert into t2 using st tags(2) values(@ts, @v)", sql.Named("ts", 1700000000000), sql.Named("v", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count("t1"))
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, 1, count("t1"))
+	assert.Equal(t, 1, count("t2"))
+
+	tx, err = db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Exec("insert into t1 values(1700000001000, 3)")
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+	assert.Equal(t, 1, count("t1"))
+
+	// a query of the transaction runs after the buffered inserts
+	tx, err = db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Exec("insert into t1 values(1700000002000, 4)")
+	assert.NoError(t, err)
+	var n int
+	assert.NoError(t, tx.QueryRow("select count(*) from t1").Scan(&n))
+	assert.Equal(t, 2, n)
+	_, err = tx.Exec("insert into t1 values(1700000003000, 5)")
+	assert.NoError(t, err)
+	stmt, err := tx.Prepare("select * from t1")
+	if assert.NoError(t, err) {
+		rows, err := stmt.Query()
+		if assert.NoError(t, err) {
+			n = 0
+			for rows.Next() {
+				n++
+			}
+			assert.NoError(t, rows.Close())
+			assert.Equal(t, 3, n)
+		}
+		assert.NoError(t, stmt.Close())
+	}
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, 3, count("t1"))
+}
@@ -23,6 +23,17 @@ func (d TDengineDriver) Open(dsn string) (driver.Conn, error) {
 	return c.Connect(context.Background())
 }
 
+// OpenConnector implements driver.DriverContext interface.
+func (d TDengineDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &connector{
+		cfg: cfg,
+	}, nil
+}
+
 func init() {
 	sql.Register("taosWS", &TDengineDriver{})
 }
@@ -25,6 +25,12 @@ func TestParseDsn(t *testing.T) {
 		{dsn: "user:passwd@wss(:0)/?interpolateParams=false&test=1", want: &config{user: "user", passwd: "passwd", net: "wss", params: map[string]string{"test": "1"}}},
 		{dsn: "user:passwd@wss(:0)/?interpolateParams=false&token=token", want: &config{user: "user", passwd: "passwd", net: "wss", token: "token"}},
 		{dsn: "user:passwd@wss(:0)/?writeTimeout=8s&readTimeout=10m", want: &config{user: "user", passwd: "passwd", net: "wss", readTimeout: 10 * time.Minute, writeTimeout: 8 * time.Second, interpolateParams: true}},
+		{dsn: "user:passwd@ws(:0)/?timePrecision=us", want: &config{user: "user", passwd: "passwd", net: "ws", timePrecision: "us", interpolateParams: true}},
+		{dsn: "user:passwd@ws(host1:6041,host2:6042)/dbname", want: &config{user: "user", passwd: "passwd", net: "ws", addr: "host1", port: 6041, addrs: []string{"host1:6041", "host2:6042"}, dbName: "dbname", interpolateParams: true}},
+		{dsn: "user:passwd@ws(host1:,:6042)/?loadBalance=leastLoaded&endpointBackoff=5s", want: &config{user: "user", passwd: "passwd", net: "ws", addr: "host1", port: 6041, addrs: []string{"host1:6041", "127.0.0.1:6042"}, loadBalance: "leastLoaded", endpointBackoff: 5 * time.Second, interpolateParams: true}},
+		{dsn: "user:passwd@ws(host1:6041,host2)/", errs: "invalid DSN: network address not terminated (missing closing brace)"},
+		{dsn: "user:passwd@ws(host1:6041,host2:port)/", errs: "invalid DSN: network port is not a valid number"},
+		{dsn: "user:passwd@ws(host1:6041,host2:6041)/?loadBalance=random", errs: "invalid DSN: loadBalance must be roundRobin or leastLoaded"},
 	}
 	for _, tc := range tests {
 		t.Run(tc.dsn, func(t *testing.T) {
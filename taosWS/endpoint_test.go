@@ -0,0 +1,49 @@
+package taosWS
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
+)
+
+func TestFailover(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	// an address nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	deadAddr := l.Addr().String()
+	l.Close()
+	liveAddr := s.Addr()
+
+	cfg, err := parseDSN("root:taosdata@ws(" + deadAddr + "," + liveAddr + ")/?endpointBackoff=1m")
+	if !assert.NoError(t, err) {
+		return
+	}
+	c := &connector{cfg: cfg}
+	for i := 0; i < 3; i++ {
+		conn, err := c.Connect(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		tc := conn.(*taosConn)
+		assert.Equal(t, liveAddr, tc.poolEndpoint.Addr)
+		assert.NoError(t, tc.Close())
+	}
+	pool := getEndpointPool(cfg)
+	assert.Equal(t, 1, pool.Failures(pool.Endpoints()[0]))
+	assert.Equal(t, 0, pool.Active(pool.Endpoints()[1]))
+	// every connection is opened on the live endpoint
+	var connects int
+	for _, req := range s.Requests() {
+		if req.Action == WSConnect {
+			connects += 1
+		}
+	}
+	assert.Equal(t, 3, connects)
+}
@@ -1,6 +1,10 @@
 package taosWS
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+)
 
 type WSConnectReq struct {
 	ReqID    uint64 `json:"req_id"`
@@ -69,3 +73,110 @@ type WSAction struct {
 	Action string          `json:"action"`
 	Args   json.RawMessage `json:"args"`
 }
+
+type WSStmtInitReq struct {
+	ReqID uint64 `json:"req_id"`
+}
+
+type WSStmtInitResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Action  string `json:"action"`
+	ReqID   uint64 `json:"req_id"`
+	Timing  int64  `json:"timing"`
+	StmtID  uint64 `json:"stmt_id"`
+}
+
+type WSStmtPrepareReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+	SQL    string `json:"sql"`
+}
+
+type WSStmtPrepareResp struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Action   string `json:"action"`
+	ReqID    uint64 `json:"req_id"`
+	Timing   int64  `json:"timing"`
+	StmtID   uint64 `json:"stmt_id"`
+	IsInsert bool   `json:"is_insert"`
+}
+
+type WSStmtGetColFieldsReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type WSStmtGetColFieldsResp struct {
+	Code    int                     `json:"code"`
+	Message string                  `json:"message"`
+	Action  string                  `json:"action"`
+	ReqID   uint64                  `json:"req_id"`
+	Timing  int64                   `json:"timing"`
+	StmtID  uint64                  `json:"stmt_id"`
+	Fields  []*stmtCommon.StmtField `json:"fields"`
+}
+
+type WSStmtBindResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Action  string `json:"action"`
+	ReqID   uint64 `json:"req_id"`
+	Timing  int64  `json:"timing"`
+	StmtID  uint64 `json:"stmt_id"`
+}
+
+type WSStmtAddBatchReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type WSStmtAddBatchResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Action  string `json:"action"`
+	ReqID   uint64 `json:"req_id"`
+	Timing  int64  `json:"timing"`
+	StmtID  uint64 `json:"stmt_id"`
+}
+
+type WSStmtExecReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type WSStmtExecResp struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Action   string `json:"action"`
+	ReqID    uint64 `json:"req_id"`
+	Timing   int64  `json:"timing"`
+	StmtID   uint64 `json:"stmt_id"`
+	Affected int    `json:"affected"`
+}
+
+type WSStmtUseResultReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
+
+type WSStmtUseResultResp struct {
+	Code          int      `json:"code"`
+	Message       string   `json:"message"`
+	Action        string   `json:"action"`
+	ReqID         uint64   `json:"req_id"`
+	Timing        int64    `json:"timing"`
+	StmtID        uint64   `json:"stmt_id"`
+	ResultID      uint64   `json:"result_id"`
+	FieldsCount   int      `json:"fields_count"`
+	FieldsNames   []string `json:"fields_names"`
+	FieldsTypes   []uint8  `json:"fields_types"`
+	FieldsLengths []int64  `json:"fields_lengths"`
+	Precision     int      `json:"precision"`
+}
+
+type WSStmtCloseReq struct {
+	ReqID  uint64 `json:"req_id"`
+	StmtID uint64 `json:"stmt_id"`
+}
@@ -2,10 +2,12 @@ package taosWS
 
 import (
 	"bytes"
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"io"
 	"reflect"
+	"time"
 	"unsafe"
 
 	"github.com/taosdata/driver-go/v3/common"
@@ -15,10 +17,12 @@ import (
 )
 
 type rows struct {
+	ctx           context.Context
 	buf           *bytes.Buffer
 	blockPtr      unsafe.Pointer
 	blockOffset   int
 	blockSize     int
+	reqID         uint64 // request ID of the query, sent with every fetch of the result
 	resultID      uint64
 	block         []byte
 	conn          *taosConn
@@ -27,6 +31,9 @@ type rows struct {
 	fieldsTypes   []uint8
 	fieldsLengths []int64
 	precision     int
+	span          common.Span // the span of the query, ended by Close
+	rowsRead      int
+	err           error // the error that ended Next early
 }
 
 func (rs *rows) Columns() []string {
@@ -52,13 +59,20 @@ func (rs *rows) ColumnTypeScanType(i int) reflect.Type {
 func (rs *rows) Close() error {
 	rs.blockPtr = nil
 	rs.block = nil
-	return rs.freeResult()
+	err := rs.freeResult()
+	if rs.span != nil {
+		rs.span.SetAttributes(common.Attribute{Key: common.AttrRows, Value: rs.rowsRead})
+		common.EndSpan(rs.span, rs.err)
+		rs.span = nil
+	}
+	return err
 }
 
 func (rs *rows) Next(dest []driver.Value) error {
 	if rs.blockPtr == nil {
 		err := rs.taosFetchBlock()
 		if err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -70,6 +84,7 @@ func (rs *rows) Next(dest []driver.Value) error {
 	if rs.blockOffset >= rs.blockSize {
 		err := rs.taosFetchBlock()
 		if err != nil {
+			rs.err = err
 			return err
 		}
 	}
@@ -80,11 +95,22 @@ func (rs *rows) Next(dest []driver.Value) error {
 	}
 	parser.ReadRow(dest, rs.blockPtr, rs.blockSize, rs.blockOffset, rs.fieldsTypes, rs.precision)
 	rs.blockOffset += 1
+	rs.rowsRead += 1
 	return nil
 }
 
 func (rs *rows) taosFetchBlock() error {
-	reqID := rs.conn.generateReqID()
+	err := rs.fetch()
+	if err != nil || rs.blockSize == 0 {
+		return err
+	}
+	return rs.fetchBlock()
+}
+
+// fetch asks for the size of the next block, blockSize is 0 when the result is completed
+func (rs *rows) fetch() (err error) {
+	defer rs.conn.observe(WSFetch, time.Now(), &err)
+	reqID := rs.reqID
 	req := &WSFetchReq{
 		ReqID: reqID,
 		ID:    rs.resultID,
@@ -103,29 +129,29 @@ func (rs *rows) taosFetchBlock() error {
 	if err != nil {
 		return err
 	}
-	err = rs.conn.writeText(rs.buf.Bytes())
+	err = rs.conn.writeText(rs.ctx, rs.buf.Bytes())
 	if err != nil {
 		return err
 	}
 	var resp WSFetchResp
-	err = rs.conn.readTo(&resp)
+	err = rs.conn.readTo(rs.ctx, &resp)
 	if err != nil {
 		return err
 	}
 	if resp.Code != 0 {
-		return taosErrors.NewError(resp.Code, resp.Message)
+		return taosErrors.NewErrorWithReqID(resp.Code, resp.Message, int64(reqID))
 	}
 	if resp.Completed {
 		rs.blockSize = 0
-		return nil
 	} else {
 		rs.blockSize = resp.Rows
-		return rs.fetchBlock()
 	}
+	return nil
 }
 
-func (rs *rows) fetchBlock() error {
-	reqID := rs.conn.generateReqID()
+func (rs *rows) fetchBlock() (err error) {
+	defer rs.conn.observe(WSFetchBlock, time.Now(), &err)
+	reqID := rs.reqID
 	req := &WSFetchBlockReq{
 		ReqID: reqID,
 		ID:    rs.resultID,
@@ -143,11 +169,11 @@ func (rs *rows) fetchBlock() error {
 	if err != nil {
 		return err
 	}
-	err = rs.conn.writeText(rs.buf.Bytes())
+	err = rs.conn.writeText(rs.ctx, rs.buf.Bytes())
 	if err != nil {
 		return err
 	}
-	respBytes, err := rs.conn.readBytes()
+	respBytes, err := rs.conn.readBytes(rs.ctx)
 	if err != nil {
 		return err
 	}
@@ -159,7 +185,11 @@ func (rs *rows) fetchBlock() error {
 
 func (rs *rows) freeResult() error {
 	tc := rs.conn
-	reqID := tc.generateReqID()
+	if tc.isBad() {
+		// the result is released together with the abandoned connection
+		return nil
+	}
+	reqID := rs.reqID
 	req := &WSFreeResultReq{
 		ReqID: reqID,
 		ID:    rs.resultID,
@@ -177,5 +207,5 @@ func (rs *rows) freeResult() error {
 	if err != nil {
 		return err
 	}
-	return nil
+	return tc.writeText(context.Background(), rs.buf.Bytes())
 }
@@ -0,0 +1,285 @@
+package taosWS
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	stmtCommon "github.com/taosdata/driver-go/v3/common/stmt"
+	"github.com/taosdata/driver-go/v3/types"
+)
+
+// Stmt binds parameters through the stmt actions of taosAdapter's /rest/ws endpoint.
+// Like taosSql, the sql can't contain unset table name and tags.
+type Stmt struct {
+	stmtID   uint64
+	conn     *taosConn
+	pSql     string
+	isInsert bool
+	cols     []*stmtCommon.StmtField
+	params   *common.Params // the placeholders of a query prepared with @name, :name or $N, nil for ?
+}
+
+func (stmt *Stmt) Close() error {
+	if stmt.conn == nil || stmt.conn.isBad() {
+		return nil
+	}
+	err := stmt.conn.stmtClose(stmt.stmtID)
+	stmt.conn = nil
+	return err
+}
+
+func (stmt *Stmt) NumInput() int {
+	if stmt.params != nil {
+		// a parameter may be used more than once
+		return -1
+	}
+	if stmt.cols != nil {
+		return len(stmt.cols)
+	}
+	return -1
+}
+
+func (stmt *Stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.ExecContext(context.Background(), common.ValueArgsToNamedValueArgs(args))
+}
+
+// ExecContext implements driver.StmtExecContext interface
+func (stmt *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if stmt.conn != nil {
+		if err := stmt.conn.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+	args, err := stmt.bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.exec(ctx, namedValueToValue(args))
+}
+
+func (stmt *Stmt) exec(ctx context.Context, args []driver.Value) (result driver.Result, err error) {
+	if stmt.conn == nil || stmt.conn.isBad() {
+		return nil, driver.ErrBadConn
+	}
+	// every message of the execution carries the same request ID
+	reqID := getReqID(ctx)
+	ctx = common.WithReqID(ctx, int64(reqID))
+	ctx, span := stmt.conn.startSpan(ctx, common.OpStmtExec, stmt.pSql, reqID)
+	defer func() {
+		common.EndSpan(span, err)
+	}()
+	defer stmt.conn.logSlowQuery(time.Now(), stmt.pSql, reqID)
+	err = stmt.bind(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := stmt.conn.stmtExec(ctx, stmt.stmtID)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: affected})
+	return driver.RowsAffected(affected), nil
+}
+
+func (stmt *Stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.QueryContext(context.Background(), common.ValueArgsToNamedValueArgs(args))
+}
+
+// QueryContext implements driver.StmtQueryContext interface
+func (stmt *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if stmt.conn != nil {
+		if err := stmt.conn.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+	args, err := stmt.bindParams(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.query(ctx, namedValueToValue(args))
+}
+
+func (stmt *Stmt) query(ctx context.Context, args []driver.Value) (driver.Rows, error) {
+	if stmt.conn == nil || stmt.conn.isBad() {
+		return nil, driver.ErrBadConn
+	}
+	reqID := getReqID(ctx)
+	ctx = common.WithReqID(ctx, int64(reqID))
+	ctx, span := stmt.conn.startSpan(ctx, common.OpStmtExec, stmt.pSql, reqID)
+	start := time.Now()
+	rs, err := stmt.useResult(ctx, args)
+	stmt.conn.logSlowQuery(start, stmt.pSql, reqID)
+	if err != nil {
+		common.EndSpan(span, err)
+		return nil, err
+	}
+	// the span ends when the rows are closed
+	rs.span = span
+	return rs, nil
+}
+
+func (stmt *Stmt) useResult(ctx context.Context, args []driver.Value) (*rows, error) {
+	err := stmt.bind(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	_, err = stmt.conn.stmtExec(ctx, stmt.stmtID)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.conn.stmtUseResult(ctx, stmt.stmtID)
+}
+
+func (stmt *Stmt) fetchCols(ctx context.Context) error {
+	if stmt.cols != nil {
+		return nil
+	}
+	cols, err := stmt.conn.stmtGetColFields(ctx, stmt.stmtID)
+	if err != nil {
+		return err
+	}
+	stmt.cols = cols
+	return nil
+}
+
+// bind binds args as one row, the args of an insert are checked against its columns
+func (stmt *Stmt) bind(ctx context.Context, args []driver.Value) error {
+	if stmt.isInsert {
+		err := stmt.fetchCols(ctx)
+		if err != nil {
+			return err
+		}
+		if len(args) != len(stmt.cols) {
+			return fmt.Errorf("stmt exec error: wrong number of parameters")
+		}
+	}
+	if len(args) == 0 {
+		return stmt.conn.stmtAddBatch(ctx, stmt.stmtID)
+	}
+	params := make([]*param.Param, len(args))
+	colTypes := param.NewColumnType(len(args))
+	for i, arg := range args {
+		params[i] = param.NewParam(1).AddValue(arg)
+		if stmt.isInsert {
+			err := addFieldType(colTypes, stmt.cols[i])
+			if err != nil {
+				return err
+			}
+		} else {
+			err := addValueType(colTypes, arg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := stmt.conn.stmtBindParam(ctx, stmt.stmtID, params, colTypes)
+	if err != nil {
+		return err
+	}
+	return stmt.conn.stmtAddBatch(ctx, stmt.stmtID)
+}
+
+func namedValueToValue(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func addFieldType(colTypes *param.ColumnType, field *stmtCommon.StmtField) error {
+	switch field.FieldType {
+	case common.TSDB_DATA_TYPE_BOOL:
+		colTypes.AddBool()
+	case common.TSDB_DATA_TYPE_TINYINT:
+		colTypes.AddTinyint()
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		colTypes.AddSmallint()
+	case common.TSDB_DATA_TYPE_INT:
+		colTypes.AddInt()
+	case common.TSDB_DATA_TYPE_BIGINT:
+		colTypes.AddBigint()
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		colTypes.AddUTinyint()
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		colTypes.AddUSmallint()
+	case common.TSDB_DATA_TYPE_UINT:
+		colTypes.AddUInt()
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		colTypes.AddUBigint()
+	case common.TSDB_DATA_TYPE_FLOAT:
+		colTypes.AddFloat()
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		colTypes.AddDouble()
+	case common.TSDB_DATA_TYPE_BINARY:
+		colTypes.AddBinary(int(field.Bytes))
+	case common.TSDB_DATA_TYPE_VARBINARY:
+		colTypes.AddVarBinary(int(field.Bytes))
+	case common.TSDB_DATA_TYPE_NCHAR:
+		colTypes.AddNchar(int(field.Bytes))
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		colTypes.AddTimestamp()
+	case common.TSDB_DATA_TYPE_JSON:
+		colTypes.AddJson(int(field.Bytes))
+	case common.TSDB_DATA_TYPE_GEOMETRY:
+		colTypes.AddGeometry(int(field.Bytes))
+	default:
+		return fmt.Errorf("unsupported type: %d, name %s", field.FieldType, field.Name)
+	}
+	return nil
+}
+
+func addValueType(colTypes *param.ColumnType, value driver.Value) error {
+	switch v := value.(type) {
+	case nil:
+		colTypes.AddBinary(0)
+	case types.TaosBool:
+		colTypes.AddBool()
+	case types.TaosBigint:
+		colTypes.AddBigint()
+	case types.TaosUBigint:
+		colTypes.AddUBigint()
+	case types.TaosDouble:
+		colTypes.AddDouble()
+	case types.TaosBinary:
+		colTypes.AddBinary(len(v))
+	default:
+		return fmt.Errorf("stmt bind error: unsupported query value %v", value)
+	}
+	return nil
+}
+
+// bindParams orders args as the ? of the prepared query
+func (stmt *Stmt) bindParams(args []driver.NamedValue) ([]driver.NamedValue, error) {
+	if stmt.params == nil {
+		return args, nil
+	}
+	return stmt.params.Bind(args)
+}
+
+// column returns the index of the column bound to v
+func (stmt *Stmt) column(v *driver.NamedValue) int {
+	if stmt.params != nil {
+		return stmt.params.Position(*v)
+	}
+	return v.Ordinal - 1
+}
+
+func (stmt *Stmt) CheckNamedValue(v *driver.NamedValue) error {
+	if !stmt.isInsert {
+		return stmtCommon.ConvertQueryValue(v)
+	}
+	err := stmt.fetchCols(context.Background())
+	if err != nil {
+		return err
+	}
+	col := stmt.column(v)
+	if col < 0 || col >= len(stmt.cols) {
+		return nil
+	}
+	return stmtCommon.ConvertInsertValue(v, stmt.cols[col])
+}
@@ -0,0 +1,229 @@
+package taosWS
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
+)
+
+// stmtTestFields are the columns of the table of TestStmtExec and TestStmtQuery
+var stmtTestFields = []wstest.Field{
+	{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+	{Name: "c1", Type: common.TSDB_DATA_TYPE_BOOL, Length: 1},
+	{Name: "c2", Type: common.TSDB_DATA_TYPE_TINYINT, Length: 1},
+	{Name: "c3", Type: common.TSDB_DATA_TYPE_SMALLINT, Length: 2},
+	{Name: "c4", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+	{Name: "c5", Type: common.TSDB_DATA_TYPE_BIGINT, Length: 8},
+	{Name: "c6", Type: common.TSDB_DATA_TYPE_UTINYINT, Length: 1},
+	{Name: "c7", Type: common.TSDB_DATA_TYPE_USMALLINT, Length: 2},
+	{Name: "c8", Type: common.TSDB_DATA_TYPE_UINT, Length: 4},
+	{Name: "c9", Type: common.TSDB_DATA_TYPE_UBIGINT, Length: 8},
+	{Name: "c10", Type: common.TSDB_DATA_TYPE_FLOAT, Length: 4},
+	{Name: "c11", Type: common.TSDB_DATA_TYPE_DOUBLE, Length: 8},
+	{Name: "c12", Type: common.TSDB_DATA_TYPE_BINARY, Length: 20},
+	{Name: "c13", Type: common.TSDB_DATA_TYPE_NCHAR, Length: 20},
+}
+
+// stmtActions returns the actions of the stmt messages received by s
+func stmtActions(s *wstest.Server) []string {
+	var actions []string
+	for _, req := range s.Requests() {
+		if strings.HasPrefix(req.Action, "stmt_") || req.Action == "bind" {
+			actions = append(actions, req.Action)
+		}
+	}
+	return actions
+}
+
+func TestStmtExec(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	s.CreateTable("ct", stmtTestFields...)
+	stmt, err := db.Prepare("insert into test_ws_stmt_driver.ct values (?,?,?,?,?,?,?,?,?,?,?,?,?,?)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stmt.Close()
+	now := time.Unix(1700000000, 123e6)
+	result, err := stmt.Exec(now, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, "binary", "nchar")
+	if !assert.NoError(t, err) {
+		return
+	}
+	affected, err := result.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+	assert.Equal(t, []string{"stmt_init", "stmt_prepare", "stmt_get_col_fields", "bind", "stmt_add_batch", "stmt_exec"}, stmtActions(s))
+	// the arguments are converted to the types of the columns
+	assert.Equal(t, [][]driver.Value{{
+		now, true, int8(2), int16(3), int32(4), int64(5), uint8(6), uint16(7), uint32(8), uint64(9),
+		float32(10), float64(11), "binary", "nchar",
+	}}, s.Rows("ct"))
+}
+
+func TestStmtQuery(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	s.CreateTable("ct", stmtTestFields...)
+	now := time.Unix(1700000000, 123e6)
+	_, err = db.Exec("insert into test_ws_stmt_driver.ct values (?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		now, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, "binary", "nchar")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the built-in SQL engine has no where clause, the bound statement is answered with the rows of the table
+	var bound string
+	s.HandleQueryFunc(`^select \* from test_ws_stmt_driver\.ct where`, func(sql string) *wstest.Result {
+		bound = sql
+		return &wstest.Result{Fields: stmtTestFields, Rows: s.Rows("ct")}
+	})
+	stmt, err := db.Prepare("select * from test_ws_stmt_driver.ct where ts = ?")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stmt.Close()
+	rows, err := stmt.Query(now)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	assert.Equal(t, "select * from test_ws_stmt_driver.ct where ts = '"+now.Format(time.RFC3339Nano)+"'", bound)
+	assert.Equal(t, []string{"stmt_init", "stmt_prepare", "bind", "stmt_add_batch", "stmt_exec", "stmt_use_result"}, stmtActions(s))
+	columns, err := rows.Columns()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"ts", "c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8", "c9", "c10", "c11", "c12", "c13"}, columns)
+	count := 0
+	for rows.Next() {
+		count += 1
+		var (
+			ts  time.Time
+			c1  bool
+			c2  int8
+			c3  int16
+			c4  int32
+			c5  int64
+			c6  uint8
+			c7  uint16
+			c8  uint32
+			c9  uint64
+			c10 float32
+			c11 float64
+			c12 string
+			c13 string
+		)
+		err = rows.Scan(&ts,
+			&c1,
+			&c2,
+			&c3,
+			&c4,
+			&c5,
+			&c6,
+			&c7,
+			&c8,
+			&c9,
+			&c10,
+			&c11,
+			&c12,
+			&c13)
+		assert.NoError(t, err)
+		assert.Equal(t, now.UnixNano()/1e6, ts.UnixNano()/1e6)
+		assert.Equal(t, true, c1)
+		assert.Equal(t, int8(2), c2)
+		assert.Equal(t, int16(3), c3)
+		assert.Equal(t, int32(4), c4)
+		assert.Equal(t, int64(5), c5)
+		assert.Equal(t, uint8(6), c6)
+		assert.Equal(t, uint16(7), c7)
+		assert.Equal(t, uint32(8), c8)
+		assert.Equal(t, uint64(9), c9)
+		assert.Equal(t, float32(10), c10)
+		assert.Equal(t, float64(11), c11)
+		assert.Equal(t, "binary", c12)
+		assert.Equal(t, "nchar", c13)
+	}
+	assert.NoError(t, rows.Err())
+	assert.Equal(t, 1, count)
+}
+
+func TestStmtNamedParams(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/?interpolateParams=false", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = db.Exec("create table t (ts timestamp, v int, name nchar(32))")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ts := time.Unix(1700000000, 0)
+	_, err = db.Exec("insert into t values(@ts, @v, :name)", sql.Named("name", "a"), sql.Named("v", 1), sql.Named("ts", ts))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = db.Exec("insert into t values($1, $2, $3)", ts.Add(time.Second), 2, "b")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = db.Exec("insert into t values(@ts, @v, :name)", sql.Named("v", 1), sql.Named("ts", ts))
+	assert.EqualError(t, err, "missing argument @name")
+
+	rows, err := db.Query("select * from t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var rowTs time.Time
+		var v int
+		var name string
+		if !assert.NoError(t, rows.Scan(&rowTs, &v, &name)) {
+			return
+		}
+		got = append(got, fmt.Sprintf("%d %d %s", rowTs.Unix(), v, name))
+	}
+	assert.Equal(t, []string{"1700000000 1 a", "1700000001 2 b"}, got)
+}
+
+func TestStmtQueryWrongArgs(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/", s.Addr()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	_, err = db.Exec("create table t (ts timestamp, v int)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	stmt, err := db.Prepare("insert into t values(?, ?)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Query(time.Now(), 1, 2)
+	assert.Error(t, err)
+	_, err = stmt.Exec(time.Now())
+	assert.Error(t, err)
+}
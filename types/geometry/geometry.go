@@ -0,0 +1,140 @@
+// Package geometry converts TDengine GEOMETRY values between WKB (well-known binary) and Go structs.
+package geometry
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Type is the WKB geometry type code.
+type Type uint32
+
+const (
+	PointType              Type = 1
+	LineStringType         Type = 2
+	PolygonType            Type = 3
+	MultiPointType         Type = 4
+	MultiLineStringType    Type = 5
+	MultiPolygonType       Type = 6
+	GeometryCollectionType Type = 7
+)
+
+// Geometry is implemented by Point, LineString, Polygon, MultiPoint, MultiLineString, MultiPolygon and GeometryCollection.
+type Geometry interface {
+	// Type returns the WKB geometry type code.
+	Type() Type
+	// String returns the WKT (well-known text) representation, which can be used in SQL statements.
+	String() string
+	appendWKB(b []byte) []byte
+}
+
+// Point is a two-dimensional point, an empty point has NaN coordinates.
+type Point struct {
+	X float64
+	Y float64
+}
+
+type LineString []Point
+
+// Polygon is a list of linear rings, the first ring is the exterior ring and the others are holes.
+type Polygon []LineString
+
+type MultiPoint []Point
+
+type MultiLineString []LineString
+
+type MultiPolygon []Polygon
+
+type GeometryCollection []Geometry
+
+func (Point) Type() Type              { return PointType }
+func (LineString) Type() Type         { return LineStringType }
+func (Polygon) Type() Type            { return PolygonType }
+func (MultiPoint) Type() Type         { return MultiPointType }
+func (MultiLineString) Type() Type    { return MultiLineStringType }
+func (MultiPolygon) Type() Type       { return MultiPolygonType }
+func (GeometryCollection) Type() Type { return GeometryCollectionType }
+
+func (p Point) isEmpty() bool {
+	return math.IsNaN(p.X) && math.IsNaN(p.Y)
+}
+
+func (p Point) String() string {
+	if p.isEmpty() {
+		return tagged("POINT", "EMPTY")
+	}
+	return tagged("POINT", writePoints([]Point{p}))
+}
+
+func (l LineString) String() string {
+	return tagged("LINESTRING", writePoints(l))
+}
+
+func (p Polygon) String() string {
+	return tagged("POLYGON", writeRings(p))
+}
+
+func (m MultiPoint) String() string {
+	return tagged("MULTIPOINT", writePoints(m))
+}
+
+func (m MultiLineString) String() string {
+	return tagged("MULTILINESTRING", writeRings(m))
+}
+
+func (m MultiPolygon) String() string {
+	if len(m) == 0 {
+		return tagged("MULTIPOLYGON", "EMPTY")
+	}
+	s := make([]string, len(m))
+	for i, polygon := range m {
+		s[i] = writeRings(polygon)
+	}
+	return tagged("MULTIPOLYGON", "("+strings.Join(s, ",")+")")
+}
+
+func (c GeometryCollection) String() string {
+	if len(c) == 0 {
+		return tagged("GEOMETRYCOLLECTION", "EMPTY")
+	}
+	s := make([]string, len(c))
+	for i, g := range c {
+		s[i] = g.String()
+	}
+	return tagged("GEOMETRYCOLLECTION", "("+strings.Join(s, ",")+")")
+}
+
+func tagged(name, body string) string {
+	if body == "EMPTY" {
+		return name + " EMPTY"
+	}
+	return name + body
+}
+
+func writePoints(points []Point) string {
+	if len(points) == 0 {
+		return "EMPTY"
+	}
+	b := []byte{'('}
+	for i, p := range points {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendFloat(b, p.X, 'f', -1, 64)
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, p.Y, 'f', -1, 64)
+	}
+	return string(append(b, ')'))
+}
+
+func writeRings(rings []LineString) string {
+	if len(rings) == 0 {
+		return "EMPTY"
+	}
+	s := make([]string, len(rings))
+	for i, ring := range rings {
+		s[i] = writePoints(ring)
+	}
+	return "(" + strings.Join(s, ",") + ")"
+}
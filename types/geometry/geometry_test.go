@@ -0,0 +1,153 @@
+package geometry
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testGeometries = []struct {
+	name string
+	g    Geometry
+	wkt  string
+}{
+	{
+		name: "point",
+		g:    Point{X: 1, Y: 2},
+		wkt:  "POINT(1 2)",
+	},
+	{
+		name: "empty point",
+		g:    Point{X: math.NaN(), Y: math.NaN()},
+		wkt:  "POINT EMPTY",
+	},
+	{
+		name: "linestring",
+		g:    LineString{{X: 1, Y: 2}, {X: 3.5, Y: -4}},
+		wkt:  "LINESTRING(1 2,3.5 -4)",
+	},
+	{
+		name: "polygon",
+		g:    Polygon{{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 0}}, {{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 2}, {X: 1, Y: 1}}},
+		wkt:  "POLYGON((0 0,4 0,4 4,0 0),(1 1,2 1,2 2,1 1))",
+	},
+	{
+		name: "multipoint",
+		g:    MultiPoint{{X: 1, Y: 2}, {X: 3, Y: 4}},
+		wkt:  "MULTIPOINT(1 2,3 4)",
+	},
+	{
+		name: "multilinestring",
+		g:    MultiLineString{{{X: 1, Y: 2}, {X: 3, Y: 4}}, {{X: 5, Y: 6}, {X: 7, Y: 8}}},
+		wkt:  "MULTILINESTRING((1 2,3 4),(5 6,7 8))",
+	},
+	{
+		name: "multipolygon",
+		g:    MultiPolygon{{{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 0}}}},
+		wkt:  "MULTIPOLYGON(((0 0,1 0,1 1,0 0)))",
+	},
+	{
+		name: "geometrycollection",
+		g:    GeometryCollection{Point{X: 1, Y: 2}, LineString{{X: 1, Y: 2}, {X: 3, Y: 4}}},
+		wkt:  "GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(1 2,3 4))",
+	},
+	{
+		name: "empty linestring",
+		g:    LineString{},
+		wkt:  "LINESTRING EMPTY",
+	},
+	{
+		name: "empty geometrycollection",
+		g:    GeometryCollection{},
+		wkt:  "GEOMETRYCOLLECTION EMPTY",
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range testGeometries {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wkt, tt.g.String())
+			got, err := Unmarshal(Marshal(tt.g))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.g.Type(), got.Type())
+			assert.Equal(t, tt.wkt, got.String())
+		})
+	}
+}
+
+func TestTDengineWKB(t *testing.T) {
+	// select st_geomfromtext('POINT(1 2)')
+	b, _ := hex.DecodeString("0101000000000000000000F03F0000000000000040")
+	assert.Equal(t, b, Marshal(Point{X: 1, Y: 2}))
+	g, err := Unmarshal(b)
+	assert.NoError(t, err)
+	assert.Equal(t, Point{X: 1, Y: 2}, g)
+}
+
+func TestBigEndian(t *testing.T) {
+	b, _ := hex.DecodeString("000000000200000002" + "3FF0000000000000" + "4000000000000000" + "4008000000000000" + "4010000000000000")
+	g, err := Unmarshal(b)
+	assert.NoError(t, err)
+	assert.Equal(t, LineString{{X: 1, Y: 2}, {X: 3, Y: 4}}, g)
+}
+
+func TestUnmarshalError(t *testing.T) {
+	point := Marshal(Point{X: 1, Y: 2})
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{name: "empty", b: nil},
+		{name: "truncated", b: point[:len(point)-1]},
+		{name: "trailing", b: append(append([]byte{}, point...), 0)},
+		{name: "byte order", b: append([]byte{2}, point[1:]...)},
+		{name: "type", b: []byte{1, 8, 0, 0, 0}},
+		{name: "huge count", b: []byte{1, 2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}},
+	}
+	// a MultiPoint holding a LineString
+	mismatch := appendHeader(nil, MultiPointType)
+	mismatch = appendUint32(mismatch, 1)
+	mismatch = LineString{{X: 1, Y: 2}}.appendWKB(mismatch)
+	tests = append(tests, struct {
+		name string
+		b    []byte
+	}{name: "mismatched member", b: mismatch})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.b)
+			assert.True(t, errors.Is(err, ErrInvalidWKB), err)
+		})
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	var p Point
+	v, err := Point{X: 1, Y: 2}.Value()
+	assert.NoError(t, err)
+	assert.NoError(t, p.Scan(v))
+	assert.Equal(t, Point{X: 1, Y: 2}, p)
+	assert.NoError(t, p.Scan(string(v.([]byte))))
+	assert.Equal(t, Point{X: 1, Y: 2}, p)
+
+	var l LineString
+	assert.Error(t, l.Scan(v))
+	assert.Error(t, l.Scan(1))
+
+	var n NullGeometry
+	assert.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	assert.Equal(t, "NULL", n.String())
+	nv, err := n.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, nv)
+
+	assert.NoError(t, n.Scan(Marshal(MultiPoint{{X: 1, Y: 2}})))
+	assert.True(t, n.Valid)
+	assert.Equal(t, MultiPoint{{X: 1, Y: 2}}, n.Inner)
+	nv, err = n.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, Marshal(MultiPoint{{X: 1, Y: 2}}), nv)
+}
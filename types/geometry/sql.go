@@ -0,0 +1,163 @@
+package geometry
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// NullGeometry scans a GEOMETRY column of any geometry type.
+type NullGeometry struct {
+	Inner Geometry
+	Valid bool // Valid is true if Inner is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (n *NullGeometry) Scan(value interface{}) error {
+	if value == nil {
+		n.Inner, n.Valid = nil, false
+		return nil
+	}
+	b, err := wkbOf(value)
+	if err != nil {
+		return err
+	}
+	n.Inner, err = Unmarshal(b)
+	if err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (n NullGeometry) Value() (driver.Value, error) {
+	if !n.Valid || n.Inner == nil {
+		return nil, nil
+	}
+	return Marshal(n.Inner), nil
+}
+
+func (n NullGeometry) String() string {
+	if n.Valid && n.Inner != nil {
+		return n.Inner.String()
+	}
+	return "NULL"
+}
+
+func wkbOf(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("geometry: can not scan %T", value)
+	}
+}
+
+// scanAs decodes value and checks that it is a geometry of type t
+func scanAs(value interface{}, t Type) (Geometry, error) {
+	b, err := wkbOf(value)
+	if err != nil {
+		return nil, err
+	}
+	g, err := Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+	if g.Type() != t {
+		return nil, fmt.Errorf("geometry: can not scan %s into %T", g.String(), g)
+	}
+	return g, nil
+}
+
+// Scan implements the Scanner interface.
+func (p *Point) Scan(value interface{}) error {
+	g, err := scanAs(value, PointType)
+	if err != nil {
+		return err
+	}
+	*p = g.(Point)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (l *LineString) Scan(value interface{}) error {
+	g, err := scanAs(value, LineStringType)
+	if err != nil {
+		return err
+	}
+	*l = g.(LineString)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (p *Polygon) Scan(value interface{}) error {
+	g, err := scanAs(value, PolygonType)
+	if err != nil {
+		return err
+	}
+	*p = g.(Polygon)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (m *MultiPoint) Scan(value interface{}) error {
+	g, err := scanAs(value, MultiPointType)
+	if err != nil {
+		return err
+	}
+	*m = g.(MultiPoint)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (m *MultiLineString) Scan(value interface{}) error {
+	g, err := scanAs(value, MultiLineStringType)
+	if err != nil {
+		return err
+	}
+	*m = g.(MultiLineString)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (m *MultiPolygon) Scan(value interface{}) error {
+	g, err := scanAs(value, MultiPolygonType)
+	if err != nil {
+		return err
+	}
+	*m = g.(MultiPolygon)
+	return nil
+}
+
+// Scan implements the Scanner interface.
+func (c *GeometryCollection) Scan(value interface{}) error {
+	g, err := scanAs(value, GeometryCollectionType)
+	if err != nil {
+		return err
+	}
+	*c = g.(GeometryCollection)
+	return nil
+}
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (p Point) Value() (driver.Value, error) { return Marshal(p), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (l LineString) Value() (driver.Value, error) { return Marshal(l), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (p Polygon) Value() (driver.Value, error) { return Marshal(p), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (m MultiPoint) Value() (driver.Value, error) { return Marshal(m), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (m MultiLineString) Value() (driver.Value, error) { return Marshal(m), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (m MultiPolygon) Value() (driver.Value, error) { return Marshal(m), nil }
+
+// Value implements the driver Valuer interface, the geometry is sent as WKB.
+func (c GeometryCollection) Value() (driver.Value, error) { return Marshal(c), nil }
@@ -0,0 +1,289 @@
+package geometry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	bigEndian    = 0
+	littleEndian = 1
+)
+
+var ErrInvalidWKB = errors.New("invalid WKB")
+
+// Marshal encodes g as little-endian WKB, the format TDengine stores GEOMETRY values in.
+func Marshal(g Geometry) []byte {
+	if g == nil {
+		return nil
+	}
+	return g.appendWKB(nil)
+}
+
+// Unmarshal decodes a WKB geometry in either byte order.
+func Unmarshal(b []byte) (Geometry, error) {
+	d := &decoder{b: b}
+	g, err := d.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(b) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidWKB, len(b)-d.pos)
+	}
+	return g, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
+
+func appendHeader(b []byte, t Type) []byte {
+	b = append(b, littleEndian)
+	return appendUint32(b, uint32(t))
+}
+
+func appendPoints(b []byte, points []Point) []byte {
+	b = appendUint32(b, uint32(len(points)))
+	for _, p := range points {
+		b = appendCoordinates(b, p)
+	}
+	return b
+}
+
+func appendCoordinates(b []byte, p Point) []byte {
+	b = appendUint64(b, math.Float64bits(p.X))
+	return appendUint64(b, math.Float64bits(p.Y))
+}
+
+func (p Point) appendWKB(b []byte) []byte {
+	return appendCoordinates(appendHeader(b, PointType), p)
+}
+
+func (l LineString) appendWKB(b []byte) []byte {
+	return appendPoints(appendHeader(b, LineStringType), l)
+}
+
+func (p Polygon) appendWKB(b []byte) []byte {
+	b = appendHeader(b, PolygonType)
+	b = appendUint32(b, uint32(len(p)))
+	for _, ring := range p {
+		b = appendPoints(b, ring)
+	}
+	return b
+}
+
+func (m MultiPoint) appendWKB(b []byte) []byte {
+	b = appendHeader(b, MultiPointType)
+	b = appendUint32(b, uint32(len(m)))
+	for _, p := range m {
+		b = p.appendWKB(b)
+	}
+	return b
+}
+
+func (m MultiLineString) appendWKB(b []byte) []byte {
+	b = appendHeader(b, MultiLineStringType)
+	b = appendUint32(b, uint32(len(m)))
+	for _, l := range m {
+		b = l.appendWKB(b)
+	}
+	return b
+}
+
+func (m MultiPolygon) appendWKB(b []byte) []byte {
+	b = appendHeader(b, MultiPolygonType)
+	b = appendUint32(b, uint32(len(m)))
+	for _, p := range m {
+		b = p.appendWKB(b)
+	}
+	return b
+}
+
+func (c GeometryCollection) appendWKB(b []byte) []byte {
+	b = appendHeader(b, GeometryCollectionType)
+	b = appendUint32(b, uint32(len(c)))
+	for _, g := range c {
+		b = g.appendWKB(b)
+	}
+	return b
+}
+
+type decoder struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *decoder) need(n int) error {
+	if n < 0 || len(d.b)-d.pos < n {
+		return fmt.Errorf("%w: unexpected end of data", ErrInvalidWKB)
+	}
+	return nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	if err := d.need(4); err != nil {
+		return 0, err
+	}
+	v := d.order.Uint32(d.b[d.pos:])
+	d.pos += 4
+	return v, nil
+}
+
+// count reads an element count, each element takes at least minSize bytes
+func (d *decoder) count(minSize int) (int, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if err = d.need(int(n) * minSize); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (d *decoder) header() (Type, error) {
+	if err := d.need(1); err != nil {
+		return 0, err
+	}
+	switch d.b[d.pos] {
+	case littleEndian:
+		d.order = binary.LittleEndian
+	case bigEndian:
+		d.order = binary.BigEndian
+	default:
+		return 0, fmt.Errorf("%w: byte order %d", ErrInvalidWKB, d.b[d.pos])
+	}
+	d.pos += 1
+	t, err := d.uint32()
+	return Type(t), err
+}
+
+func (d *decoder) coordinates() (Point, error) {
+	if err := d.need(16); err != nil {
+		return Point{}, err
+	}
+	x := math.Float64frombits(d.order.Uint64(d.b[d.pos:]))
+	y := math.Float64frombits(d.order.Uint64(d.b[d.pos+8:]))
+	d.pos += 16
+	return Point{X: x, Y: y}, nil
+}
+
+func (d *decoder) points() ([]Point, error) {
+	n, err := d.count(16)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]Point, n)
+	for i := range points {
+		if points[i], err = d.coordinates(); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (d *decoder) rings() ([]LineString, error) {
+	n, err := d.count(4)
+	if err != nil {
+		return nil, err
+	}
+	rings := make([]LineString, n)
+	for i := range rings {
+		if rings[i], err = d.points(); err != nil {
+			return nil, err
+		}
+	}
+	return rings, nil
+}
+
+// member reads a geometry nested in a multi geometry and checks its type
+func (d *decoder) member(want Type) (Geometry, error) {
+	g, err := d.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if g.Type() != want {
+		return nil, fmt.Errorf("%w: unexpected %d in multi geometry of %d", ErrInvalidWKB, g.Type(), want)
+	}
+	return g, nil
+}
+
+func (d *decoder) geometry() (Geometry, error) {
+	t, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case PointType:
+		return d.coordinates()
+	case LineStringType:
+		points, err := d.points()
+		return LineString(points), err
+	case PolygonType:
+		rings, err := d.rings()
+		return Polygon(rings), err
+	case MultiPointType:
+		n, err := d.count(21)
+		if err != nil {
+			return nil, err
+		}
+		m := make(MultiPoint, n)
+		for i := range m {
+			g, err := d.member(PointType)
+			if err != nil {
+				return nil, err
+			}
+			m[i] = g.(Point)
+		}
+		return m, nil
+	case MultiLineStringType:
+		n, err := d.count(9)
+		if err != nil {
+			return nil, err
+		}
+		m := make(MultiLineString, n)
+		for i := range m {
+			g, err := d.member(LineStringType)
+			if err != nil {
+				return nil, err
+			}
+			m[i] = g.(LineString)
+		}
+		return m, nil
+	case MultiPolygonType:
+		n, err := d.count(9)
+		if err != nil {
+			return nil, err
+		}
+		m := make(MultiPolygon, n)
+		for i := range m {
+			g, err := d.member(PolygonType)
+			if err != nil {
+				return nil, err
+			}
+			m[i] = g.(Polygon)
+		}
+		return m, nil
+	case GeometryCollectionType:
+		n, err := d.count(5)
+		if err != nil {
+			return nil, err
+		}
+		c := make(GeometryCollection, n)
+		for i := range c {
+			if c[i], err = d.geometry(); err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported geometry type %d", ErrInvalidWKB, t)
+	}
+}
@@ -97,10 +97,11 @@ func (c *Client) ReadPump() {
 	for {
 		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
-			if e, ok := err.(*websocket.CloseError); ok && e.Code == websocket.CloseAbnormalClosure {
-				break
+			// errors caused by Close are expected, a connection dropped by the server
+			// (including abnormal closure) must be reported
+			if c.IsRunning() {
+				c.handleError(err)
 			}
-			c.handleError(err)
 			break
 		}
 		switch messageType {
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ReqID returns the req_id of a JSON response of taosAdapter. When the response can not be decoded the error
+// is returned with the req_id read before it, the response is then still handed to the request waiting for it
+// so that the request fails with the error instead of waiting for its timeout.
+func ReqID(message []byte) (reqID uint64, err error) {
+	iter := JsonI.BorrowIterator(message)
+	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
+		switch s {
+		case "req_id":
+			reqID = iter.ReadUint64()
+			return false
+		default:
+			iter.Skip()
+		}
+		return iter.Error == nil
+	})
+	err = iter.Error
+	JsonI.ReturnIterator(iter)
+	if err == io.EOF {
+		err = nil
+	}
+	return reqID, err
+}
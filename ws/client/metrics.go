@@ -0,0 +1,28 @@
+package client
+
+import (
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+)
+
+// RecordResponse reports a request of action sent at start to metrics, or to the global Metrics when metrics is nil.
+// The request failed when err is set or when resp is a JSON response with a non-zero code.
+func RecordResponse(metrics common.Metrics, driver string, action string, start time.Time, resp []byte, err error) {
+	if metrics == nil {
+		metrics = common.GetMetrics()
+		if metrics == nil {
+			return
+		}
+	}
+	if err == nil {
+		metrics.AddBytes(driver, common.BytesReceived, len(resp))
+		if len(resp) != 0 && resp[0] == '{' {
+			if code := JsonI.Get(resp, "code").ToInt(); code != 0 {
+				err = taosErrors.NewError(code, JsonI.Get(resp, "message").ToString())
+			}
+		}
+	}
+	metrics.ObserveRequest(driver, action, time.Since(start), err)
+}
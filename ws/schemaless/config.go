@@ -2,6 +2,8 @@ package schemaless
 
 import (
 	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
 )
 
 const (
@@ -18,6 +20,9 @@ type Config struct {
 	readTimeout  time.Duration
 	writeTimeout time.Duration
 	errorHandler func(error)
+	tls          string
+	metrics      common.Metrics
+	logger       common.Logger
 }
 
 func NewConfig(url string, chanLength uint, opts ...func(*Config)) *Config {
@@ -64,3 +69,25 @@ func SetErrorHandler(errorHandler func(error)) func(*Config) {
 		c.errorHandler = errorHandler
 	}
 }
+
+// SetTLS sets the TLS configuration, name is "true", "skip-verify" or a name registered with common.RegisterTLSConfig.
+// A ws:// url is dialed as wss:// when TLS is set.
+func SetTLS(name string) func(*Config) {
+	return func(c *Config) {
+		c.tls = name
+	}
+}
+
+// SetMetrics sets the Metrics of this schemaless connection, the one installed with common.SetMetrics is used when it is not set.
+func SetMetrics(metrics common.Metrics) func(*Config) {
+	return func(c *Config) {
+		c.metrics = metrics
+	}
+}
+
+// SetLogger sets the Logger of this schemaless connection, the one installed with common.SetLogger is used when it is not set.
+func SetLogger(logger common.Logger) func(*Config) {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/ws/client"
@@ -26,6 +25,7 @@ type Schemaless struct {
 	client       *client.Client
 	sendList     *list.List
 	url          string
+	addr         string // host:port of taosAdapter, reported to the tracer
 	user         string
 	password     string
 	db           string
@@ -34,6 +34,8 @@ type Schemaless struct {
 	once         sync.Once
 	closeChan    chan struct{}
 	errorHandler func(error)
+	metrics      common.Metrics
+	logger       common.Logger
 }
 
 func NewSchemaless(config *Config) (*Schemaless, error) {
@@ -44,10 +46,15 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 	if wsUrl.Scheme != "ws" && wsUrl.Scheme != "wss" {
 		return nil, errors.New("config url scheme error")
 	}
+	tlsConfig, err := common.GetTLSConfig(config.tls)
+	if err != nil {
+		return nil, fmt.Errorf("config tls error: %s", err)
+	}
+	wsUrl.Scheme = common.TLSScheme(wsUrl.Scheme, tlsConfig)
 	if len(wsUrl.Path) == 0 || wsUrl.Path != "/rest/schemaless" {
 		wsUrl.Path = "/rest/schemaless"
 	}
-	ws, _, err := common.DefaultDialer.Dial(wsUrl.String(), nil)
+	ws, _, err := common.NewDialer(tlsConfig).Dial(wsUrl.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("dial ws error: %s", err)
 	}
@@ -56,11 +63,14 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 		client:       client.NewClient(ws, config.chanLength),
 		sendList:     list.New(),
 		url:          config.url,
+		addr:         wsUrl.Host,
 		user:         config.user,
 		password:     config.password,
 		db:           config.db,
 		closeChan:    make(chan struct{}),
 		errorHandler: config.errorHandler,
+		metrics:      config.metrics,
+		logger:       config.logger,
 	}
 
 	if config.readTimeout > 0 {
@@ -77,16 +87,29 @@ func NewSchemaless(config *Config) (*Schemaless, error) {
 	go s.client.WritePump()
 
 	if err = s.connect(); err != nil {
+		s.log(common.LogLevelWarn, "connect failed", common.LogKeyEndpoint, s.addr, common.LogKeyError, err)
 		return nil, fmt.Errorf("connect ws error: %s", err)
 	}
+	s.log(common.LogLevelInfo, "connected", common.LogKeyEndpoint, s.addr)
 
 	return &s, nil
 }
 
-func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl int, reqID int64) error {
+// log reports the connection state and dropped responses to the logger given by SetLogger
+func (s *Schemaless) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(s.logger, level, msg, keyvals...)
+}
+
+func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl int, reqID int64) (err error) {
 	if reqID == 0 {
 		reqID = common.GetReqID()
 	}
+	_, span := common.StartSpan(context.Background(), common.OpSchemaless,
+		common.Attribute{Key: common.AttrReqID, Value: reqID},
+		common.Attribute{Key: common.AttrEndpoint, Value: s.addr},
+		common.Attribute{Key: common.AttrSchemalessProtocol, Value: protocol},
+	)
+	defer func() { common.EndSpan(span, err) }()
 	req := &schemalessReq{
 		ReqID:     uint64(reqID),
 		DB:        s.db,
@@ -107,7 +130,7 @@ func (s *Schemaless) Insert(lines string, protocol int, precision string, ttl in
 		s.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.sendText(uint64(reqID), envelope)
+	respBytes, err := s.sendText(insertAction, uint64(reqID), envelope)
 	if err != nil {
 		return err
 	}
@@ -129,6 +152,7 @@ func (s *Schemaless) Close() {
 			s.client.Close()
 		}
 		s.client = nil
+		s.log(common.LogLevelInfo, "connection closed", common.LogKeyEndpoint, s.addr)
 	})
 }
 
@@ -155,7 +179,7 @@ func (s *Schemaless) connect() error {
 		return err
 	}
 
-	respBytes, err := s.sendText(reqID, envelope)
+	respBytes, err := s.sendText(connAction, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -170,12 +194,17 @@ func (s *Schemaless) connect() error {
 	return nil
 }
 
-func (s *Schemaless) sendText(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+func (s *Schemaless) sendText(action string, reqID uint64, envelope *client.Envelope) ([]byte, error) {
 	envelope.Type = websocket.TextMessage
-	return s.send(reqID, envelope)
+	return s.send(action, reqID, envelope)
 }
 
-func (s *Schemaless) send(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+func (s *Schemaless) send(action string, reqID uint64, envelope *client.Envelope) (resp []byte, err error) {
+	start := time.Now()
+	common.RecordBytes(s.metrics, common.DriverSchemaless, common.BytesSent, envelope.Msg.Len())
+	defer func() {
+		client.RecordResponse(s.metrics, common.DriverSchemaless, action, start, resp, err)
+	}()
 	channel := &IndexedChan{
 		index:   reqID,
 		channel: make(chan []byte, 1),
@@ -210,19 +239,10 @@ func (s *Schemaless) addMessageOutChan(outChan *IndexedChan) *list.Element {
 }
 
 func (s *Schemaless) handleTextMessage(message []byte) {
-	iter := client.JsonI.BorrowIterator(message)
-	var reqID uint64
-	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
-		switch s {
-		case "req_id":
-			reqID = iter.ReadUint64()
-			return false
-		default:
-			iter.Skip()
-		}
-		return iter.Error == nil
-	})
-	client.JsonI.ReturnIterator(iter)
+	reqID, err := client.ReqID(message)
+	if err != nil {
+		s.log(common.LogLevelError, "decode response failed", common.LogKeyReqID, reqID, common.LogKeyError, err)
+	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -230,6 +250,8 @@ func (s *Schemaless) handleTextMessage(message []byte) {
 	if element != nil {
 		element.Value.(*IndexedChan).channel <- message
 		s.sendList.Remove(element)
+	} else {
+		s.log(common.LogLevelWarn, "response to unknown request dropped", common.LogKeyReqID, reqID)
 	}
 }
 
@@ -255,6 +277,7 @@ func (s *Schemaless) findOutChanByID(index uint64) *list.Element {
 }
 
 func (s *Schemaless) handleError(err error) {
+	s.log(common.LogLevelError, "connection lost", common.LogKeyEndpoint, s.addr, common.LogKeyError, err)
 	if s.errorHandler != nil {
 		s.errorHandler(err)
 	}
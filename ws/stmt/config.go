@@ -1,8 +1,11 @@
 package stmt
 
 import (
+	"crypto/tls"
 	"errors"
 	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
 )
 
 type Config struct {
@@ -15,6 +18,9 @@ type Config struct {
 	User           string
 	Password       string
 	DB             string
+	TLSConfig      *tls.Config
+	Metrics        common.Metrics // metrics of this connector, the global one when nil
+	Logger         common.Logger  // logger of this connector, the global one when nil
 }
 
 func NewConfig(url string, chanLength uint) *Config {
@@ -53,6 +59,17 @@ func (c *Config) SetWriteWait(writeWait time.Duration) error {
 	return nil
 }
 
+// SetTLS sets the TLS configuration, name is "true", "skip-verify" or a name registered with common.RegisterTLSConfig.
+// A ws:// url is dialed as wss:// when TLS is set.
+func (c *Config) SetTLS(name string) error {
+	tlsConfig, err := common.GetTLSConfig(name)
+	if err != nil {
+		return err
+	}
+	c.TLSConfig = tlsConfig
+	return nil
+}
+
 func (c *Config) SetErrorHandler(f func(connector *Connector, err error)) {
 	c.ErrorHandler = f
 }
@@ -5,12 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
 	"github.com/taosdata/driver-go/v3/ws/client"
@@ -18,6 +18,7 @@ import (
 
 type Connector struct {
 	client             *client.Client
+	addr               string // host:port of taosAdapter, reported to the tracer
 	requestID          uint64
 	listLock           sync.RWMutex
 	sendChanList       *list.List
@@ -44,7 +45,12 @@ func NewConnector(config *Config) (*Connector, error) {
 	if config.WriteWait > 0 {
 		writeTimeout = config.WriteWait
 	}
-	ws, _, err := common.DefaultDialer.Dial(config.Url, nil)
+	connectUrl, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	connectUrl.Scheme = common.TLSScheme(connectUrl.Scheme, config.TLSConfig)
+	ws, _, err := common.NewDialer(config.TLSConfig).Dial(connectUrl.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -108,6 +114,7 @@ func NewConnector(config *Config) (*Connector, error) {
 	wsClient.WriteWait = writeTimeout
 	connector = &Connector{
 		client:             wsClient,
+		addr:               connectUrl.Host,
 		requestID:          0,
 		listLock:           sync.RWMutex{},
 		sendChanList:       list.New(),
@@ -124,23 +131,20 @@ func NewConnector(config *Config) (*Connector, error) {
 	wsClient.ErrorHandler = connector.handleError
 	go wsClient.WritePump()
 	go wsClient.ReadPump()
+	connector.log(common.LogLevelInfo, "connected", common.LogKeyEndpoint, connector.addr)
 	return connector, nil
 }
 
+// log reports the connection state and dropped responses to config.Logger
+func (c *Connector) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(c.config.Logger, level, msg, keyvals...)
+}
+
 func (c *Connector) handleTextMessage(message []byte) {
-	iter := client.JsonI.BorrowIterator(message)
-	var reqID uint64
-	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
-		switch s {
-		case "req_id":
-			reqID = iter.ReadUint64()
-			return false
-		default:
-			iter.Skip()
-		}
-		return iter.Error == nil
-	})
-	client.JsonI.ReturnIterator(iter)
+	reqID, err := client.ReqID(message)
+	if err != nil {
+		c.log(common.LogLevelError, "decode response failed", common.LogKeyReqID, reqID, common.LogKeyError, err)
+	}
 	c.listLock.Lock()
 	element := c.findOutChanByID(reqID)
 	if element != nil {
@@ -148,6 +152,9 @@ func (c *Connector) handleTextMessage(message []byte) {
 		c.sendChanList.Remove(element)
 	}
 	c.listLock.Unlock()
+	if element == nil {
+		c.log(common.LogLevelWarn, "response to unknown request dropped", common.LogKeyReqID, reqID)
+	}
 }
 
 type IndexedChan struct {
@@ -155,15 +162,20 @@ type IndexedChan struct {
 	channel chan []byte
 }
 
-func (c *Connector) sendText(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+func (c *Connector) sendText(action string, reqID uint64, envelope *client.Envelope) ([]byte, error) {
 	envelope.Type = websocket.TextMessage
-	return c.send(reqID, envelope)
+	return c.send(action, reqID, envelope)
 }
-func (c *Connector) sendBinary(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+func (c *Connector) sendBinary(action string, reqID uint64, envelope *client.Envelope) ([]byte, error) {
 	envelope.Type = websocket.BinaryMessage
-	return c.send(reqID, envelope)
+	return c.send(action, reqID, envelope)
 }
-func (c *Connector) send(reqID uint64, envelope *client.Envelope) ([]byte, error) {
+func (c *Connector) send(action string, reqID uint64, envelope *client.Envelope) (resp []byte, err error) {
+	start := time.Now()
+	common.RecordBytes(c.config.Metrics, common.DriverStmt, common.BytesSent, envelope.Msg.Len())
+	defer func() {
+		client.RecordResponse(c.config.Metrics, common.DriverStmt, action, start, resp, err)
+	}()
 	channel := &IndexedChan{
 		index:   reqID,
 		channel: make(chan []byte, 1),
@@ -219,6 +231,7 @@ func (c *Connector) addMessageOutChan(outChan *IndexedChan) *list.Element {
 }
 
 func (c *Connector) handleError(err error) {
+	c.log(common.LogLevelError, "connection lost", common.LogKeyEndpoint, c.addr, common.LogKeyError, err)
 	if c.customErrorHandler != nil {
 		c.customErrorHandler(c, err)
 	}
@@ -248,7 +261,7 @@ func (c *Connector) Init() (*Stmt, error) {
 		c.client.PutEnvelope(envelope)
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(STMTInit, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
@@ -270,6 +283,7 @@ func (c *Connector) Close() error {
 	c.closeOnce.Do(func() {
 		close(c.closeChan)
 		c.client.Close()
+		c.log(common.LogLevelInfo, "connection closed", common.LogKeyEndpoint, c.addr)
 		if c.customCloseHandler != nil {
 			c.customCloseHandler()
 		}
@@ -15,6 +15,9 @@ const (
 	STMTAddBatch     = "add_batch"
 	STMTExec         = "exec"
 	STMTClose        = "close"
+	// STMTSetTags and STMTBind name the binary set tags and bind messages reported to common.Metrics
+	STMTSetTags = "set_tags"
+	STMTBind    = "bind"
 )
 
 type ConnectReq struct {
@@ -1,8 +1,11 @@
 package stmt
 
 import (
+	"context"
 	"encoding/binary"
+	"fmt"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/param"
 	"github.com/taosdata/driver-go/v3/common/serializer"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
@@ -12,15 +15,25 @@ import (
 type Stmt struct {
 	connector    *Connector
 	id           uint64
+	sql          string
 	lastAffected int
+	params       *common.Params
 }
 
+// Prepare prepares sql, its placeholders are either all ? or @name, :name and $N placeholders, the table name
+// of an insert may be ? in both cases. With @name and :name placeholders SetTags and BindParam each take a value
+// or column per distinct name of the tags or the values in order of first appearance, with $N placeholders one
+// per distinct $N in order of N. A parameter may be used more than once.
 func (s *Stmt) Prepare(sql string) error {
+	rewritten, params, err := common.ParseParams(sql)
+	if err != nil {
+		return err
+	}
 	reqID := s.connector.generateReqID()
 	req := &PrepareReq{
 		ReqID:  reqID,
 		StmtID: s.id,
-		SQL:    sql,
+		SQL:    rewritten,
 	}
 	args, err := client.JsonI.Marshal(req)
 	if err != nil {
@@ -36,7 +49,7 @@ func (s *Stmt) Prepare(sql string) error {
 		s.connector.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.connector.sendText(reqID, envelope)
+	respBytes, err := s.connector.sendText(STMTPrepare, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -48,6 +61,8 @@ func (s *Stmt) Prepare(sql string) error {
 	if resp.Code != 0 {
 		return taosErrors.NewError(resp.Code, resp.Message)
 	}
+	s.sql = sql
+	s.params = params
 	return nil
 }
 
@@ -72,7 +87,7 @@ func (s *Stmt) SetTableName(name string) error {
 		s.connector.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.connector.sendText(reqID, envelope)
+	respBytes, err := s.connector.sendText(STMTSetTableName, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -88,6 +103,16 @@ func (s *Stmt) SetTableName(name string) error {
 }
 
 func (s *Stmt) SetTags(tags *param.Param, bindType *param.ColumnType) error {
+	if s.params != nil {
+		if n := len(tags.GetValues()); n != s.params.Tags.NumArgs {
+			return fmt.Errorf("tag count error : expect %d got %d", s.params.Tags.NumArgs, n)
+		}
+		tags = tags.Select(s.params.Tags.Index)
+		var err error
+		if bindType, err = bindType.Select(s.params.Tags.Index); err != nil {
+			return err
+		}
+	}
 	tagValues := tags.GetValues()
 	reverseTags := make([]*param.Param, len(tagValues))
 	for i := 0; i < len(tagValues); i++ {
@@ -106,7 +131,7 @@ func (s *Stmt) SetTags(tags *param.Param, bindType *param.ColumnType) error {
 	envelope.Msg.Grow(24 + len(block))
 	envelope.Msg.Write(reqData)
 	envelope.Msg.Write(block)
-	respBytes, err := s.connector.sendBinary(reqID, envelope)
+	respBytes, err := s.connector.sendBinary(STMTSetTags, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -122,6 +147,20 @@ func (s *Stmt) SetTags(tags *param.Param, bindType *param.ColumnType) error {
 }
 
 func (s *Stmt) BindParam(params []*param.Param, bindType *param.ColumnType) error {
+	if s.params != nil {
+		if len(params) != s.params.Values.NumArgs {
+			return fmt.Errorf("param count error : expect %d got %d", s.params.Values.NumArgs, len(params))
+		}
+		columns := make([]*param.Param, len(s.params.Values.Index))
+		for i, index := range s.params.Values.Index {
+			columns[i] = params[index]
+		}
+		var err error
+		if bindType, err = bindType.Select(s.params.Values.Index); err != nil {
+			return err
+		}
+		params = columns
+	}
 	block, err := serializer.SerializeRawBlock(params, bindType)
 	if err != nil {
 		return err
@@ -140,7 +179,7 @@ func (s *Stmt) BindParam(params []*param.Param, bindType *param.ColumnType) erro
 		s.connector.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.connector.sendBinary(reqID, envelope)
+	respBytes, err := s.connector.sendBinary(STMTBind, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -175,7 +214,7 @@ func (s *Stmt) AddBatch() error {
 		s.connector.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.connector.sendText(reqID, envelope)
+	respBytes, err := s.connector.sendText(STMTAddBatch, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -190,8 +229,19 @@ func (s *Stmt) AddBatch() error {
 	return nil
 }
 
-func (s *Stmt) Exec() error {
+func (s *Stmt) Exec() (err error) {
 	reqID := s.connector.generateReqID()
+	_, span := common.StartSpan(context.Background(), common.OpStmtExec,
+		common.Attribute{Key: common.AttrSQL, Value: s.sql},
+		common.Attribute{Key: common.AttrReqID, Value: int64(reqID)},
+		common.Attribute{Key: common.AttrEndpoint, Value: s.connector.addr},
+	)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(common.Attribute{Key: common.AttrAffectedRows, Value: s.lastAffected})
+		}
+		common.EndSpan(span, err)
+	}()
 	req := &ExecReq{
 		ReqID:  reqID,
 		StmtID: s.id,
@@ -210,7 +260,7 @@ func (s *Stmt) Exec() error {
 		s.connector.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := s.connector.sendText(reqID, envelope)
+	respBytes, err := s.connector.sendText(STMTExec, reqID, envelope)
 	if err != nil {
 		return err
 	}
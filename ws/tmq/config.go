@@ -1,10 +1,12 @@
 package tmq
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/tmq"
 )
 
@@ -22,6 +24,13 @@ type config struct {
 	AutoCommitIntervalMS string
 	SnapshotEnable       string
 	WithTableName        string
+	AutoReconnect        bool
+	ReconnectIntervalMs  int
+	ReconnectRetryCount  int
+	TLSConfig            *tls.Config
+	Metrics              common.Metrics
+	Logger               common.Logger
+	LagIntervalMs        int
 }
 
 func newConfig(url string, chanLength uint) *config {
@@ -138,3 +147,78 @@ func (c *config) setWithTableName(withTableName tmq.ConfigValue) error {
 	}
 	return nil
 }
+
+func (c *config) setAutoReconnect(autoReconnect tmq.ConfigValue) error {
+	var ok bool
+	c.AutoReconnect, ok = autoReconnect.(bool)
+	if !ok {
+		return fmt.Errorf("ws.autoReconnect requires bool got %T", autoReconnect)
+	}
+	return nil
+}
+
+func (c *config) setReconnectIntervalMs(reconnectIntervalMs tmq.ConfigValue) error {
+	var ok bool
+	c.ReconnectIntervalMs, ok = reconnectIntervalMs.(int)
+	if !ok {
+		return fmt.Errorf("ws.reconnectIntervalMs requires int got %T", reconnectIntervalMs)
+	}
+	if c.ReconnectIntervalMs < 0 {
+		return errors.New("ws.reconnectIntervalMs cannot be less than 0")
+	}
+	return nil
+}
+
+func (c *config) setReconnectRetryCount(reconnectRetryCount tmq.ConfigValue) error {
+	var ok bool
+	c.ReconnectRetryCount, ok = reconnectRetryCount.(int)
+	if !ok {
+		return fmt.Errorf("ws.reconnectRetryCount requires int got %T", reconnectRetryCount)
+	}
+	if c.ReconnectRetryCount < 1 {
+		return errors.New("ws.reconnectRetryCount cannot be less than 1")
+	}
+	return nil
+}
+
+func (c *config) setTLS(name tmq.ConfigValue) error {
+	tlsName, ok := name.(string)
+	if !ok {
+		return fmt.Errorf("ws.tls requires string got %T", name)
+	}
+	var err error
+	c.TLSConfig, err = common.GetTLSConfig(tlsName)
+	return err
+}
+
+func (c *config) setMetrics(metrics tmq.ConfigValue) error {
+	switch m := metrics.(type) {
+	case nil:
+		return nil
+	case string:
+		var err error
+		c.Metrics, err = common.GetRegisteredMetrics(m)
+		return err
+	case common.Metrics:
+		c.Metrics = m
+		return nil
+	default:
+		return fmt.Errorf("ws.metrics requires string or common.Metrics got %T", metrics)
+	}
+}
+
+func (c *config) setLagInterval(lagInterval tmq.ConfigValue) error {
+	value, ok := lagInterval.(string)
+	if !ok {
+		return fmt.Errorf("%s requires string got %T", tmq.LagIntervalKey, lagInterval)
+	}
+	var err error
+	c.LagIntervalMs, err = tmq.ParseLagInterval(value)
+	return err
+}
+
+func (c *config) setLogger(logger tmq.ConfigValue) error {
+	var err error
+	c.Logger, err = tmq.ParseLogger("ws.logger", logger)
+	return err
+}
@@ -2,17 +2,16 @@ package tmq
 
 import (
 	"container/list"
-	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"github.com/gorilla/websocket"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/parser"
 	"github.com/taosdata/driver-go/v3/common/tmq"
@@ -41,6 +40,30 @@ type Consumer struct {
 	closeOnce            sync.Once
 	closeChan            chan struct{}
 	topics               []string
+	chanLength           uint
+	writeWait            time.Duration
+	connLock             sync.RWMutex
+	brokenChan           chan struct{}
+	autoReconnect        bool
+	dialer               *websocket.Dialer
+	reconnectInterval    time.Duration
+	reconnectRetryCount  int
+	offsetLock           sync.Mutex
+	deliveredOffsets     map[topicVgroup]tmq.Offset
+	metrics              common.Metrics
+	logger               common.Logger
+	rebalanceCb          RebalanceCb
+	assignment           tmq.AssignmentTracker
+	lagInterval          time.Duration
+	lastLag              time.Time
+}
+
+// assignmentCheckInterval is how often Poll checks the assignment for the RebalanceCb
+var assignmentCheckInterval = time.Second
+
+type topicVgroup struct {
+	topic    string
+	vgroupID int32
 }
 
 type IndexedChan struct {
@@ -63,13 +86,15 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 	if err != nil {
 		return nil, err
 	}
-	ws, _, err := common.DefaultDialer.Dial(config.Url, nil)
-	if err != nil {
-		return nil, err
+	if config.TLSConfig != nil {
+		wsUrl, err := url.Parse(config.Url)
+		if err != nil {
+			return nil, err
+		}
+		wsUrl.Scheme = common.TLSScheme(wsUrl.Scheme, config.TLSConfig)
+		config.Url = wsUrl.String()
 	}
-	wsClient := client.NewClient(ws, config.ChanLength)
 	tmq := &Consumer{
-		client:               wsClient,
 		requestID:            0,
 		sendChanList:         list.New(),
 		messageTimeout:       config.MessageTimeout,
@@ -84,16 +109,54 @@ func NewConsumer(conf *tmq.ConfigMap) (*Consumer, error) {
 		snapshotEnable:       config.SnapshotEnable,
 		withTableName:        config.WithTableName,
 		closeChan:            make(chan struct{}),
+		chanLength:           config.ChanLength,
+		writeWait:            config.WriteWait,
+		autoReconnect:        config.AutoReconnect,
+		reconnectInterval:    time.Duration(config.ReconnectIntervalMs) * time.Millisecond,
+		reconnectRetryCount:  config.ReconnectRetryCount,
+		deliveredOffsets:     make(map[topicVgroup]tmq.Offset),
+		dialer:               common.NewDialer(config.TLSConfig),
+		metrics:              config.Metrics,
+		logger:               config.Logger,
+		lagInterval:          time.Duration(config.LagIntervalMs) * time.Millisecond,
+	}
+	err = tmq.connect()
+	if err != nil {
+		tmq.log(common.LogLevelWarn, "connect failed", common.LogKeyEndpoint, tmq.url, common.LogKeyError, err)
+		return nil, err
 	}
-	if config.WriteWait > 0 {
-		wsClient.WriteWait = config.WriteWait
+	tmq.log(common.LogLevelInfo, "connected", common.LogKeyEndpoint, tmq.url)
+	return tmq, nil
+}
+
+// log reports reconnects and the failed assignment, lag and rebalance checks to the ws.logger of the consumer
+func (c *Consumer) log(level common.LogLevel, msg string, keyvals ...interface{}) {
+	common.Log(c.logger, level, msg, keyvals...)
+}
+
+// connect dials ws.url and replaces the websocket client of the consumer
+func (c *Consumer) connect() error {
+	ws, _, err := c.dialer.Dial(c.url, nil)
+	if err != nil {
+		return err
+	}
+	wsClient := client.NewClient(ws, c.chanLength)
+	if c.writeWait > 0 {
+		wsClient.WriteWait = c.writeWait
+	}
+	wsClient.BinaryMessageHandler = c.handleBinaryMessage
+	wsClient.TextMessageHandler = c.handleTextMessage
+	wsClient.ErrorHandler = func(err error) {
+		c.handleError(wsClient, err)
 	}
-	wsClient.BinaryMessageHandler = tmq.handleBinaryMessage
-	wsClient.TextMessageHandler = tmq.handleTextMessage
-	wsClient.ErrorHandler = tmq.handleError
+	c.connLock.Lock()
+	c.client = wsClient
+	c.brokenChan = make(chan struct{})
+	c.err = nil
+	c.connLock.Unlock()
 	go wsClient.WritePump()
 	go wsClient.ReadPump()
-	return tmq, nil
+	return nil
 }
 
 func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
@@ -153,6 +216,34 @@ func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	if err != nil {
 		return nil, err
 	}
+	autoReconnect, err := m.Get("ws.autoReconnect", true)
+	if err != nil {
+		return nil, err
+	}
+	reconnectIntervalMs, err := m.Get("ws.reconnectIntervalMs", 2000)
+	if err != nil {
+		return nil, err
+	}
+	reconnectRetryCount, err := m.Get("ws.reconnectRetryCount", 3)
+	if err != nil {
+		return nil, err
+	}
+	tlsName, err := m.Get("ws.tls", "")
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := m.Get("ws.metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := m.Get("ws.logger", nil)
+	if err != nil {
+		return nil, err
+	}
+	lagInterval, err := m.Get(tmq.LagIntervalKey, "")
+	if err != nil {
+		return nil, err
+	}
 	config := newConfig(url.(string), chanLen.(uint))
 	err = config.setMessageTimeout(messageTimeout.(time.Duration))
 	if err != nil {
@@ -198,34 +289,52 @@ func configMapToConfig(m *tmq.ConfigMap) (*config, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = config.setAutoReconnect(autoReconnect)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setReconnectIntervalMs(reconnectIntervalMs)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setReconnectRetryCount(reconnectRetryCount)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setTLS(tlsName)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setLogger(logger)
+	if err != nil {
+		return nil, err
+	}
+	err = config.setLagInterval(lagInterval)
+	if err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
 func (c *Consumer) handleTextMessage(message []byte) {
-	iter := client.JsonI.BorrowIterator(message)
-	var reqID uint64
-	iter.ReadObjectCB(func(iter *jsoniter.Iterator, s string) bool {
-		switch s {
-		case "req_id":
-			reqID = iter.ReadUint64()
-			return false
-		default:
-			iter.Skip()
-		}
-		return iter.Error == nil
-	})
-	client.JsonI.ReturnIterator(iter)
-	c.listLock.Lock()
-	element := c.findOutChanByID(reqID)
-	if element != nil {
-		element.Value.(*IndexedChan).channel <- message
-		c.sendChanList.Remove(element)
+	reqID, err := client.ReqID(message)
+	if err != nil {
+		c.log(common.LogLevelError, "decode response failed", common.LogKeyReqID, reqID, common.LogKeyError, err)
 	}
-	c.listLock.Unlock()
+	c.deliver(reqID, message)
 }
 
 func (c *Consumer) handleBinaryMessage(message []byte) {
 	reqID := binary.LittleEndian.Uint64(message[8:16])
+	c.deliver(reqID, message)
+}
+
+// deliver hands message to the request waiting for reqID
+func (c *Consumer) deliver(reqID uint64, message []byte) {
 	c.listLock.Lock()
 	element := c.findOutChanByID(reqID)
 	if element != nil {
@@ -233,13 +342,36 @@ func (c *Consumer) handleBinaryMessage(message []byte) {
 		c.sendChanList.Remove(element)
 	}
 	c.listLock.Unlock()
+	if element == nil {
+		c.log(common.LogLevelWarn, "response to unknown request dropped", common.LogKeyReqID, reqID)
+	}
 }
 
-func (c *Consumer) handleError(err error) {
+func (c *Consumer) handleError(wsClient *client.Client, err error) {
+	c.connLock.Lock()
+	if wsClient != c.client {
+		// error from a connection that has already been replaced
+		c.connLock.Unlock()
+		return
+	}
 	c.err = &WSError{err: err}
+	close(c.brokenChan)
+	c.connLock.Unlock()
+	c.log(common.LogLevelError, "connection lost", common.LogKeyEndpoint, c.url, common.LogKeyError, err)
+	if c.autoReconnect {
+		// keep the consumer open, the next Poll reestablishes the connection
+		wsClient.Close()
+		return
+	}
 	c.Close()
 }
 
+func (c *Consumer) getErr() error {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+	return c.err
+}
+
 func (c *Consumer) generateReqID() uint64 {
 	return atomic.AddUint64(&c.requestID, 1)
 }
@@ -248,7 +380,11 @@ func (c *Consumer) generateReqID() uint64 {
 func (c *Consumer) Close() error {
 	c.closeOnce.Do(func() {
 		close(c.closeChan)
-		c.client.Close()
+		c.connLock.RLock()
+		wsClient := c.client
+		c.connLock.RUnlock()
+		wsClient.Close()
+		c.log(common.LogLevelInfo, "consumer closed", common.LogKeyEndpoint, c.url)
 	})
 	return nil
 }
@@ -298,43 +434,122 @@ const (
 )
 
 var ClosedErr = errors.New("connection closed")
+var MessageTimeoutErr = errors.New("message timeout")
 
-func (c *Consumer) sendText(reqID uint64, envelope *client.Envelope) ([]byte, error) {
-	if !c.client.IsRunning() {
-		c.client.PutEnvelope(envelope)
-		return nil, ClosedErr
-	}
-	channel := &IndexedChan{
+func (c *Consumer) sendText(action string, reqID uint64, envelope *client.Envelope) ([]byte, error) {
+	return c.wait(c.send(action, reqID, envelope))
+}
+
+// pendingResponse is a request sent to the server, wait reads its response
+type pendingResponse struct {
+	action   string
+	start    time.Time
+	envelope *client.Envelope
+	channel  *IndexedChan
+	element  *list.Element
+	broken   chan struct{}
+	err      error
+}
+
+// send sends a text request without waiting for the response, so that several requests can be in flight
+func (c *Consumer) send(action string, reqID uint64, envelope *client.Envelope) *pendingResponse {
+	p := &pendingResponse{action: action, start: time.Now(), envelope: envelope}
+	c.connLock.RLock()
+	wsClient, broken := c.client, c.brokenChan
+	c.connLock.RUnlock()
+	if !wsClient.IsRunning() {
+		wsClient.PutEnvelope(envelope)
+		p.err = ClosedErr
+		return p
+	}
+	common.RecordBytes(c.metrics, common.DriverTMQ, common.BytesSent, envelope.Msg.Len())
+	p.broken = broken
+	p.channel = &IndexedChan{
 		index:   reqID,
 		channel: make(chan []byte, 1),
 	}
-	element := c.addMessageOutChan(channel)
+	p.element = c.addMessageOutChan(p.channel)
 	envelope.Type = websocket.TextMessage
-	c.client.Send(envelope)
-	ctx, cancel := context.WithTimeout(context.Background(), c.messageTimeout)
-	defer cancel()
+	wsClient.Send(envelope)
+	return p
+}
+
+// request encodes req as the args of action and sends it, see send
+func (c *Consumer) request(action string, reqID uint64, req interface{}) *pendingResponse {
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return &pendingResponse{action: action, start: time.Now(), err: err}
+	}
+	wsAction := &client.WSAction{
+		Action: action,
+		Args:   args,
+	}
+	envelope := c.client.GetEnvelope()
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(wsAction)
+	if err != nil {
+		c.client.PutEnvelope(envelope)
+		return &pendingResponse{action: action, start: time.Now(), err: err}
+	}
+	return c.send(action, reqID, envelope)
+}
+
+// wait waits for the response to a request for ws.message.timeout since it was sent
+func (c *Consumer) wait(p *pendingResponse) (resp []byte, err error) {
+	defer func() {
+		client.RecordResponse(c.metrics, common.DriverTMQ, p.action, p.start, resp, err)
+	}()
+	if p.err != nil {
+		return nil, p.err
+	}
+	timer := time.NewTimer(c.messageTimeout - time.Since(p.start))
+	defer timer.Stop()
 	select {
 	case <-c.closeChan:
 		return nil, ClosedErr
-	case resp := <-channel.channel:
+	case <-p.broken:
+		c.discard(p)
+		return nil, ClosedErr
+	case resp := <-p.channel.channel:
 		return resp, nil
-	case <-ctx.Done():
-		c.listLock.Lock()
-		c.sendChanList.Remove(element)
-		c.listLock.Unlock()
-		return nil, fmt.Errorf("message timeout :%s", envelope.Msg.String())
+	case <-timer.C:
+		c.discard(p)
+		return nil, fmt.Errorf("%w :%s", MessageTimeoutErr, p.envelope.Msg.String())
 	}
 }
 
+// discard stops waiting for the response to a request
+func (c *Consumer) discard(p *pendingResponse) {
+	if p.element == nil {
+		return
+	}
+	c.listLock.Lock()
+	c.sendChanList.Remove(p.element)
+	c.listLock.Unlock()
+}
+
+// RebalanceCb is called by Poll with a tmq.AssignedPartitions or tmq.RevokedPartitions event when the vgroups
+// assigned to the consumer change, and by Unsubscribe with the vgroups it is about to lose.
 type RebalanceCb func(*Consumer, tmq.Event) error
 
 func (c *Consumer) Subscribe(topic string, rebalanceCb RebalanceCb) error {
 	return c.SubscribeTopics([]string{topic}, rebalanceCb)
 }
 
+// SubscribeTopics subscribes to topics, rebalanceCb is called when the assignment changes, it may be nil.
 func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) error {
-	if c.err != nil {
-		return c.err
+	err := c.subscribe(topics)
+	if err != nil {
+		return err
+	}
+	c.rebalanceCb = rebalanceCb
+	c.assignment.Expire()
+	c.lastLag = time.Now()
+	return nil
+}
+
+func (c *Consumer) subscribe(topics []string) error {
+	if err := c.getErr(); err != nil {
+		return err
 	}
 	reqID := c.generateReqID()
 	req := &SubscribeReq{
@@ -364,7 +579,7 @@ func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) err
 		c.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQSubscribe, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -383,31 +598,96 @@ func (c *Consumer) SubscribeTopics(topics []string, rebalanceCb RebalanceCb) err
 
 // Poll messages
 func (c *Consumer) Poll(timeoutMs int) tmq.Event {
-	if c.err != nil {
-		panic(c.err)
-	}
-	reqID := c.generateReqID()
-	req := &PollReq{
-		ReqID:        reqID,
-		BlockingTime: int64(timeoutMs),
+	return c.readPoll(c.startPoll(timeoutMs), nil)
+}
+
+// PollBatch polls up to maxMessages messages within timeout, it returns early when no message arrives in time
+// or with a tmq.Error as the last event. The poll of the next message is sent as soon as every request reading
+// the current one is answered, the server then looks for the next message while the current one is decoded.
+// It is not sent earlier, a poll lets the server release the message read so far.
+func (c *Consumer) PollBatch(maxMessages int, timeout time.Duration) []tmq.Event {
+	if maxMessages <= 0 {
+		return nil
 	}
-	args, err := client.JsonI.Marshal(req)
-	if err != nil {
-		return tmq.NewTMQErrorWithErr(err)
+	deadline := time.Now().Add(timeout)
+	remainingMs := func() int {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			return 0
+		}
+		return int(remaining / time.Millisecond)
+	}
+	var events []tmq.Event
+	p := c.startPoll(remainingMs())
+	for p != nil {
+		var next *polling
+		ev := c.readPoll(p, func() {
+			if len(events)+1 < maxMessages && time.Now().Before(deadline) {
+				next = c.startPoll(remainingMs())
+			}
+		})
+		// next is only started once a message is read, so a nil event or an error ends the batch without a poll in flight
+		if ev == nil {
+			break
+		}
+		events = append(events, ev)
+		if _, ok := ev.(tmq.Error); ok {
+			break
+		}
+		if next == nil && len(events) < maxMessages && time.Now().Before(deadline) {
+			next = c.startPoll(remainingMs())
+		}
+		p = next
 	}
-	action := &client.WSAction{
-		Action: TMQPoll,
-		Args:   args,
+	return events
+}
+
+// polling is a poll started by startPoll
+type polling struct {
+	span    common.Span
+	ev      tmq.Event        // the event returned instead of polling, such as a reconnection or a consumer lag
+	pending *pendingResponse // the poll request when ev is nil
+}
+
+// startPoll sends a poll request without waiting for the response, readPoll reads the message it returns.
+// A broken connection, a rebalance and a due consumer lag are handled first.
+func (c *Consumer) startPoll(timeoutMs int) *polling {
+	p := &polling{span: tmq.StartPollSpan()}
+	if err := c.getErr(); err != nil {
+		if !c.autoReconnect {
+			p.ev = tmq.NewFatalTMQError(err)
+		} else {
+			p.ev = c.reconnect(err)
+		}
+		return p
 	}
-	envelope := c.client.GetEnvelope()
-	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
-	if err != nil {
-		c.client.PutEnvelope(envelope)
-		return tmq.NewTMQErrorWithErr(err)
+	c.checkAssignment()
+	if p.ev = c.lagEvent(); p.ev != nil {
+		return p
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	reqID := c.generateReqID()
+	p.pending = c.request(TMQPoll, reqID, &PollReq{
+		ReqID:        reqID,
+		BlockingTime: int64(timeoutMs),
+	})
+	return p
+}
+
+// readPoll returns the event of a poll started by startPoll. beforeDecode, when not nil, is called once
+// the message is read and every request reading it is answered, before its blocks are decoded.
+func (c *Consumer) readPoll(p *polling, beforeDecode func()) tmq.Event {
+	ev := p.ev
+	if p.pending != nil {
+		ev = c.readMessage(p.pending, beforeDecode)
+	}
+	tmq.EndPollSpan(p.span, ev)
+	return ev
+}
+
+func (c *Consumer) readMessage(pending *pendingResponse, beforeDecode func()) tmq.Event {
+	respBytes, err := c.wait(pending)
 	if err != nil {
-		return tmq.NewTMQErrorWithErr(err)
+		return c.pollError(err)
 	}
 	var resp PollResp
 	err = client.JsonI.Unmarshal(respBytes, &resp)
@@ -415,98 +695,222 @@ func (c *Consumer) Poll(timeoutMs int) tmq.Event {
 		return tmq.NewTMQErrorWithErr(err)
 	}
 	if resp.Code != 0 {
-		panic(taosErrors.NewError(resp.Code, resp.Message))
+		return tmq.NewTMQError(resp.Code, resp.Message)
 	}
 	c.latestMessageID = resp.MessageID
-	if resp.HaveMessage {
-		switch resp.MessageType {
-		case common.TMQ_RES_DATA:
-			result := &tmq.DataMessage{}
-			result.SetDbName(resp.Database)
-			result.SetTopic(resp.Topic)
-			result.SetOffset(tmq.Offset(resp.Offset))
-			data, err := c.fetch(resp.MessageID)
-			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
-			}
-			result.SetData(data)
-			topic := resp.Topic
-			result.TopicPartition = tmq.TopicPartition{
-				Topic:     &topic,
-				Partition: resp.VgroupID,
-				Offset:    tmq.Offset(resp.Offset),
-			}
-			return result
-		case common.TMQ_RES_TABLE_META:
-			result := &tmq.MetaMessage{}
-			result.SetDbName(resp.Database)
-			result.SetTopic(resp.Topic)
-			result.SetOffset(tmq.Offset(resp.Offset))
-			meta, err := c.fetchJsonMeta(resp.MessageID)
-			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
-			}
-			topic := resp.Topic
-			result.TopicPartition = tmq.TopicPartition{
-				Topic:     &topic,
-				Partition: resp.VgroupID,
-				Offset:    tmq.Offset(resp.Offset),
-			}
-			result.SetMeta(meta)
-			return result
-		case common.TMQ_RES_METADATA:
-			result := &tmq.MetaDataMessage{}
-			result.SetDbName(resp.Database)
-			result.SetTopic(resp.Topic)
-			result.SetOffset(tmq.Offset(resp.Offset))
-			meta, err := c.fetchJsonMeta(resp.MessageID)
-			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
-			}
-			data, err := c.fetch(resp.MessageID)
-			if err != nil {
-				return tmq.NewTMQErrorWithErr(err)
-			}
-			result.SetMetaData(&tmq.MetaData{
-				Meta: meta,
-				Data: data,
-			})
-			topic := resp.Topic
-			result.TopicPartition = tmq.TopicPartition{
-				Topic:     &topic,
-				Partition: resp.VgroupID,
-				Offset:    tmq.Offset(resp.Offset),
+	if !resp.HaveMessage {
+		return nil
+	}
+	c.recordOffset(resp.Topic, resp.VgroupID, tmq.Offset(resp.Offset))
+	var meta *tmq.Meta
+	var blocks []*rawBlock
+	switch resp.MessageType {
+	case common.TMQ_RES_DATA:
+		blocks, err = c.fetch(resp.MessageID)
+	case common.TMQ_RES_TABLE_META:
+		meta, err = c.fetchJsonMeta(resp.MessageID)
+	case common.TMQ_RES_METADATA:
+		meta, err = c.fetchJsonMeta(resp.MessageID)
+		if err == nil {
+			blocks, err = c.fetch(resp.MessageID)
+		}
+	default:
+		return tmq.NewTMQError(tmq.ErrorOther, "invalid tmq message type")
+	}
+	if err != nil {
+		return c.pollError(err)
+	}
+	if beforeDecode != nil {
+		beforeDecode()
+	}
+	topic := resp.Topic
+	partition := tmq.TopicPartition{
+		Topic:     &topic,
+		Partition: resp.VgroupID,
+		Offset:    tmq.Offset(resp.Offset),
+	}
+	switch resp.MessageType {
+	case common.TMQ_RES_DATA:
+		result := &tmq.DataMessage{}
+		result.SetDbName(resp.Database)
+		result.SetTopic(resp.Topic)
+		result.SetOffset(tmq.Offset(resp.Offset))
+		result.SetData(decodeBlocks(blocks))
+		result.TopicPartition = partition
+		return result
+	case common.TMQ_RES_TABLE_META:
+		result := &tmq.MetaMessage{}
+		result.SetDbName(resp.Database)
+		result.SetTopic(resp.Topic)
+		result.SetOffset(tmq.Offset(resp.Offset))
+		result.SetMeta(meta)
+		result.TopicPartition = partition
+		return result
+	default:
+		result := &tmq.MetaDataMessage{}
+		result.SetDbName(resp.Database)
+		result.SetTopic(resp.Topic)
+		result.SetOffset(tmq.Offset(resp.Offset))
+		result.SetMetaData(&tmq.MetaData{
+			Meta: meta,
+			Data: decodeBlocks(blocks),
+		})
+		result.TopicPartition = partition
+		return result
+	}
+}
+
+// pollError converts an error raised while polling into a tmq.Error. A broken connection is retriable
+// when automatic reconnection is enabled and fatal otherwise, a message timeout is always retriable.
+func (c *Consumer) pollError(err error) tmq.Error {
+	var wsErr *WSError
+	if errors.Is(err, ClosedErr) || errors.As(err, &wsErr) {
+		select {
+		case <-c.closeChan:
+			return tmq.NewFatalTMQError(err)
+		default:
+		}
+		if c.autoReconnect {
+			return tmq.NewRetriableTMQError(err)
+		}
+		return tmq.NewFatalTMQError(err)
+	}
+	if errors.Is(err, MessageTimeoutErr) {
+		return tmq.NewRetriableTMQError(err)
+	}
+	return tmq.NewTMQErrorWithErr(err)
+}
+
+// reconnect redials ws.url, subscribes to the remembered topics again and seeks every vgroup back to
+// the offset last delivered by Poll (or set by Seek), the other vgroups resume from the committed offset.
+// On success a tmq.Reconnected event is returned, otherwise the consumer stays broken and the next Poll retries.
+func (c *Consumer) reconnect(cause error) tmq.Event {
+	// err is the reason of each attempt, the lost connection then the failed dial
+	err := cause
+	for i := 0; i < c.reconnectRetryCount; i++ {
+		if i > 0 {
+			select {
+			case <-c.closeChan:
+				return tmq.NewFatalTMQError(ClosedErr)
+			case <-time.After(c.reconnectInterval):
 			}
-			return result
+		}
+		select {
+		case <-c.closeChan:
+			return tmq.NewFatalTMQError(ClosedErr)
 		default:
-			return tmq.NewTMQErrorWithErr(err)
 		}
-	} else {
+		c.log(common.LogLevelWarn, "reconnecting", common.LogKeyEndpoint, c.url, common.LogKeyAttempt, i+1, common.LogKeyError, err)
+		err = c.connect()
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		c.log(common.LogLevelError, "reconnect failed", common.LogKeyEndpoint, c.url, common.LogKeyError, err)
+		return tmq.NewRetriableTMQError(err)
+	}
+	c.log(common.LogLevelInfo, "reconnected", common.LogKeyEndpoint, c.url)
+	common.RecordReconnect(c.metrics, common.DriverTMQ)
+	reconnected := tmq.Reconnected{Cause: cause}
+	if len(c.topics) == 0 {
+		return reconnected
+	}
+	partitions, err := c.restoreSession()
+	if err != nil {
+		c.connLock.RLock()
+		wsClient := c.client
+		c.connLock.RUnlock()
+		c.log(common.LogLevelError, "restore session failed", common.LogKeyEndpoint, c.url, common.LogKeyError, err)
+		c.handleError(wsClient, err)
+		return tmq.NewRetriableTMQError(err)
+	}
+	reconnected.Partitions = partitions
+	// the vgroups may have been reassigned while the consumer was away
+	c.assignment.Expire()
+	return reconnected
+}
+
+// checkAssignment calls the RebalanceCb with the vgroups revoked and assigned since the last check
+func (c *Consumer) checkAssignment() {
+	if c.rebalanceCb == nil || !c.assignment.Due(assignmentCheckInterval) {
+		return
+	}
+	partitions, err := c.Assignment()
+	if err != nil {
+		c.log(common.LogLevelWarn, "get assignment failed", common.LogKeyError, err)
+		return
+	}
+	assigned, revoked := c.assignment.Update(partitions)
+	if len(revoked) != 0 {
+		c.callRebalanceCb(tmq.RevokedPartitions{Partitions: revoked})
+	}
+	if len(assigned) != 0 {
+		c.callRebalanceCb(tmq.AssignedPartitions{Partitions: assigned})
+	}
+}
+
+// lagEvent returns a tmq.ConsumerLag when lag.interval.ms has passed since the last one, nil otherwise
+func (c *Consumer) lagEvent() tmq.Event {
+	if c.lagInterval <= 0 || len(c.topics) == 0 || time.Since(c.lastLag) < c.lagInterval {
 		return nil
 	}
+	partitions, err := c.Lag()
+	c.lastLag = time.Now()
+	if err != nil {
+		c.log(common.LogLevelWarn, "get lag failed", common.LogKeyError, err)
+		return nil
+	}
+	return tmq.ConsumerLag{Partitions: partitions}
 }
 
-func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
-	reqID := c.generateReqID()
-	req := &FetchJsonMetaReq{
-		ReqID:     reqID,
-		MessageID: messageID,
+func (c *Consumer) callRebalanceCb(ev tmq.Event) {
+	if err := c.rebalanceCb(c, ev); err != nil {
+		c.log(common.LogLevelWarn, "rebalance callback failed", common.LogKeyError, err)
 	}
-	args, err := client.JsonI.Marshal(req)
+}
+
+func (c *Consumer) restoreSession() ([]tmq.TopicPartition, error) {
+	err := c.subscribe(c.topics)
 	if err != nil {
 		return nil, err
 	}
-	action := &client.WSAction{
-		Action: TMQFetchJsonMeta,
-		Args:   args,
-	}
-	envelope := c.client.GetEnvelope()
-	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	partitions, err := c.Assignment()
 	if err != nil {
-		c.client.PutEnvelope(envelope)
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	c.offsetLock.Lock()
+	delivered := make(map[topicVgroup]tmq.Offset, len(c.deliveredOffsets))
+	for k, v := range c.deliveredOffsets {
+		delivered[k] = v
+	}
+	c.offsetLock.Unlock()
+	for i := 0; i < len(partitions); i++ {
+		offset, exist := delivered[topicVgroup{topic: *partitions[i].Topic, vgroupID: partitions[i].Partition}]
+		if !exist {
+			continue
+		}
+		partitions[i].Offset = offset
+		err = c.Seek(partitions[i], 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return partitions, nil
+}
+
+func (c *Consumer) recordOffset(topic string, vgroupID int32, offset tmq.Offset) {
+	c.offsetLock.Lock()
+	c.deliveredOffsets[topicVgroup{topic: topic, vgroupID: vgroupID}] = offset
+	c.offsetLock.Unlock()
+}
+
+func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
+	reqID := c.generateReqID()
+	respBytes, err := c.wait(c.request(TMQFetchJsonMeta, reqID, &FetchJsonMetaReq{
+		ReqID:     reqID,
+		MessageID: messageID,
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -526,77 +930,61 @@ func (c *Consumer) fetchJsonMeta(messageID uint64) (*tmq.Meta, error) {
 	return &meta, nil
 }
 
-func (c *Consumer) fetch(messageID uint64) ([]*tmq.Data, error) {
-	var tmqData []*tmq.Data
+// rawBlock is a block of a message as read by fetch, decodeBlocks decodes it
+type rawBlock struct {
+	resp  FetchResp
+	bytes []byte // the fetch_block response, the raw block starts at byte 24
+}
+
+// fetch reads the blocks of a message. fetch moves the server to the next block of the message and fetch_block
+// returns the current one, so each request is sent once the previous one is answered.
+func (c *Consumer) fetch(messageID uint64) ([]*rawBlock, error) {
+	var blocks []*rawBlock
 	for {
 		reqID := c.generateReqID()
-		req := &FetchReq{
+		respBytes, err := c.wait(c.request(TMQFetch, reqID, &FetchReq{
 			ReqID:     reqID,
 			MessageID: messageID,
-		}
-		args, err := client.JsonI.Marshal(req)
+		}))
 		if err != nil {
 			return nil, err
 		}
-		action := &client.WSAction{
-			Action: TMQFetch,
-			Args:   args,
-		}
-		envelope := c.client.GetEnvelope()
-		err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+		block := &rawBlock{}
+		err = client.JsonI.Unmarshal(respBytes, &block.resp)
 		if err != nil {
-			c.client.PutEnvelope(envelope)
 			return nil, err
 		}
-		respBytes, err := c.sendText(reqID, envelope)
-		if err != nil {
-			return nil, err
+		if block.resp.Code != 0 {
+			return nil, taosErrors.NewError(block.resp.Code, block.resp.Message)
 		}
-		var resp FetchResp
-		err = client.JsonI.Unmarshal(respBytes, &resp)
+		if block.resp.Completed {
+			return blocks, nil
+		}
+		reqID = c.generateReqID()
+		block.bytes, err = c.wait(c.request(TMQFetchBlock, reqID, &FetchBlockReq{
+			ReqID:     reqID,
+			MessageID: messageID,
+		}))
 		if err != nil {
 			return nil, err
 		}
-		if resp.Code != 0 {
-			return nil, taosErrors.NewError(resp.Code, resp.Message)
-		}
-		if resp.Completed {
-			break
-		}
-		// fetch block
-		{
-			req := &FetchBlockReq{
-				ReqID:     reqID,
-				MessageID: messageID,
-			}
-			args, err := client.JsonI.Marshal(req)
-			if err != nil {
-				return nil, err
-			}
-			action := &client.WSAction{
-				Action: TMQFetchBlock,
-				Args:   args,
-			}
-			envelope := c.client.GetEnvelope()
-			err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
-			if err != nil {
-				c.client.PutEnvelope(envelope)
-				return nil, err
-			}
-			respBytes, err := c.sendText(reqID, envelope)
-			if err != nil {
-				return nil, err
-			}
-			block := respBytes[24:]
-			p := unsafe.Pointer(&block[0])
-			data := parser.ReadBlock(p, resp.Rows, resp.FieldsTypes, resp.Precision)
-			tmqData = append(tmqData, &tmq.Data{
-				TableName: resp.TableName,
-				Data:      data,
-			})
-		}
+		blocks = append(blocks, block)
 	}
-	return tmqData, nil
+}
+
+func decodeBlocks(blocks []*rawBlock) []*tmq.Data {
+	var tmqData []*tmq.Data
+	for _, block := range blocks {
+		resp := &block.resp
+		data := parser.ReadBlock(unsafe.Pointer(&block.bytes[24]), resp.Rows, resp.FieldsTypes, resp.Precision)
+		tmqData = append(tmqData, &tmq.Data{
+			TableName: resp.TableName,
+			Data:      data,
+			Fields:    tmq.NewFields(resp.FieldsNames, resp.FieldsTypes, resp.FieldsLengths),
+			Precision: resp.Precision,
+		})
+	}
+	return tmqData
 }
 
 func (c *Consumer) Commit() ([]tmq.TopicPartition, error) {
@@ -604,8 +992,8 @@ func (c *Consumer) Commit() ([]tmq.TopicPartition, error) {
 }
 
 func (c *Consumer) doCommit(messageID uint64) ([]tmq.TopicPartition, error) {
-	if c.err != nil {
-		return nil, c.err
+	if err := c.getErr(); err != nil {
+		return nil, err
 	}
 	reqID := c.generateReqID()
 	req := &CommitReq{
@@ -626,7 +1014,7 @@ func (c *Consumer) doCommit(messageID uint64) ([]tmq.TopicPartition, error) {
 		c.client.PutEnvelope(envelope)
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQCommit, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
@@ -645,9 +1033,15 @@ func (c *Consumer) doCommit(messageID uint64) ([]tmq.TopicPartition, error) {
 	return c.Committed(partitions, 0)
 }
 
+// Unsubscribe unsubscribes from all topics, the RebalanceCb is called first with the vgroups assigned so far
+// so that their offsets can still be committed.
 func (c *Consumer) Unsubscribe() error {
-	if c.err != nil {
-		return c.err
+	if err := c.getErr(); err != nil {
+		return err
+	}
+	revoked := c.assignment.Reset()
+	if c.rebalanceCb != nil && len(revoked) != 0 {
+		c.callRebalanceCb(tmq.RevokedPartitions{Partitions: revoked})
 	}
 	reqID := c.generateReqID()
 	req := &UnsubscribeReq{
@@ -667,7 +1061,7 @@ func (c *Consumer) Unsubscribe() error {
 		c.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQUnsubscribe, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -679,61 +1073,121 @@ func (c *Consumer) Unsubscribe() error {
 	if resp.Code != 0 {
 		return taosErrors.NewError(resp.Code, resp.Message)
 	}
+	c.topics = nil
+	c.offsetLock.Lock()
+	c.deliveredOffsets = make(map[topicVgroup]tmq.Offset)
+	c.offsetLock.Unlock()
 	return nil
 }
 
 func (c *Consumer) Assignment() (partitions []tmq.TopicPartition, err error) {
-	if c.err != nil {
-		return nil, c.err
+	if err := c.getErr(); err != nil {
+		return nil, err
 	}
 	for _, topic := range c.topics {
-		reqID := c.generateReqID()
-		req := &AssignmentReq{
-			ReqID: reqID,
-			Topic: topic,
-		}
-		args, err := client.JsonI.Marshal(req)
+		assignment, err := c.topicAssignment(topic)
 		if err != nil {
 			return nil, err
 		}
-		action := &client.WSAction{
-			Action: TMQGetTopicAssignment,
-			Args:   args,
+		topicName := topic
+		for i := 0; i < len(assignment); i++ {
+			offset := tmq.Offset(assignment[i].Offset)
+			partitions = append(partitions, tmq.TopicPartition{
+				Topic:     &topicName,
+				Partition: assignment[i].VGroupID,
+				Offset:    offset,
+			})
 		}
-		envelope := c.client.GetEnvelope()
-		err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	}
+	return partitions, nil
+}
+
+func (c *Consumer) topicAssignment(topic string) ([]tmq.Assignment, error) {
+	reqID := c.generateReqID()
+	req := &AssignmentReq{
+		ReqID: reqID,
+		Topic: topic,
+	}
+	args, err := client.JsonI.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	action := &client.WSAction{
+		Action: TMQGetTopicAssignment,
+		Args:   args,
+	}
+	envelope := c.client.GetEnvelope()
+	err = client.JsonI.NewEncoder(envelope.Msg).Encode(action)
+	if err != nil {
+		c.client.PutEnvelope(envelope)
+		return nil, err
+	}
+	respBytes, err := c.sendText(TMQGetTopicAssignment, reqID, envelope)
+	if err != nil {
+		return nil, err
+	}
+	var resp AssignmentResp
+	err = client.JsonI.Unmarshal(respBytes, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, taosErrors.NewError(resp.Code, resp.Message)
+	}
+	return resp.Assignment, nil
+}
+
+// Lag returns the committed offset, the position and the watermarks of every vgroup assigned to the consumer.
+func (c *Consumer) Lag() ([]tmq.PartitionLag, error) {
+	if err := c.getErr(); err != nil {
+		return nil, err
+	}
+	var lags []tmq.PartitionLag
+	for _, topic := range c.topics {
+		assignment, err := c.topicAssignment(topic)
 		if err != nil {
-			c.client.PutEnvelope(envelope)
 			return nil, err
 		}
-		respBytes, err := c.sendText(reqID, envelope)
-		if err != nil {
-			return nil, err
+		if len(assignment) == 0 {
+			continue
 		}
-		var resp AssignmentResp
-		err = client.JsonI.Unmarshal(respBytes, &resp)
+		topicName := topic
+		partitions := make([]tmq.TopicPartition, len(assignment))
+		for i := 0; i < len(assignment); i++ {
+			partitions[i] = tmq.TopicPartition{Topic: &topicName, Partition: assignment[i].VGroupID}
+		}
+		committed, err := c.Committed(partitions, 0)
 		if err != nil {
 			return nil, err
 		}
-		if resp.Code != 0 {
-			return nil, taosErrors.NewError(resp.Code, resp.Message)
+		for i := 0; i < len(assignment); i++ {
+			lags = append(lags, tmq.NewPartitionLag(topic, &assignment[i], committed[i].Offset))
 		}
-		topicName := topic
-		for i := 0; i < len(resp.Assignment); i++ {
-			offset := tmq.Offset(resp.Assignment[i].Offset)
-			partitions = append(partitions, tmq.TopicPartition{
-				Topic:     &topicName,
-				Partition: resp.Assignment[i].VGroupID,
-				Offset:    offset,
-			})
+	}
+	return lags, nil
+}
+
+// WatermarkOffsets returns the first offset still stored and the offset of the next message written
+// of a vgroup assigned to the consumer.
+func (c *Consumer) WatermarkOffsets(topic string, vgroupID int32) (low, high int64, err error) {
+	if err := c.getErr(); err != nil {
+		return 0, 0, err
+	}
+	assignment, err := c.topicAssignment(topic)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := 0; i < len(assignment); i++ {
+		if assignment[i].VGroupID == vgroupID {
+			return assignment[i].Begin, assignment[i].End, nil
 		}
 	}
-	return partitions, nil
+	return 0, 0, fmt.Errorf("vgroup %d of topic %s is not assigned", vgroupID, topic)
 }
 
 func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) error {
-	if c.err != nil {
-		return c.err
+	if err := c.getErr(); err != nil {
+		return err
 	}
 	reqID := c.generateReqID()
 	req := &OffsetSeekReq{
@@ -756,7 +1210,7 @@ func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) erro
 		c.client.PutEnvelope(envelope)
 		return err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQSeek, reqID, envelope)
 	if err != nil {
 		return err
 	}
@@ -768,6 +1222,7 @@ func (c *Consumer) Seek(partition tmq.TopicPartition, ignoredTimeoutMs int) erro
 	if resp.Code != 0 {
 		return taosErrors.NewError(resp.Code, resp.Message)
 	}
+	c.recordOffset(*partition.Topic, partition.Partition, partition.Offset)
 	return nil
 }
 
@@ -798,7 +1253,7 @@ func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (of
 		c.client.PutEnvelope(envelope)
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQCommitted, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
@@ -821,8 +1276,8 @@ func (c *Consumer) Committed(partitions []tmq.TopicPartition, timeoutMs int) (of
 }
 
 func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicPartition, error) {
-	if c.err != nil {
-		return nil, c.err
+	if err := c.getErr(); err != nil {
+		return nil, err
 	}
 	for i := 0; i < len(offsets); i++ {
 		reqID := c.generateReqID()
@@ -846,7 +1301,7 @@ func (c *Consumer) CommitOffsets(offsets []tmq.TopicPartition) ([]tmq.TopicParti
 			c.client.PutEnvelope(envelope)
 			return nil, err
 		}
-		respBytes, err := c.sendText(reqID, envelope)
+		respBytes, err := c.sendText(TMQCommitOffset, reqID, envelope)
 		if err != nil {
 			return nil, err
 		}
@@ -889,7 +1344,7 @@ func (c *Consumer) Position(partitions []tmq.TopicPartition) (offsets []tmq.Topi
 		c.client.PutEnvelope(envelope)
 		return nil, err
 	}
-	respBytes, err := c.sendText(reqID, envelope)
+	respBytes, err := c.sendText(TMQPosition, reqID, envelope)
 	if err != nil {
 		return nil, err
 	}
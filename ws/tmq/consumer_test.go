@@ -1,19 +1,25 @@
 package tmq
 
 import (
+	"database/sql/driver"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/taosdata/driver-go/v3/common"
 	"github.com/taosdata/driver-go/v3/common/tmq"
 	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	"github.com/taosdata/driver-go/v3/internal/testutil"
 	"github.com/taosdata/driver-go/v3/ws/client"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
 )
 
 func prepareEnv() error {
@@ -350,3 +356,415 @@ func TestSeek(t *testing.T) {
 	assert.Equal(t, "test_ws_tmq_seek_topic", *partitions[0].Topic)
 	assert.GreaterOrEqual(t, partitions[0].Offset, messageOffset)
 }
+
+func TestReconnect(t *testing.T) {
+	var lock sync.Mutex
+	var conns []*websocket.Conn
+	subscribeCount := 0
+	var seeks []OffsetSeekReq
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		lock.Lock()
+		conns = append(conns, ws)
+		first := len(conns) == 1
+		lock.Unlock()
+		defer ws.Close()
+		polled := false
+		for {
+			_, message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var action client.WSAction
+			if err = client.JsonI.Unmarshal(message, &action); err != nil {
+				return
+			}
+			var req struct {
+				ReqID uint64 `json:"req_id"`
+			}
+			client.JsonI.Unmarshal(action.Args, &req)
+			switch action.Action {
+			case TMQSubscribe:
+				lock.Lock()
+				subscribeCount += 1
+				lock.Unlock()
+				ws.WriteJSON(&SubscribeResp{Action: TMQSubscribe, ReqID: req.ReqID})
+			case TMQPoll:
+				resp := &PollResp{Action: TMQPoll, ReqID: req.ReqID}
+				if first && !polled {
+					polled = true
+					resp.HaveMessage = true
+					resp.Topic = "test_ws_tmq_reconnect"
+					resp.Database = "db"
+					resp.VgroupID = 2
+					resp.MessageType = common.TMQ_RES_TABLE_META
+					resp.MessageID = 1
+					resp.Offset = 10
+				}
+				ws.WriteJSON(resp)
+			case TMQFetchJsonMeta:
+				ws.WriteJSON(&FetchJsonMetaResp{Action: TMQFetchJsonMeta, ReqID: req.ReqID, MessageID: 1, Data: []byte(`{"type":"create"}`)})
+			case TMQGetTopicAssignment:
+				ws.WriteJSON(&AssignmentResp{
+					Action:     TMQGetTopicAssignment,
+					ReqID:      req.ReqID,
+					Assignment: []tmq.Assignment{{VGroupID: 2, Offset: 5, Begin: 0, End: 20}},
+				})
+			case TMQSeek:
+				var seek OffsetSeekReq
+				client.JsonI.Unmarshal(action.Args, &seek)
+				lock.Lock()
+				seeks = append(seeks, seek)
+				lock.Unlock()
+				ws.WriteJSON(&OffsetSeekResp{Action: TMQSeek, ReqID: req.ReqID})
+			}
+		}
+	}))
+	defer server.Close()
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":                 "ws" + strings.TrimPrefix(server.URL, "http") + "/rest/tmq",
+		"ws.message.channelLen":  uint(0),
+		"ws.message.timeout":     common.DefaultMessageTimeout,
+		"ws.message.writeWait":   common.DefaultWriteWait,
+		"ws.autoReconnect":       true,
+		"ws.reconnectIntervalMs": 10,
+		"group.id":               "test",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	err = consumer.Subscribe("test_ws_tmq_reconnect", nil)
+	assert.NoError(t, err)
+	ev := consumer.Poll(100)
+	_, ok := ev.(*tmq.MetaMessage)
+	assert.True(t, ok, "expect meta message got %v", ev)
+
+	// simulate a taosAdapter restart
+	lock.Lock()
+	conns[0].UnderlyingConn().Close()
+	lock.Unlock()
+
+	var reconnected tmq.Reconnected
+	for i := 0; i < 100; i++ {
+		ev = consumer.Poll(100)
+		if r, ok := ev.(tmq.Reconnected); ok {
+			reconnected = r
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !assert.NotNil(t, reconnected.Cause) {
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 2, subscribeCount)
+	assert.Equal(t, 1, len(seeks))
+	assert.Equal(t, int32(2), seeks[0].VgroupID)
+	assert.Equal(t, int64(10), seeks[0].Offset)
+	assert.Equal(t, 1, len(reconnected.Partitions))
+	assert.Equal(t, tmq.Offset(10), reconnected.Partitions[0].Offset)
+	ev = consumer.Poll(100)
+	assert.Nil(t, ev)
+}
+
+func TestPollErrorEvent(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var lock sync.Mutex
+	var conns []*websocket.Conn
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		lock.Lock()
+		conns = append(conns, ws)
+		lock.Unlock()
+		defer ws.Close()
+		for {
+			_, message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var action client.WSAction
+			if err = client.JsonI.Unmarshal(message, &action); err != nil {
+				return
+			}
+			var req struct {
+				ReqID uint64 `json:"req_id"`
+			}
+			client.JsonI.Unmarshal(action.Args, &req)
+			switch action.Action {
+			case TMQSubscribe:
+				ws.WriteJSON(&SubscribeResp{Action: TMQSubscribe, ReqID: req.ReqID})
+			case TMQPoll:
+				ws.WriteJSON(&PollResp{Action: TMQPoll, ReqID: req.ReqID, Code: 0x2603, Message: "Table does not exist"})
+			}
+		}
+	}))
+	defer server.Close()
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":                "ws" + strings.TrimPrefix(server.URL, "http") + "/rest/tmq",
+		"ws.message.channelLen": uint(0),
+		"ws.message.timeout":    common.DefaultMessageTimeout,
+		"ws.message.writeWait":  common.DefaultWriteWait,
+		"ws.autoReconnect":      false,
+		"group.id":              "test",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	err = consumer.Subscribe("test_ws_tmq_poll_error", nil)
+	assert.NoError(t, err)
+	ev := consumer.Poll(100)
+	e, ok := ev.(tmq.Error)
+	if !assert.True(t, ok, "expect error got %v", ev) {
+		return
+	}
+	assert.Equal(t, 0x2603, e.Code())
+	assert.False(t, e.IsFatal())
+	assert.False(t, e.IsRetriable())
+
+	// without automatic reconnection a broken connection is fatal
+	lock.Lock()
+	conns[0].UnderlyingConn().Close()
+	lock.Unlock()
+	var fatal tmq.Error
+	for i := 0; i < 100; i++ {
+		if e, ok = consumer.Poll(100).(tmq.Error); ok && e.IsFatal() {
+			fatal = e
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, fatal.IsFatal())
+}
+
+func TestRebalanceCallback(t *testing.T) {
+	defer func(interval time.Duration) { assignmentCheckInterval = interval }(assignmentCheckInterval)
+	assignmentCheckInterval = 0
+	s := wstest.NewServer()
+	defer s.Close()
+	s.CreateTopic("test_ws_tmq_rebalance", 1, 2)
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":             s.URL() + "/rest/tmq",
+		"ws.message.timeout": 5 * time.Second,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	var events []string
+	err = consumer.Subscribe("test_ws_tmq_rebalance", func(c *Consumer, ev tmq.Event) error {
+		events = append(events, ev.String())
+		if _, ok := ev.(tmq.RevokedPartitions); ok {
+			// the vgroups can still be committed
+			_, err := c.Commit()
+			return err
+		}
+		return nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, consumer.Poll(10))
+	assert.Equal(t, []string{"AssignedPartitions: [test_ws_tmq_rebalance[1]@0 test_ws_tmq_rebalance[2]@0]"}, events)
+	assert.Nil(t, consumer.Poll(10))
+	assert.Len(t, events, 1)
+
+	s.CreateTopic("test_ws_tmq_rebalance", 3)
+	assert.Nil(t, consumer.Poll(10))
+	assert.Equal(t, "AssignedPartitions: [test_ws_tmq_rebalance[3]@0]", events[len(events)-1])
+
+	assert.NoError(t, consumer.Unsubscribe())
+	assert.Equal(t, "RevokedPartitions: [test_ws_tmq_rebalance[1]@0 test_ws_tmq_rebalance[2]@0 test_ws_tmq_rebalance[3]@0]", events[len(events)-1])
+	assert.Len(t, events, 3)
+}
+
+func TestLag(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	topic := "test_ws_tmq_lag"
+	s.CreateTopic(topic, 1, 2)
+	s.Produce(topic,
+		wstest.Message{Database: "test", VgroupID: 1},
+		wstest.Message{Database: "test", VgroupID: 1},
+		wstest.Message{Database: "test", VgroupID: 1},
+		wstest.Message{Database: "test", VgroupID: 2},
+	)
+	config := tmq.ConfigMap{
+		"ws.url":             s.URL() + "/rest/tmq",
+		"ws.message.timeout": 5 * time.Second,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test",
+		"auto.offset.reset":  "earliest",
+		tmq.LagIntervalKey:   1,
+	}
+	_, err := NewConsumer(&config)
+	assert.Error(t, err)
+	config[tmq.LagIntervalKey] = "100"
+	consumer, err := NewConsumer(&config)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	if !assert.NoError(t, consumer.Subscribe(topic, nil)) {
+		return
+	}
+
+	lags, err := consumer.Lag()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []tmq.PartitionLag{
+		{Topic: topic, Partition: 1, Committed: tmq.OffsetInvalid, Position: 0, Begin: 0, End: 3, Lag: 3},
+		{Topic: topic, Partition: 2, Committed: tmq.OffsetInvalid, Position: 0, Begin: 0, End: 1, Lag: 1},
+	}, lags)
+	low, high, err := consumer.WatermarkOffsets(topic, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), low)
+	assert.Equal(t, int64(3), high)
+	_, _, err = consumer.WatermarkOffsets(topic, 9)
+	assert.Error(t, err)
+
+	// the lag is returned by Poll once lag.interval.ms has passed, then the messages
+	time.Sleep(100 * time.Millisecond)
+	lag, ok := consumer.Poll(10).(tmq.ConsumerLag)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, int64(4), lag.Total())
+	ev := consumer.Poll(10)
+	_, ok = ev.(*tmq.DataMessage)
+	assert.True(t, ok, "%v", ev)
+	_, err = consumer.Commit()
+	assert.NoError(t, err)
+	lags, err = consumer.Lag()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, tmq.PartitionLag{Topic: topic, Partition: 1, Committed: 1, Position: 1, Begin: 0, End: 3, Lag: 2}, lags[0])
+}
+
+func TestPollBatch(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	topic := "test_ws_tmq_poll_batch"
+	fields := []wstest.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		{Name: "v", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+	}
+	ts := time.Unix(1700000000, 0)
+	block := func(table string, v int32) wstest.Block {
+		return wstest.Block{TableName: table, Fields: fields, Rows: [][]driver.Value{{ts, v}}}
+	}
+	s.CreateTopic(topic, 1)
+	s.Produce(topic,
+		wstest.Message{Database: "test", VgroupID: 1, Blocks: []wstest.Block{block("t1", 1), block("t2", 2)}},
+		wstest.Message{
+			Database: "test",
+			VgroupID: 1,
+			Type:     common.TMQ_RES_METADATA,
+			Meta:     []byte(`{"type":"create","tableName":"t3"}`),
+			Blocks:   []wstest.Block{block("t3", 3)},
+		},
+		wstest.Message{Database: "test", VgroupID: 1, Blocks: []wstest.Block{block("t1", 4)}},
+	)
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":             s.URL() + "/rest/tmq",
+		"ws.message.timeout": 5 * time.Second,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test",
+		"auto.offset.reset":  "earliest",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	if !assert.NoError(t, consumer.Subscribe(topic, nil)) {
+		return
+	}
+
+	events := consumer.PollBatch(2, 5*time.Second)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+	data := events[0].(*tmq.DataMessage).Value().([]*tmq.Data)
+	if assert.Len(t, data, 2) {
+		assert.Equal(t, "t1", data[0].TableName)
+		assert.Equal(t, [][]driver.Value{{ts, int32(1)}}, data[0].Data)
+		assert.Equal(t, "t2", data[1].TableName)
+		assert.Equal(t, [][]driver.Value{{ts, int32(2)}}, data[1].Data)
+	}
+	metaData := events[1].(*tmq.MetaDataMessage).Value().(*tmq.MetaData)
+	assert.Equal(t, "t3", metaData.Meta.TableName)
+	if assert.Len(t, metaData.Data, 1) {
+		assert.Equal(t, [][]driver.Value{{ts, int32(3)}}, metaData.Data[0].Data)
+	}
+	// each request is sent once the previous one is answered, the second poll right after the last fetch of
+	// the first message and no poll is left in flight when the batch is full
+	var actions []string
+	for _, req := range s.Requests() {
+		if req.Action != "subscribe" {
+			actions = append(actions, req.Action)
+		}
+	}
+	assert.Equal(t, []string{
+		"poll", "fetch", "fetch_block", "fetch", "fetch_block", "fetch",
+		"poll", "fetch_json_meta", "fetch", "fetch_block", "fetch",
+	}, actions)
+
+	// the batch ends when no message arrives in time
+	events = consumer.PollBatch(10, 100*time.Millisecond)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, tmq.Offset(2), events[0].(*tmq.DataMessage).Offset())
+	}
+	assert.Empty(t, consumer.PollBatch(10, 10*time.Millisecond))
+	// no request is left waiting for a response
+	consumer.listLock.RLock()
+	assert.Equal(t, 0, consumer.sendChanList.Len())
+	consumer.listLock.RUnlock()
+}
+
+func TestMetricsAndLoggerValues(t *testing.T) {
+	defer func(interval time.Duration) { assignmentCheckInterval = interval }(assignmentCheckInterval)
+	assignmentCheckInterval = 0
+	s := wstest.NewServer()
+	defer s.Close()
+	s.CreateTopic("test_ws_tmq_values", 1)
+	metrics := &testutil.Metrics{}
+	logger := &testutil.Logger{}
+	consumer, err := NewConsumer(&tmq.ConfigMap{
+		"ws.url":             s.URL() + "/rest/tmq",
+		"ws.message.timeout": 5 * time.Second,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "test",
+		"ws.metrics":         common.Metrics(metrics),
+		"ws.logger":          common.Logger(logger),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer consumer.Close()
+	err = consumer.Subscribe("test_ws_tmq_values", func(c *Consumer, ev tmq.Event) error {
+		return fmt.Errorf("callback failed")
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Nil(t, consumer.Poll(10))
+	assert.Contains(t, metrics.Requests(), common.DriverTMQ+" "+common.ActionPoll)
+	assert.Contains(t, logger.Entries(), "WARN rebalance callback failed")
+}
@@ -0,0 +1,141 @@
+package wstest
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	"github.com/taosdata/driver-go/v3/common/parser"
+	"github.com/taosdata/driver-go/v3/common/serializer"
+	taosTypes "github.com/taosdata/driver-go/v3/types"
+)
+
+// encodeBlock serializes rows as a raw block, the format of fetch_block responses
+func encodeBlock(fields []Field, rows [][]driver.Value, precision int) ([]byte, error) {
+	params := make([]*param.Param, len(fields))
+	colTypes := param.NewColumnType(len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case common.TSDB_DATA_TYPE_BOOL:
+			colTypes.AddBool()
+		case common.TSDB_DATA_TYPE_TINYINT:
+			colTypes.AddTinyint()
+		case common.TSDB_DATA_TYPE_SMALLINT:
+			colTypes.AddSmallint()
+		case common.TSDB_DATA_TYPE_INT:
+			colTypes.AddInt()
+		case common.TSDB_DATA_TYPE_BIGINT:
+			colTypes.AddBigint()
+		case common.TSDB_DATA_TYPE_UTINYINT:
+			colTypes.AddUTinyint()
+		case common.TSDB_DATA_TYPE_USMALLINT:
+			colTypes.AddUSmallint()
+		case common.TSDB_DATA_TYPE_UINT:
+			colTypes.AddUInt()
+		case common.TSDB_DATA_TYPE_UBIGINT:
+			colTypes.AddUBigint()
+		case common.TSDB_DATA_TYPE_FLOAT:
+			colTypes.AddFloat()
+		case common.TSDB_DATA_TYPE_DOUBLE:
+			colTypes.AddDouble()
+		case common.TSDB_DATA_TYPE_BINARY:
+			colTypes.AddBinary(int(field.Length))
+		case common.TSDB_DATA_TYPE_VARBINARY:
+			colTypes.AddVarBinary(int(field.Length))
+		case common.TSDB_DATA_TYPE_NCHAR:
+			colTypes.AddNchar(int(field.Length))
+		case common.TSDB_DATA_TYPE_TIMESTAMP:
+			colTypes.AddTimestamp()
+		case common.TSDB_DATA_TYPE_JSON:
+			colTypes.AddJson(int(field.Length))
+		case common.TSDB_DATA_TYPE_GEOMETRY:
+			colTypes.AddGeometry(int(field.Length))
+		default:
+			return nil, fmt.Errorf("unsupported type %d of column %s", field.Type, field.Name)
+		}
+		params[i] = param.NewParam(len(rows))
+		for _, row := range rows {
+			v, err := toTaosValue(field.Type, row[i], precision)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", field.Name, err)
+			}
+			params[i].AddValue(v)
+		}
+	}
+	return serializer.SerializeRawBlock(params, colTypes)
+}
+
+// toTaosValue converts a stored value to the types expected by the serializer
+func toTaosValue(t uint8, v driver.Value, precision int) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	v, err := convertValue(t, v, precision)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case common.TSDB_DATA_TYPE_BOOL:
+		return taosTypes.TaosBool(v.(bool)), nil
+	case common.TSDB_DATA_TYPE_TINYINT:
+		return taosTypes.TaosTinyint(v.(int8)), nil
+	case common.TSDB_DATA_TYPE_SMALLINT:
+		return taosTypes.TaosSmallint(v.(int16)), nil
+	case common.TSDB_DATA_TYPE_INT:
+		return taosTypes.TaosInt(v.(int32)), nil
+	case common.TSDB_DATA_TYPE_BIGINT:
+		return taosTypes.TaosBigint(v.(int64)), nil
+	case common.TSDB_DATA_TYPE_UTINYINT:
+		return taosTypes.TaosUTinyint(v.(uint8)), nil
+	case common.TSDB_DATA_TYPE_USMALLINT:
+		return taosTypes.TaosUSmallint(v.(uint16)), nil
+	case common.TSDB_DATA_TYPE_UINT:
+		return taosTypes.TaosUInt(v.(uint32)), nil
+	case common.TSDB_DATA_TYPE_UBIGINT:
+		return taosTypes.TaosUBigint(v.(uint64)), nil
+	case common.TSDB_DATA_TYPE_FLOAT:
+		return taosTypes.TaosFloat(v.(float32)), nil
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		return taosTypes.TaosDouble(v.(float64)), nil
+	case common.TSDB_DATA_TYPE_BINARY:
+		return taosTypes.TaosBinary(v.(string)), nil
+	case common.TSDB_DATA_TYPE_NCHAR:
+		return taosTypes.TaosNchar(v.(string)), nil
+	case common.TSDB_DATA_TYPE_VARBINARY:
+		return taosTypes.TaosVarBinary(v.([]byte)), nil
+	case common.TSDB_DATA_TYPE_GEOMETRY:
+		return taosTypes.TaosGeometry(v.([]byte)), nil
+	case common.TSDB_DATA_TYPE_JSON:
+		return taosTypes.TaosJson(v.([]byte)), nil
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		return taosTypes.TaosTimestamp{T: v.(time.Time), Precision: precision}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %d", t)
+}
+
+// decodeBlock reads the raw block of a stmt bind or set_tags message
+func decodeBlock(block []byte, precision int) ([]uint8, [][]driver.Value, error) {
+	if len(block) < int(parser.ColInfoOffset) {
+		return nil, nil, fmt.Errorf("raw block too short: %d bytes", len(block))
+	}
+	length := int(binary.LittleEndian.Uint32(block[parser.RawBlockLengthOffset:]))
+	rows := int(binary.LittleEndian.Uint32(block[parser.NumOfRowsOffset:]))
+	cols := int(binary.LittleEndian.Uint32(block[parser.NumOfColsOffset:]))
+	if length < int(parser.RawBlockGetColDataOffset(cols)) || length > len(block) {
+		return nil, nil, fmt.Errorf("invalid raw block length %d", length)
+	}
+	// own the block, the parser reads it in place and computes a pointer just past its end
+	block = append(make([]byte, 0, length+8), block[:length]...)
+	p := unsafe.Pointer(&block[0])
+	infos := make([]parser.RawBlockColInfo, cols)
+	parser.RawBlockGetColInfo(p, infos)
+	colTypes := make([]uint8, cols)
+	for i, info := range infos {
+		colTypes[i] = uint8(info.ColType)
+	}
+	return colTypes, parser.ReadBlock(p, rows, colTypes, precision), nil
+}
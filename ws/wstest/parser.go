@@ -0,0 +1,366 @@
+package wstest
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// placeholder is a `?` in the values of a prepared insert
+type placeholder struct{}
+
+// sqlParser is a tiny tokenizer for the statements the built-in SQL engine understands
+type sqlParser struct {
+	s   string
+	pos int
+}
+
+func (p *sqlParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *sqlParser) eof() bool {
+	p.skipSpace()
+	return p.pos >= len(p.s)
+}
+
+func (p *sqlParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *sqlParser) rest() string {
+	return p.s[p.pos:]
+}
+
+func (p *sqlParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("syntax error: expect '%c' near '%s'", c, p.rest())
+	}
+	p.pos++
+	return nil
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || c == '`' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// word reads an identifier, a keyword or a `?`
+func (p *sqlParser) word() string {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '?' {
+		p.pos++
+		return "?"
+	}
+	start := p.pos
+	for p.pos < len(p.s) && isWordChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// keyword consumes kw if it is the next word
+func (p *sqlParser) keyword(kw string) bool {
+	save := p.pos
+	if strings.EqualFold(p.word(), kw) {
+		return true
+	}
+	p.pos = save
+	return false
+}
+
+func (p *sqlParser) ident() (string, error) {
+	w := p.word()
+	if w == "" {
+		return "", fmt.Errorf("syntax error: expect a name near '%s'", p.rest())
+	}
+	return w, nil
+}
+
+var typeNames = map[string]uint8{
+	"BINARY":  common.TSDB_DATA_TYPE_BINARY,
+	"INTEGER": common.TSDB_DATA_TYPE_INT,
+}
+
+// columnDefs reads `(name type[(length)], ...)`
+func (p *sqlParser) columnDefs() ([]Field, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var fields []Field
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		typeName := strings.ToUpper(p.word())
+		if p.keyword("unsigned") {
+			typeName += " UNSIGNED"
+		}
+		t, exist := typeNames[typeName]
+		if !exist {
+			id, exist := common.NameTypeMap[typeName]
+			if !exist {
+				return nil, fmt.Errorf("syntax error: unknown type '%s'", typeName)
+			}
+			t = uint8(id)
+		}
+		field := Field{Name: strings.Trim(name, "`"), Type: t, Length: int64(common.TypeLengthMap[int(t)])}
+		if p.peek() == '(' {
+			p.pos++
+			length, err := strconv.ParseInt(strings.TrimSpace(p.word()), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("syntax error: invalid length near '%s'", p.rest())
+			}
+			field.Length = length
+			if err = p.expect(')'); err != nil {
+				return nil, err
+			}
+		} else if t == common.TSDB_DATA_TYPE_JSON {
+			field.Length = 4095
+		}
+		fields = append(fields, field)
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		return fields, p.expect(')')
+	}
+}
+
+type insertTarget struct {
+	name    string
+	super   string
+	tags    []driver.Value
+	columns []string
+}
+
+// insertTarget reads `tb [using stb [(tag names)] tags(...)] [(cols)] values`
+func (p *sqlParser) insertTarget() (*insertTarget, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	target := &insertTarget{name: name}
+	if p.keyword("using") {
+		if target.super, err = p.ident(); err != nil {
+			return nil, err
+		}
+		if p.peek() == '(' {
+			if _, err = p.names(); err != nil {
+				return nil, err
+			}
+		}
+		if !p.keyword("tags") {
+			return nil, fmt.Errorf("syntax error: expect tags near '%s'", p.rest())
+		}
+		if target.tags, err = p.values(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() == '(' {
+		if target.columns, err = p.names(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.keyword("values") {
+		return nil, fmt.Errorf("syntax error: expect values near '%s'", p.rest())
+	}
+	return target, nil
+}
+
+// names reads `(a, b, ...)`
+func (p *sqlParser) names() ([]string, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, strings.Trim(name, "`"))
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		return names, p.expect(')')
+	}
+}
+
+// values reads `(literal, ...)`
+func (p *sqlParser) values() ([]driver.Value, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var values []driver.Value
+	for {
+		v, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		return values, p.expect(')')
+	}
+}
+
+var errUnterminatedString = errors.New("syntax error: unterminated quoted string")
+
+func (p *sqlParser) literal() (driver.Value, error) {
+	c := p.peek()
+	switch {
+	case c == '\'' || c == '"':
+		return p.quoted(c)
+	case c == '?':
+		p.pos++
+		return placeholder{}, nil
+	case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.s) {
+			c := p.s[p.pos]
+			if c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' ||
+				(c == '-' || c == '+') && (p.s[p.pos-1] == 'e' || p.s[p.pos-1] == 'E') {
+				p.pos++
+				continue
+			}
+			break
+		}
+		text := p.s[start:p.pos]
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("syntax error: invalid number '%s'", text)
+		}
+		return f, nil
+	}
+	w := p.word()
+	switch strings.ToLower(w) {
+	case "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "now":
+		if p.peek() == '(' {
+			p.pos++
+			if err := p.expect(')'); err != nil {
+				return nil, err
+			}
+		}
+		return time.Now(), nil
+	}
+	return nil, fmt.Errorf("syntax error: unexpected '%s'", p.rest())
+}
+
+// quoted reads a string quoted by q, the quote is escaped by doubling it or with a backslash
+func (p *sqlParser) quoted(q byte) (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && p.pos < len(p.s):
+			e := p.s[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			default:
+				b.WriteByte(e)
+			}
+		case c == q:
+			if p.pos < len(p.s) && p.s[p.pos] == q {
+				b.WriteByte(q)
+				p.pos++
+				continue
+			}
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errUnterminatedString
+}
+
+// bindSQL replaces the `?` of a prepared query with the bound values
+func bindSQL(sql string, args []driver.Value) (string, error) {
+	var b strings.Builder
+	n := 0
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(sql) {
+				b.WriteByte(c)
+				i++
+				c = sql[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			if n >= len(args) {
+				return "", errors.New("not enough parameters")
+			}
+			b.WriteString(formatLiteral(args[n]))
+			n++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if n != len(args) {
+		return "", errors.New("too many parameters")
+	}
+	return b.String(), nil
+}
+
+func formatLiteral(v driver.Value) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val) + "'"
+	case []byte:
+		return formatLiteral(string(val))
+	case time.Time:
+		return strconv.FormatInt(common.TimeToTimestamp(val, common.PrecisionMilliSecond), 10)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
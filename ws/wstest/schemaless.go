@@ -0,0 +1,52 @@
+package wstest
+
+// SchemalessRequest is a schemaless insert received by the server.
+type SchemalessRequest struct {
+	DB        string
+	Protocol  int
+	Precision string
+	TTL       int
+	Data      string
+}
+
+// SchemalessRequests returns the schemaless inserts received so far, the lines are recorded but not stored in tables.
+func (s *Server) SchemalessRequests() []SchemalessRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]SchemalessRequest(nil), s.schemaless...)
+}
+
+// handleSchemaless serves /rest/schemaless, used by ws/schemaless
+func (sess *session) handleSchemaless(req *Request) error {
+	a, err := sess.parseArgs(req)
+	if err != nil {
+		return err
+	}
+	if req.Action != "conn" && !sess.connected {
+		return sess.notConnected(req.Action, req.ReqID)
+	}
+	switch req.Action {
+	case "conn":
+		return sess.connect(req.Action, a)
+	case "insert":
+		if a.Protocol < 1 || a.Protocol > 3 {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "invalid protocol")
+		}
+		db := a.DB
+		if db == "" {
+			db = sess.db
+		}
+		s := sess.server
+		s.lock.Lock()
+		s.schemaless = append(s.schemaless, SchemalessRequest{
+			DB:        db,
+			Protocol:  a.Protocol,
+			Precision: a.Precision,
+			TTL:       a.TTL,
+			Data:      a.Data,
+		})
+		s.lock.Unlock()
+		return sess.write(req.Action, a.ReqID, nil)
+	}
+	return sess.writeError(req.Action, req.ReqID, CodeInvalidPara, "unknown action "+req.Action)
+}
@@ -0,0 +1,284 @@
+// Package wstest provides an in-process fake taosAdapter for hermetic tests of taosWS, ws/stmt, ws/tmq and ws/schemaless.
+//
+// The server speaks the WebSocket protocols of /rest/ws, /rest/stmt, /rest/tmq and /rest/schemaless and keeps tables
+// in memory. It understands `create table`, `create stable`, `drop table`, `insert into ... values ...`,
+// `select * from <table>` and `select count(*) from <table>`, other statements succeed without effect
+// or can be answered with HandleQuery. Errors, delays and disconnects are injected with InjectFault.
+package wstest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	jsoniter "github.com/json-iterator/go"
+)
+
+var jsonI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+const (
+	DefaultUser     = "root"
+	DefaultPassword = "taosdata"
+)
+
+// Request is a message received by the server.
+type Request struct {
+	Path   string          // the endpoint, e.g. /rest/ws
+	Action string          // the action of a text message, or "bind" and "set_tags" for binary stmt messages
+	ReqID  uint64          // req_id of the message
+	Args   json.RawMessage // args of a text message
+	Data   []byte          // a binary message
+}
+
+// Fault replaces the response to a request.
+type Fault struct {
+	Delay      time.Duration // wait before handling the request
+	Code       int           // respond with this error code instead of handling the request
+	Message    string        // error message returned with Code
+	Disconnect bool          // close the connection instead of responding
+}
+
+type fault struct {
+	path   string
+	action string
+	Fault
+}
+
+// Server is a fake taosAdapter.
+type Server struct {
+	server   *httptest.Server
+	upgrader websocket.Upgrader
+
+	lock       sync.Mutex
+	user       string
+	password   string
+	handlers   []*queryHandler
+	tables     map[string]*table
+	faults     []*fault
+	requests   []Request
+	conns      map[*websocket.Conn]struct{}
+	nextID     uint64
+	topics     map[string]*topic
+	committed  map[string]map[topicVgroup]int64
+	produced   chan struct{}
+	schemaless []SchemalessRequest
+	latency    time.Duration
+}
+
+// NewServer starts a fake taosAdapter accepting the default user root with password taosdata.
+func NewServer() *Server {
+	s := newServer()
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// NewTLSServer starts a fake taosAdapter serving wss, see httptest.NewTLSServer.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer() *Server {
+	return &Server{
+		user:      DefaultUser,
+		password:  DefaultPassword,
+		tables:    make(map[string]*table),
+		conns:     make(map[*websocket.Conn]struct{}),
+		topics:    make(map[string]*topic),
+		committed: make(map[string]map[topicVgroup]int64),
+		produced:  make(chan struct{}),
+	}
+}
+
+// URL returns the base url of the server, e.g. ws://127.0.0.1:12345
+func (s *Server) URL() string {
+	u, _ := url.Parse(s.server.URL)
+	if u.Scheme == "https" {
+		u.Scheme = "wss"
+	} else {
+		u.Scheme = "ws"
+	}
+	return u.String()
+}
+
+// Addr returns host:port of the server, for use in a taosWS DSN.
+func (s *Server) Addr() string {
+	return s.server.Listener.Addr().String()
+}
+
+// HTTPServer returns the underlying httptest.Server, e.g. to get the certificate of a TLS server.
+func (s *Server) HTTPServer() *httptest.Server {
+	return s.server
+}
+
+// Close disconnects every client and shuts the server down.
+func (s *Server) Close() {
+	s.CloseConnections()
+	s.server.Close()
+}
+
+// SetAuth changes the accepted user and password.
+func (s *Server) SetAuth(user, password string) {
+	s.lock.Lock()
+	s.user, s.password = user, password
+	s.lock.Unlock()
+}
+
+// CloseConnections drops every open WebSocket connection, the server keeps accepting new ones.
+func (s *Server) CloseConnections() {
+	s.lock.Lock()
+	conns := s.conns
+	s.conns = make(map[*websocket.Conn]struct{})
+	s.lock.Unlock()
+	for conn := range conns {
+		conn.Close()
+	}
+}
+
+// InjectFault applies f to the next request with the given path and action,
+// an empty path or action matches any. Faults are consumed in the order they are injected.
+func (s *Server) InjectFault(path, action string, f Fault) {
+	s.lock.Lock()
+	s.faults = append(s.faults, &fault{path: path, action: action, Fault: f})
+	s.lock.Unlock()
+}
+
+// SetLatency delays the handling of every request by d, as a network round trip of d would.
+// The requests of a connection are handled in order, a request sent before the previous one is answered waits for it.
+func (s *Server) SetLatency(d time.Duration) {
+	s.lock.Lock()
+	s.latency = d
+	s.lock.Unlock()
+}
+
+// Requests returns the messages received so far.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var handler func(*session, *Request) error
+	switch r.URL.Path {
+	case "/rest/ws":
+		handler = (*session).handleWS
+	case "/rest/stmt":
+		handler = (*session).handleStmt
+	case "/rest/tmq":
+		handler = (*session).handleTMQ
+	case "/rest/schemaless":
+		handler = (*session).handleSchemaless
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.lock.Lock()
+	s.conns[ws] = struct{}{}
+	s.lock.Unlock()
+	sess := newSession(s, r.URL.Path, ws)
+	defer func() {
+		sess.close()
+		s.lock.Lock()
+		delete(s.conns, ws)
+		s.lock.Unlock()
+		ws.Close()
+	}()
+	for {
+		mt, message, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		req := &Request{Path: r.URL.Path}
+		switch mt {
+		case websocket.TextMessage:
+			var action struct {
+				Action string          `json:"action"`
+				Args   json.RawMessage `json:"args"`
+			}
+			if err = jsonI.Unmarshal(message, &action); err != nil {
+				return
+			}
+			req.Action = action.Action
+			req.Args = action.Args
+			var args struct {
+				ReqID uint64 `json:"req_id"`
+			}
+			_ = jsonI.Unmarshal(action.Args, &args)
+			req.ReqID = args.ReqID
+		case websocket.BinaryMessage:
+			// req_id, stmt_id and message type, followed by a raw block
+			if len(message) < 24 {
+				return
+			}
+			req.ReqID = binary.LittleEndian.Uint64(message)
+			req.Action = binaryAction(binary.LittleEndian.Uint64(message[16:]))
+			req.Data = message
+		default:
+			continue
+		}
+		s.lock.Lock()
+		s.requests = append(s.requests, *req)
+		f := s.takeFault(req.Path, req.Action)
+		latency := s.latency
+		s.lock.Unlock()
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		if f != nil {
+			if f.Delay > 0 {
+				time.Sleep(f.Delay)
+			}
+			if f.Disconnect {
+				return
+			}
+			if f.Code != 0 {
+				if err = sess.writeError(req.Action, req.ReqID, f.Code, f.Message); err != nil {
+					return
+				}
+				continue
+			}
+		}
+		if err = handler(sess, req); err != nil {
+			return
+		}
+	}
+}
+
+func binaryAction(messageType uint64) string {
+	switch messageType {
+	case setTagsMessage:
+		return "set_tags"
+	case bindMessage:
+		return "bind"
+	}
+	return "unknown"
+}
+
+func (s *Server) takeFault(path, action string) *fault {
+	for i, f := range s.faults {
+		if (f.path == "" || f.path == path) && (f.action == "" || strings.EqualFold(f.action, action)) {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Server) generateID() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.nextID += 1
+	return s.nextID
+}
@@ -0,0 +1,292 @@
+package wstest_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/param"
+	tmqcommon "github.com/taosdata/driver-go/v3/common/tmq"
+	taosErrors "github.com/taosdata/driver-go/v3/errors"
+	_ "github.com/taosdata/driver-go/v3/taosWS"
+	"github.com/taosdata/driver-go/v3/ws/schemaless"
+	"github.com/taosdata/driver-go/v3/ws/stmt"
+	"github.com/taosdata/driver-go/v3/ws/tmq"
+	"github.com/taosdata/driver-go/v3/ws/wstest"
+)
+
+func openDB(t *testing.T, s *wstest.Server) *sql.DB {
+	db, err := sql.Open("taosWS", fmt.Sprintf("root:taosdata@ws(%s)/test?readTimeout=5s", s.Addr()))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestQuery(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db := openDB(t, s)
+
+	_, err := db.Exec("create table t (ts timestamp, v int, s varchar(20), n nchar(10), d double)")
+	require.NoError(t, err)
+	res, err := db.Exec("insert into t values(1700000000000, 1, 'a''b', '中文', 1.5)(1700000000001, null, \"c\", null, -2)")
+	require.NoError(t, err)
+	affected, _ := res.RowsAffected()
+	assert.Equal(t, int64(2), affected)
+
+	rows, err := db.Query("select * from t")
+	require.NoError(t, err)
+	var got [][]interface{}
+	for rows.Next() {
+		var ts time.Time
+		var v sql.NullInt32
+		var str, nchar sql.NullString
+		var d float64
+		require.NoError(t, rows.Scan(&ts, &v, &str, &nchar, &d))
+		got = append(got, []interface{}{ts.UnixNano() / 1e6, v, str.String, nchar, d})
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+	assert.Equal(t, [][]interface{}{
+		{int64(1700000000000), sql.NullInt32{Int32: 1, Valid: true}, "a'b", sql.NullString{String: "中文", Valid: true}, 1.5},
+		{int64(1700000000001), sql.NullInt32{}, "c", sql.NullString{}, float64(-2)},
+	}, got)
+
+	var count int64
+	require.NoError(t, db.QueryRow("select count(*) from t").Scan(&count))
+	assert.Equal(t, int64(2), count)
+
+	_, err = db.Exec("select * from not_exist")
+	var taosErr *taosErrors.TaosError
+	require.ErrorAs(t, err, &taosErr)
+	assert.Equal(t, int32(wstest.CodeTableNotExist), taosErr.Code)
+}
+
+func TestHandleQuery(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	s.HandleQuery(`^select .* from meters where`, &wstest.Result{
+		Fields: []wstest.Field{
+			{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+			{Name: "current", Type: common.TSDB_DATA_TYPE_FLOAT, Length: 4},
+		},
+		Rows: [][]driver.Value{{time.Unix(1, 0), float32(10.5)}},
+	})
+	s.HandleQuery(`^delete`, &wstest.Result{Code: 0x2600, Message: "syntax error"})
+	db := openDB(t, s)
+
+	var ts time.Time
+	var current float32
+	require.NoError(t, db.QueryRow("select ts, current from meters where voltage > 1").Scan(&ts, &current))
+	assert.Equal(t, time.Unix(1, 0).UnixNano(), ts.UnixNano())
+	assert.Equal(t, float32(10.5), current)
+
+	_, err := db.Exec("delete from meters")
+	assert.EqualError(t, err, "[0x2600] syntax error")
+}
+
+func TestStmtOverTaosWS(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	s.CreateTable("t",
+		wstest.Field{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		wstest.Field{Name: "v", Type: common.TSDB_DATA_TYPE_BIGINT, Length: 8},
+	)
+	db := openDB(t, s)
+
+	st, err := db.Prepare("insert into t values(?, ?)")
+	require.NoError(t, err)
+	now := time.Now().Truncate(time.Millisecond)
+	_, err = st.Exec(now, int64(100))
+	require.NoError(t, err)
+	require.NoError(t, st.Close())
+	assert.Equal(t, [][]driver.Value{{now, int64(100)}}, s.Rows("t"))
+
+	st, err = db.Prepare("select * from t limit ?")
+	require.NoError(t, err)
+	var ts time.Time
+	var v int64
+	require.NoError(t, st.QueryRow(int64(1)).Scan(&ts, &v))
+	assert.Equal(t, now.UnixNano(), ts.UnixNano())
+	assert.Equal(t, int64(100), v)
+	require.NoError(t, st.Close())
+}
+
+func TestFault(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db := openDB(t, s)
+	db.SetMaxIdleConns(1)
+	require.NoError(t, db.Ping())
+
+	s.InjectFault("/rest/ws", "query", wstest.Fault{Code: 0x0019, Message: "timeout"})
+	_, err := db.Exec("create database test")
+	assert.EqualError(t, err, "[0x19] timeout")
+
+	// a pinned connection surfaces the disconnect, database/sql retries it otherwise
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	s.InjectFault("/rest/ws", "query", wstest.Fault{Disconnect: true})
+	_, err = conn.ExecContext(context.Background(), "create database test")
+	assert.Error(t, err)
+	conn.Close()
+
+	_, err = db.Exec("create database test")
+	assert.NoError(t, err)
+
+	bad, _ := sql.Open("taosWS", fmt.Sprintf("root:wrong@ws(%s)/", s.Addr()))
+	defer bad.Close()
+	err = bad.Ping()
+	var taosErr *taosErrors.TaosError
+	require.ErrorAs(t, err, &taosErr)
+	assert.Equal(t, int32(wstest.CodeAuthFailure), taosErr.Code)
+
+	var actions []string
+	for _, req := range s.Requests() {
+		if req.Path == "/rest/ws" && req.Action == "query" {
+			actions = append(actions, req.Action)
+		}
+	}
+	assert.Len(t, actions, 3)
+}
+
+func TestStmt(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	db := openDB(t, s)
+	_, err := db.Exec("create stable meters (ts timestamp, current float) tags (location varchar(20))")
+	require.NoError(t, err)
+
+	config := stmt.NewConfig(s.URL()+"/rest/stmt", 0)
+	config.SetConnectUser("root")
+	config.SetConnectPass("taosdata")
+	config.SetConnectDB("test")
+	config.SetMessageTimeout(5 * time.Second)
+	connector, err := stmt.NewConnector(config)
+	require.NoError(t, err)
+	defer connector.Close()
+	st, err := connector.Init()
+	require.NoError(t, err)
+	require.NoError(t, st.Prepare("insert into ? using meters tags(?) values(?, ?)"))
+	require.NoError(t, st.SetTableName("d0"))
+	require.NoError(t, st.SetTags(param.NewParam(1).AddBinary([]byte("beijing")), param.NewColumnType(1).AddBinary(7)))
+	now := time.Now().Truncate(time.Millisecond)
+	params := []*param.Param{
+		param.NewParam(2).AddTimestamp(now, common.PrecisionMilliSecond).AddTimestamp(now.Add(time.Millisecond), common.PrecisionMilliSecond),
+		param.NewParam(2).AddFloat(1.5).AddNull(),
+	}
+	require.NoError(t, st.BindParam(params, param.NewColumnType(2).AddTimestamp().AddFloat()))
+	require.NoError(t, st.AddBatch())
+	require.NoError(t, st.Exec())
+	assert.Equal(t, 2, st.GetAffectedRows())
+	require.NoError(t, st.Close())
+	assert.Equal(t, [][]driver.Value{
+		{now, float32(1.5)},
+		{now.Add(time.Millisecond), nil},
+	}, s.Rows("d0"))
+
+	s.InjectFault("/rest/stmt", "init", wstest.Fault{Code: 0x0118, Message: "invalid"})
+	_, err = connector.Init()
+	assert.EqualError(t, err, "[0x118] invalid")
+}
+
+func TestSchemaless(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	sml, err := schemaless.NewSchemaless(schemaless.NewConfig(s.URL(), 0,
+		schemaless.SetUser("root"),
+		schemaless.SetPassword("taosdata"),
+		schemaless.SetDb("test"),
+		schemaless.SetReadTimeout(5*time.Second),
+	))
+	require.NoError(t, err)
+	defer sml.Close()
+	line := "measurement,host=host1 field1=2i 1626006833639000000"
+	require.NoError(t, sml.Insert(line, schemaless.InfluxDBLineProtocol, "ns", 0, 0))
+	assert.Equal(t, []wstest.SchemalessRequest{{
+		DB:        "test",
+		Protocol:  schemaless.InfluxDBLineProtocol,
+		Precision: "ns",
+		Data:      line,
+	}}, s.SchemalessRequests())
+	assert.Error(t, sml.Insert(line, 4, "ns", 0, 0))
+}
+
+func TestTMQ(t *testing.T) {
+	s := wstest.NewServer()
+	defer s.Close()
+	fields := []wstest.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		{Name: "v", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+	}
+	ts := time.Unix(1700000000, 0)
+	s.CreateTopic("topic", 2, 3)
+	s.Produce("topic", wstest.Message{
+		Database: "test",
+		VgroupID: 2,
+		Blocks:   []wstest.Block{{TableName: "t", Fields: fields, Rows: [][]driver.Value{{ts, int32(1)}, {ts.Add(time.Second), nil}}}},
+	})
+
+	consumer, err := tmq.NewConsumer(&tmqcommon.ConfigMap{
+		"ws.url":             s.URL() + "/rest/tmq",
+		"ws.message.timeout": 5 * time.Second,
+		"td.connect.user":    "root",
+		"td.connect.pass":    "taosdata",
+		"group.id":           "g1",
+		"auto.offset.reset":  "earliest",
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+	require.NoError(t, consumer.Subscribe("topic", nil))
+
+	ev := consumer.Poll(100)
+	msg, ok := ev.(*tmqcommon.DataMessage)
+	require.True(t, ok, "%v", ev)
+	assert.Equal(t, "test", msg.DBName())
+	assert.Equal(t, int32(2), msg.TopicPartition.Partition)
+	data := msg.Value().([]*tmqcommon.Data)
+	require.Len(t, data, 1)
+	assert.Equal(t, "t", data[0].TableName)
+	assert.Equal(t, []*tmqcommon.Field{
+		{Name: "ts", Type: common.TSDB_DATA_TYPE_TIMESTAMP, Length: 8},
+		{Name: "v", Type: common.TSDB_DATA_TYPE_INT, Length: 4},
+	}, data[0].Fields)
+	assert.Equal(t, 1, data[0].FieldIndex("v"))
+	assert.Equal(t, [][]driver.Value{{ts, int32(1)}, {ts.Add(time.Second), nil}}, data[0].Data)
+
+	// a poll waits for the next message
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.Produce("topic", wstest.Message{Database: "test", VgroupID: 3})
+	}()
+	ev = consumer.Poll(5000)
+	msg, ok = ev.(*tmqcommon.DataMessage)
+	require.True(t, ok, "%v", ev)
+	assert.Equal(t, int32(3), msg.TopicPartition.Partition)
+	assert.Nil(t, consumer.Poll(10))
+
+	committed, err := consumer.Commit()
+	require.NoError(t, err)
+	assert.Len(t, committed, 2)
+	assert.Equal(t, tmqcommon.Offset(1), s.Committed("g1", "topic", 2))
+
+	partitions, err := consumer.Assignment()
+	require.NoError(t, err)
+	require.Len(t, partitions, 2)
+	partitions[0].Offset = 0
+	require.NoError(t, consumer.Seek(partitions[0], 0))
+	msg, ok = consumer.Poll(100).(*tmqcommon.DataMessage)
+	require.True(t, ok)
+	assert.Equal(t, tmqcommon.Offset(0), msg.Offset())
+
+	s.InjectFault("/rest/tmq", "poll", wstest.Fault{Code: 0x0019, Message: "timeout"})
+	tmqErr, ok := consumer.Poll(100).(tmqcommon.Error)
+	require.True(t, ok)
+	assert.True(t, tmqErr.IsRetriable())
+}
@@ -0,0 +1,401 @@
+package wstest
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+const (
+	setTagsMessage = 1
+	bindMessage    = 2
+)
+
+// args holds the fields of every action handled by the server
+type args struct {
+	ReqID          uint64          `json:"req_id"`
+	User           string          `json:"user"`
+	Password       string          `json:"password"`
+	DB             string          `json:"db"`
+	SQL            string          `json:"sql"`
+	ID             uint64          `json:"id"`
+	StmtID         uint64          `json:"stmt_id"`
+	Name           string          `json:"name"`
+	Protocol       int             `json:"protocol"`
+	Precision      string          `json:"precision"`
+	TTL            int             `json:"ttl"`
+	Data           string          `json:"data"`
+	GroupID        string          `json:"group_id"`
+	ClientID       string          `json:"client_id"`
+	OffsetRest     string          `json:"offset_rest"`
+	Topics         []string        `json:"topics"`
+	BlockingTime   int64           `json:"blocking_time"`
+	MessageID      uint64          `json:"message_id"`
+	Topic          string          `json:"topic"`
+	VgroupID       int32           `json:"vgroup_id"`
+	Offset         int64           `json:"offset"`
+	TopicVgroupIDs []topicVgroupID `json:"topic_vgroup_ids"`
+}
+
+type topicVgroupID struct {
+	Topic    string `json:"topic"`
+	VgroupID int32  `json:"vgroup_id"`
+}
+
+type response map[string]interface{}
+
+// resultSet is a query result waiting to be fetched
+type resultSet struct {
+	result  *Result
+	fetched bool
+}
+
+// stmtState is a prepared statement of /rest/ws or /rest/stmt
+type stmtState struct {
+	sql      string
+	isInsert bool
+	target   *insertTarget
+	table    string
+	pending  [][]driver.Value
+	batch    [][]driver.Value
+	result   *Result
+}
+
+// session is the state of one WebSocket connection
+type session struct {
+	server    *Server
+	path      string
+	ws        *websocket.Conn
+	connected bool
+	db        string
+	results   map[uint64]*resultSet
+	stmts     map[uint64]*stmtState
+	consumer  *consumer
+}
+
+func newSession(s *Server, path string, ws *websocket.Conn) *session {
+	return &session{
+		server:  s,
+		path:    path,
+		ws:      ws,
+		results: make(map[uint64]*resultSet),
+		stmts:   make(map[uint64]*stmtState),
+	}
+}
+
+func (sess *session) close() {
+	if sess.consumer != nil {
+		sess.consumer.close()
+	}
+}
+
+func (sess *session) parseArgs(req *Request) (*args, error) {
+	var a args
+	if req.Args == nil {
+		return &a, nil
+	}
+	return &a, jsonI.Unmarshal(req.Args, &a)
+}
+
+func (sess *session) write(action string, reqID uint64, fields response) error {
+	resp := response{
+		"code":    0,
+		"message": "",
+		"action":  action,
+		"req_id":  reqID,
+		"timing":  0,
+	}
+	for k, v := range fields {
+		resp[k] = v
+	}
+	b, err := jsonI.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return sess.ws.WriteMessage(websocket.TextMessage, b)
+}
+
+func (sess *session) writeError(action string, reqID uint64, code int, message string) error {
+	return sess.write(action, reqID, response{"code": code, "message": message})
+}
+
+func (sess *session) writeResult(action string, reqID uint64, result *Result) error {
+	return sess.writeError(action, reqID, result.Code, result.Message)
+}
+
+// writeBinary sends the header words followed by block
+func (sess *session) writeBinary(block []byte, header ...uint64) error {
+	b := make([]byte, 8*len(header), 8*len(header)+len(block))
+	for i, h := range header {
+		binary.LittleEndian.PutUint64(b[8*i:], h)
+	}
+	return sess.ws.WriteMessage(websocket.BinaryMessage, append(b, block...))
+}
+
+// connect checks the credentials of a conn action
+func (sess *session) connect(action string, a *args) error {
+	s := sess.server
+	s.lock.Lock()
+	ok := a.User == s.user && a.Password == s.password
+	s.lock.Unlock()
+	if !ok {
+		return sess.writeError(action, a.ReqID, CodeAuthFailure, "Authentication failure")
+	}
+	sess.connected = true
+	sess.db = a.DB
+	return sess.write(action, a.ReqID, nil)
+}
+
+func (sess *session) notConnected(action string, reqID uint64) error {
+	return sess.writeError(action, reqID, 0xffff, "server not connected")
+}
+
+// handleWS serves /rest/ws, used by taosWS
+func (sess *session) handleWS(req *Request) error {
+	a, err := sess.parseArgs(req)
+	if err != nil {
+		return err
+	}
+	if req.Action != "conn" && !sess.connected {
+		return sess.notConnected(req.Action, req.ReqID)
+	}
+	switch req.Action {
+	case "conn":
+		return sess.connect(req.Action, a)
+	case "query":
+		result := sess.server.query(a.SQL)
+		return sess.writeQueryResult(req.Action, a.ReqID, result)
+	case "fetch":
+		rs, exist := sess.results[a.ID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "result is nil")
+		}
+		if rs.fetched || len(rs.result.Rows) == 0 {
+			return sess.write(req.Action, a.ReqID, response{"id": a.ID, "completed": true})
+		}
+		lengths := make([]int64, len(rs.result.Fields))
+		for i, field := range rs.result.Fields {
+			lengths[i] = field.Length
+		}
+		return sess.write(req.Action, a.ReqID, response{
+			"id":        a.ID,
+			"completed": false,
+			"lengths":   lengths,
+			"rows":      len(rs.result.Rows),
+		})
+	case "fetch_block":
+		rs, exist := sess.results[a.ID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "result is nil")
+		}
+		block, err := encodeBlock(rs.result.Fields, rs.result.Rows, rs.result.Precision)
+		if err != nil {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, err.Error())
+		}
+		rs.fetched = true
+		// timing and result id
+		return sess.writeBinary(block, 0, a.ID)
+	case "free_result":
+		delete(sess.results, a.ID)
+		return nil
+	case "stmt_init":
+		id := sess.server.generateID()
+		sess.stmts[id] = &stmtState{}
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": id})
+	case "stmt_prepare":
+		stmt, exist := sess.stmts[a.StmtID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt is nil")
+		}
+		if result := stmt.prepare(a.SQL); result != nil {
+			return sess.writeResult(req.Action, a.ReqID, result)
+		}
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": a.StmtID, "is_insert": stmt.isInsert})
+	case "stmt_get_col_fields":
+		stmt, exist := sess.stmts[a.StmtID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt is nil")
+		}
+		fields, result := sess.stmtFields(stmt)
+		if result != nil {
+			return sess.writeResult(req.Action, a.ReqID, result)
+		}
+		stmtFields := make([]response, len(fields))
+		for i, field := range fields {
+			stmtFields[i] = response{"name": field.Name, "field_type": field.Type, "precision": 0, "scale": 0, "bytes": field.Length}
+		}
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": a.StmtID, "fields": stmtFields})
+	case "bind":
+		return sess.bind(req)
+	case "stmt_add_batch":
+		return sess.addBatch(req.Action, a)
+	case "stmt_exec":
+		return sess.exec(req.Action, a)
+	case "stmt_use_result":
+		stmt, exist := sess.stmts[a.StmtID]
+		if !exist || stmt.result == nil || stmt.result.Fields == nil {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt has no result")
+		}
+		id := sess.server.generateID()
+		sess.results[id] = &resultSet{result: stmt.result}
+		resp := queryFields(stmt.result)
+		resp["stmt_id"] = a.StmtID
+		resp["result_id"] = id
+		return sess.write(req.Action, a.ReqID, resp)
+	case "stmt_close":
+		delete(sess.stmts, a.StmtID)
+		return nil
+	}
+	return sess.writeError(req.Action, req.ReqID, CodeInvalidPara, "unknown action "+req.Action)
+}
+
+func queryFields(result *Result) response {
+	names := make([]string, len(result.Fields))
+	types := make([]uint8, len(result.Fields))
+	lengths := make([]int64, len(result.Fields))
+	for i, field := range result.Fields {
+		names[i] = field.Name
+		types[i] = field.Type
+		lengths[i] = field.Length
+	}
+	return response{
+		"fields_count":   len(result.Fields),
+		"fields_names":   names,
+		"fields_types":   types,
+		"fields_lengths": lengths,
+		"precision":      result.Precision,
+	}
+}
+
+func (sess *session) writeQueryResult(action string, reqID uint64, result *Result) error {
+	if result.Code != 0 {
+		return sess.writeResult(action, reqID, result)
+	}
+	if result.Fields == nil {
+		return sess.write(action, reqID, response{"is_update": true, "affected_rows": result.AffectedRows})
+	}
+	id := sess.server.generateID()
+	sess.results[id] = &resultSet{result: result}
+	resp := queryFields(result)
+	resp["id"] = id
+	return sess.write(action, reqID, resp)
+}
+
+// prepare parses the sql of a stmt, a non nil Result is an error
+func (stmt *stmtState) prepare(sql string) *Result {
+	stmt.sql = sql
+	stmt.isInsert = strings.HasPrefix(strings.ToLower(strings.TrimSpace(sql)), "insert")
+	if !stmt.isInsert {
+		return nil
+	}
+	p := &sqlParser{s: sql}
+	if !p.keyword("insert") || !p.keyword("into") {
+		return errorResult(CodeSyntaxError, "syntax error near '%s'", sql)
+	}
+	target, err := p.insertTarget()
+	if err == nil {
+		_, err = p.values()
+	}
+	if err != nil {
+		return errorResult(CodeSyntaxError, "%s", err)
+	}
+	stmt.target = target
+	if target.name != "?" {
+		stmt.table = target.name
+	}
+	return nil
+}
+
+// stmtFields returns the columns bound by an insert stmt
+func (sess *session) stmtFields(stmt *stmtState) ([]Field, *Result) {
+	if !stmt.isInsert {
+		return nil, errorResult(CodeInvalidPara, "stmt is not an insert")
+	}
+	s := sess.server
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, errResult := s.insertTable(stmt.table, stmt.target.super)
+	if errResult != nil {
+		return nil, errResult
+	}
+	if len(stmt.target.columns) == 0 {
+		return t.fields, nil
+	}
+	fields := make([]Field, 0, len(stmt.target.columns))
+	for _, column := range stmt.target.columns {
+		for _, field := range t.fields {
+			if strings.EqualFold(field.Name, column) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields, nil
+}
+
+// bind handles the binary bind and set_tags messages of /rest/ws and /rest/stmt
+func (sess *session) bind(req *Request) error {
+	action := req.Action
+	stmtID := binary.LittleEndian.Uint64(req.Data[8:])
+	stmt, exist := sess.stmts[stmtID]
+	if !exist {
+		return sess.writeError(action, req.ReqID, CodeInvalidPara, "stmt is nil")
+	}
+	_, rows, err := decodeBlock(req.Data[24:], common.PrecisionMilliSecond)
+	if err != nil {
+		return sess.writeError(action, req.ReqID, CodeInvalidPara, err.Error())
+	}
+	if action == "bind" {
+		stmt.pending = append(stmt.pending, rows...)
+	}
+	return sess.write(action, req.ReqID, response{"stmt_id": stmtID})
+}
+
+func (sess *session) addBatch(action string, a *args) error {
+	stmt, exist := sess.stmts[a.StmtID]
+	if !exist {
+		return sess.writeError(action, a.ReqID, CodeInvalidPara, "stmt is nil")
+	}
+	stmt.batch = append(stmt.batch, stmt.pending...)
+	stmt.pending = nil
+	return sess.write(action, a.ReqID, response{"stmt_id": a.StmtID})
+}
+
+// exec inserts the batched rows, or runs a query with the bound parameters
+func (sess *session) exec(action string, a *args) error {
+	stmt, exist := sess.stmts[a.StmtID]
+	if !exist {
+		return sess.writeError(action, a.ReqID, CodeInvalidPara, "stmt is nil")
+	}
+	rows := append(stmt.batch, stmt.pending...)
+	stmt.batch, stmt.pending = nil, nil
+	if !stmt.isInsert {
+		var bound []driver.Value
+		if len(rows) > 0 {
+			bound = rows[0]
+		}
+		sql, err := bindSQL(stmt.sql, bound)
+		if err != nil {
+			return sess.writeError(action, a.ReqID, CodeInvalidPara, err.Error())
+		}
+		stmt.result = sess.server.query(sql)
+		if stmt.result.Code != 0 {
+			return sess.writeResult(action, a.ReqID, stmt.result)
+		}
+		return sess.write(action, a.ReqID, response{"stmt_id": a.StmtID, "affected": stmt.result.AffectedRows})
+	}
+	s := sess.server
+	s.lock.Lock()
+	t, errResult := s.insertTable(stmt.table, stmt.target.super)
+	var affected int
+	if errResult == nil {
+		affected, errResult = t.appendRows(stmt.target.columns, rows)
+	}
+	s.lock.Unlock()
+	if errResult != nil {
+		return sess.writeResult(action, a.ReqID, errResult)
+	}
+	return sess.write(action, a.ReqID, response{"stmt_id": a.StmtID, "affected": affected})
+}
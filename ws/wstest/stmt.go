@@ -0,0 +1,58 @@
+package wstest
+
+import (
+	"encoding/binary"
+)
+
+// handleStmt serves /rest/stmt, used by ws/stmt
+func (sess *session) handleStmt(req *Request) error {
+	a, err := sess.parseArgs(req)
+	if err != nil {
+		return err
+	}
+	if req.Action != "conn" && !sess.connected {
+		return sess.notConnected(req.Action, req.ReqID)
+	}
+	switch req.Action {
+	case "conn":
+		return sess.connect(req.Action, a)
+	case "init":
+		id := sess.server.generateID()
+		sess.stmts[id] = &stmtState{}
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": id})
+	case "prepare":
+		stmt, exist := sess.stmts[a.StmtID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt is nil")
+		}
+		if result := stmt.prepare(a.SQL); result != nil {
+			return sess.writeResult(req.Action, a.ReqID, result)
+		}
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": a.StmtID})
+	case "set_table_name":
+		stmt, exist := sess.stmts[a.StmtID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt is nil")
+		}
+		if !stmt.isInsert {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "stmt is not an insert")
+		}
+		stmt.table = a.Name
+		return sess.write(req.Action, a.ReqID, response{"stmt_id": a.StmtID})
+	case "set_tags", "bind":
+		stmtID := binary.LittleEndian.Uint64(req.Data[8:])
+		stmt, exist := sess.stmts[stmtID]
+		if exist && stmt.table == "" && stmt.isInsert {
+			return sess.writeError(req.Action, req.ReqID, CodeInvalidPara, "table name is not set")
+		}
+		return sess.bind(req)
+	case "add_batch":
+		return sess.addBatch(req.Action, a)
+	case "exec":
+		return sess.exec(req.Action, a)
+	case "close":
+		delete(sess.stmts, a.StmtID)
+		return nil
+	}
+	return sess.writeError(req.Action, req.ReqID, CodeInvalidPara, "unknown action "+req.Action)
+}
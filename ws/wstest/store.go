@@ -0,0 +1,451 @@
+package wstest
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+)
+
+// Error codes returned by the built-in SQL engine.
+const (
+	CodeInvalidPara   = 0x0118
+	CodeAuthFailure   = 0x0357
+	CodeSyntaxError   = 0x2600
+	CodeTableExists   = 0x2603
+	CodeTableNotExist = 0x2662
+)
+
+// Field is a column of a table or of a result.
+type Field struct {
+	Name   string
+	Type   uint8 // common.TSDB_DATA_TYPE_*
+	Length int64 // bytes of VARCHAR, NCHAR, VARBINARY, GEOMETRY and JSON columns
+}
+
+// Result is the response to a SQL statement. A Result with Fields is a query result,
+// otherwise AffectedRows are reported. A non-zero Code is returned as an error.
+type Result struct {
+	Fields       []Field
+	Rows         [][]driver.Value
+	AffectedRows int
+	Precision    int // common.PrecisionMilliSecond, common.PrecisionMicroSecond or common.PrecisionNanoSecond
+	Code         int
+	Message      string
+}
+
+func errorResult(code int, format string, args ...interface{}) *Result {
+	return &Result{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+type queryHandler struct {
+	pattern *regexp.Regexp
+	handle  func(sql string) *Result
+}
+
+type table struct {
+	fields []Field
+	tags   []Field
+	super  string
+	rows   [][]driver.Value
+}
+
+// HandleQuery makes every SQL statement matching pattern (a case-insensitive regular expression) return result,
+// before the built-in SQL engine is consulted. Handlers are tried in the order they are registered.
+func (s *Server) HandleQuery(pattern string, result *Result) {
+	s.HandleQueryFunc(pattern, func(string) *Result {
+		return result
+	})
+}
+
+// HandleQueryFunc is like HandleQuery but the result is computed by f for each matching statement.
+func (s *Server) HandleQueryFunc(pattern string, f func(sql string) *Result) {
+	re := regexp.MustCompile("(?is)" + pattern)
+	s.lock.Lock()
+	s.handlers = append(s.handlers, &queryHandler{pattern: re, handle: f})
+	s.lock.Unlock()
+}
+
+// CreateTable creates (or replaces) a table with the given columns.
+func (s *Server) CreateTable(name string, fields ...Field) {
+	s.lock.Lock()
+	s.tables[tableKey(name)] = &table{fields: fields}
+	s.lock.Unlock()
+}
+
+// Rows returns a copy of the rows stored in a table, nil if the table does not exist.
+func (s *Server) Rows(name string) [][]driver.Value {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, exist := s.tables[tableKey(name)]
+	if !exist {
+		return nil
+	}
+	rows := make([][]driver.Value, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = append([]driver.Value(nil), row...)
+	}
+	return rows
+}
+
+// tableKey strips the database name and quotes of a table name
+func tableKey(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "`"))
+}
+
+var (
+	createTableRe = regexp.MustCompile(`(?is)^create\s+(stable|table)\s+(if\s+not\s+exists\s+)?([\w.` + "`" + `]+)\s*(.*)$`)
+	dropTableRe   = regexp.MustCompile(`(?is)^drop\s+(stable|table)\s+(if\s+exists\s+)?([\w.` + "`" + `]+)$`)
+	selectRe      = regexp.MustCompile(`(?is)^select\s+(\*|count\(\*\))\s+from\s+([\w.` + "`" + `]+)(\s+limit\s+(\d+))?$`)
+	columnDefRe   = regexp.MustCompile(`(?is)^([\w` + "`" + `]+)\s+(\w+(\s+unsigned)?)(\s*\(\s*(\d+)\s*\))?`)
+)
+
+// query runs sql against the canned handlers and then against the in-memory tables
+func (s *Server) query(sql string) *Result {
+	sql = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sql), ";"))
+	s.lock.Lock()
+	handlers := s.handlers
+	s.lock.Unlock()
+	for _, h := range handlers {
+		if h.pattern.MatchString(sql) {
+			if result := h.handle(sql); result != nil {
+				return result
+			}
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	lower := strings.ToLower(sql)
+	switch {
+	case strings.HasPrefix(lower, "create table") || strings.HasPrefix(lower, "create stable"):
+		return s.createTable(sql)
+	case strings.HasPrefix(lower, "drop table") || strings.HasPrefix(lower, "drop stable"):
+		m := dropTableRe.FindStringSubmatch(sql)
+		if m == nil {
+			return errorResult(CodeSyntaxError, "syntax error near '%s'", sql)
+		}
+		if _, exist := s.tables[tableKey(m[3])]; !exist && m[2] == "" {
+			return errorResult(CodeTableNotExist, "Table does not exist")
+		}
+		delete(s.tables, tableKey(m[3]))
+		return &Result{}
+	case strings.HasPrefix(lower, "create"), strings.HasPrefix(lower, "drop"), strings.HasPrefix(lower, "alter"),
+		strings.HasPrefix(lower, "use"), strings.HasPrefix(lower, "flush"):
+		return &Result{}
+	case strings.HasPrefix(lower, "insert"):
+		return s.insert(sql)
+	case lower == "select server_version()":
+		return &Result{
+			Fields: []Field{{Name: "server_version()", Type: common.TSDB_DATA_TYPE_BINARY, Length: 32}},
+			Rows:   [][]driver.Value{{"3.0.0.0"}},
+		}
+	case strings.HasPrefix(lower, "select"):
+		return s.selectTable(sql)
+	}
+	return errorResult(CodeSyntaxError, "syntax error near '%s'", sql)
+}
+
+func (s *Server) createTable(sql string) *Result {
+	m := createTableRe.FindStringSubmatch(sql)
+	if m == nil {
+		return errorResult(CodeSyntaxError, "syntax error near '%s'", sql)
+	}
+	key := tableKey(m[3])
+	if _, exist := s.tables[key]; exist {
+		if m[2] != "" {
+			return &Result{}
+		}
+		return errorResult(CodeTableExists, "Table already exists")
+	}
+	rest := strings.TrimSpace(m[4])
+	if strings.HasPrefix(strings.ToLower(rest), "using") {
+		p := &sqlParser{s: rest[len("using"):]}
+		super, err := p.ident()
+		if err != nil {
+			return errorResult(CodeSyntaxError, "%s", err)
+		}
+		stable, exist := s.tables[tableKey(super)]
+		if !exist {
+			return errorResult(CodeTableNotExist, "Table does not exist")
+		}
+		s.tables[key] = &table{fields: stable.fields, super: tableKey(super)}
+		return &Result{}
+	}
+	p := &sqlParser{s: rest}
+	fields, err := p.columnDefs()
+	if err != nil {
+		return errorResult(CodeSyntaxError, "%s", err)
+	}
+	t := &table{fields: fields}
+	if p.keyword("tags") {
+		if t.tags, err = p.columnDefs(); err != nil {
+			return errorResult(CodeSyntaxError, "%s", err)
+		}
+	}
+	s.tables[key] = t
+	return &Result{}
+}
+
+func (s *Server) selectTable(sql string) *Result {
+	m := selectRe.FindStringSubmatch(sql)
+	if m == nil {
+		return errorResult(CodeSyntaxError, "wstest only supports 'select * from <table>' and 'select count(*) from <table>'")
+	}
+	t, exist := s.tables[tableKey(m[2])]
+	if !exist {
+		return errorResult(CodeTableNotExist, "Table does not exist")
+	}
+	rows := t.rows
+	if m[4] != "" {
+		limit, _ := strconv.Atoi(m[4])
+		if limit < len(rows) {
+			rows = rows[:limit]
+		}
+	}
+	if strings.EqualFold(m[1], "count(*)") {
+		return &Result{
+			Fields: []Field{{Name: "count(*)", Type: common.TSDB_DATA_TYPE_BIGINT, Length: 8}},
+			Rows:   [][]driver.Value{{int64(len(rows))}},
+		}
+	}
+	result := &Result{Fields: t.fields, Rows: make([][]driver.Value, len(rows))}
+	copy(result.Rows, rows)
+	return result
+}
+
+// insert supports `insert into tb [using stb tags(...)] [(cols)] values(...)... [tb2 ...]`
+func (s *Server) insert(sql string) *Result {
+	p := &sqlParser{s: sql}
+	if !p.keyword("insert") || !p.keyword("into") {
+		return errorResult(CodeSyntaxError, "syntax error near '%s'", sql)
+	}
+	affected := 0
+	for !p.eof() {
+		target, err := p.insertTarget()
+		if err != nil {
+			return errorResult(CodeSyntaxError, "%s", err)
+		}
+		t, errResult := s.insertTable(target.name, target.super)
+		if errResult != nil {
+			return errResult
+		}
+		var rows [][]driver.Value
+		for p.peek() == '(' {
+			values, err := p.values()
+			if err != nil {
+				return errorResult(CodeSyntaxError, "%s", err)
+			}
+			rows = append(rows, values)
+		}
+		if len(rows) == 0 {
+			return errorResult(CodeSyntaxError, "syntax error near '%s'", p.rest())
+		}
+		n, errResult := t.appendRows(target.columns, rows)
+		if errResult != nil {
+			return errResult
+		}
+		affected += n
+	}
+	return &Result{AffectedRows: affected}
+}
+
+// insertTable finds the table of an insert, a child table is created automatically when stable is set
+func (s *Server) insertTable(name, stable string) (*table, *Result) {
+	t, exist := s.tables[tableKey(name)]
+	if exist {
+		return t, nil
+	}
+	if stable != "" {
+		super, exist := s.tables[tableKey(stable)]
+		if exist {
+			t = &table{fields: super.fields, super: tableKey(stable)}
+			s.tables[tableKey(name)] = t
+			return t, nil
+		}
+	}
+	return nil, errorResult(CodeTableNotExist, "Table does not exist")
+}
+
+// appendRows converts and stores rows, columns selects the fields the values belong to (all when empty)
+func (t *table) appendRows(columns []string, rows [][]driver.Value) (int, *Result) {
+	index := make([]int, len(t.fields))
+	if len(columns) == 0 {
+		for i := range index {
+			index[i] = i
+		}
+	} else {
+		for i := range index {
+			index[i] = -1
+		}
+		for i, column := range columns {
+			found := false
+			for j, field := range t.fields {
+				if strings.EqualFold(field.Name, column) {
+					index[j] = i
+					found = true
+				}
+			}
+			if !found {
+				return 0, errorResult(CodeInvalidPara, "Invalid column name: %s", column)
+			}
+		}
+	}
+	converted := make([][]driver.Value, len(rows))
+	for r, values := range rows {
+		if len(columns) == 0 && len(values) != len(t.fields) || len(columns) != 0 && len(values) != len(columns) {
+			return 0, errorResult(CodeInvalidPara, "Illegal number of columns")
+		}
+		row := make([]driver.Value, len(t.fields))
+		for i, field := range t.fields {
+			if index[i] < 0 {
+				continue
+			}
+			v, err := convertValue(field.Type, values[index[i]], common.PrecisionMilliSecond)
+			if err != nil {
+				return 0, errorResult(CodeInvalidPara, "column %s: %s", field.Name, err)
+			}
+			row[i] = v
+		}
+		converted[r] = row
+	}
+	t.rows = append(t.rows, converted...)
+	return len(converted), nil
+}
+
+// convertValue converts v to the Go type the parser returns for type t:
+// bool, int8 to int64, uint8 to uint64, float32, float64, string for VARCHAR and NCHAR,
+// []byte for VARBINARY, GEOMETRY and JSON, time.Time for TIMESTAMP.
+func convertValue(t uint8, v driver.Value, precision int) (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch t {
+	case common.TSDB_DATA_TYPE_BOOL:
+		switch rv.Kind() {
+		case reflect.Bool:
+			return rv.Bool(), nil
+		case reflect.String:
+			return strconv.ParseBool(rv.String())
+		}
+		if f, ok := toFloat(rv); ok {
+			return f != 0, nil
+		}
+	case common.TSDB_DATA_TYPE_TINYINT, common.TSDB_DATA_TYPE_SMALLINT, common.TSDB_DATA_TYPE_INT, common.TSDB_DATA_TYPE_BIGINT:
+		i, ok := toInt(rv)
+		if !ok {
+			break
+		}
+		switch t {
+		case common.TSDB_DATA_TYPE_TINYINT:
+			return int8(i), nil
+		case common.TSDB_DATA_TYPE_SMALLINT:
+			return int16(i), nil
+		case common.TSDB_DATA_TYPE_INT:
+			return int32(i), nil
+		default:
+			return i, nil
+		}
+	case common.TSDB_DATA_TYPE_UTINYINT, common.TSDB_DATA_TYPE_USMALLINT, common.TSDB_DATA_TYPE_UINT, common.TSDB_DATA_TYPE_UBIGINT:
+		var u uint64
+		switch rv.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = rv.Uint()
+		default:
+			i, ok := toInt(rv)
+			if !ok || i < 0 {
+				return nil, fmt.Errorf("can not convert %v to an unsigned integer", v)
+			}
+			u = uint64(i)
+		}
+		switch t {
+		case common.TSDB_DATA_TYPE_UTINYINT:
+			return uint8(u), nil
+		case common.TSDB_DATA_TYPE_USMALLINT:
+			return uint16(u), nil
+		case common.TSDB_DATA_TYPE_UINT:
+			return uint32(u), nil
+		default:
+			return u, nil
+		}
+	case common.TSDB_DATA_TYPE_FLOAT:
+		if f, ok := toFloat(rv); ok {
+			return float32(f), nil
+		}
+	case common.TSDB_DATA_TYPE_DOUBLE:
+		if f, ok := toFloat(rv); ok {
+			return f, nil
+		}
+	case common.TSDB_DATA_TYPE_BINARY, common.TSDB_DATA_TYPE_NCHAR:
+		switch val := v.(type) {
+		case string:
+			return val, nil
+		case []byte:
+			return string(val), nil
+		}
+	case common.TSDB_DATA_TYPE_VARBINARY, common.TSDB_DATA_TYPE_GEOMETRY, common.TSDB_DATA_TYPE_JSON:
+		switch val := v.(type) {
+		case string:
+			return []byte(val), nil
+		case []byte:
+			return append([]byte(nil), val...), nil
+		}
+	case common.TSDB_DATA_TYPE_TIMESTAMP:
+		switch val := v.(type) {
+		case time.Time:
+			return val, nil
+		case string:
+			for _, layout := range []string{"2006-01-02 15:04:05.999999999", time.RFC3339Nano} {
+				if ts, err := time.ParseInLocation(layout, val, time.Local); err == nil {
+					return ts, nil
+				}
+			}
+			return nil, fmt.Errorf("invalid timestamp %q", val)
+		}
+		if i, ok := toInt(rv); ok {
+			return common.TimestampConvertToTime(i, precision), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %d", t)
+	}
+	return nil, fmt.Errorf("can not convert %T to %s", v, common.TypeNameMap[int(t)])
+}
+
+func toInt(rv reflect.Value) (int64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		return int64(f), f == math.Trunc(f)
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toFloat(rv reflect.Value) (float64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
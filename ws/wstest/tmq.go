@@ -0,0 +1,318 @@
+package wstest
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/taosdata/driver-go/v3/common"
+	"github.com/taosdata/driver-go/v3/common/tmq"
+)
+
+// Message is a message of a topic, see Server.Produce.
+type Message struct {
+	Database string
+	VgroupID int32
+	Type     int32           // common.TMQ_RES_DATA, common.TMQ_RES_TABLE_META or common.TMQ_RES_METADATA, TMQ_RES_DATA when 0
+	Meta     json.RawMessage // the json meta of TMQ_RES_TABLE_META and TMQ_RES_METADATA messages
+	Blocks   []Block         // the data of TMQ_RES_DATA and TMQ_RES_METADATA messages
+	offset   int64
+}
+
+// Block is the data of one table in a message.
+type Block struct {
+	TableName string
+	Fields    []Field
+	Rows      [][]driver.Value
+	Precision int
+}
+
+type topicVgroup struct {
+	topic    string
+	vgroupID int32
+}
+
+type topic struct {
+	vgroups  map[int32]int64 // vgroup id to the number of messages produced
+	messages []*Message
+}
+
+func (s *Server) getTopic(name string) *topic {
+	t, exist := s.topics[name]
+	if !exist {
+		t = &topic{vgroups: make(map[int32]int64)}
+		s.topics[name] = t
+	}
+	return t
+}
+
+// CreateTopic creates an empty topic with the given vgroups, Produce and subscribing also create missing topics.
+func (s *Server) CreateTopic(name string, vgroupIDs ...int32) {
+	s.lock.Lock()
+	t := s.getTopic(name)
+	for _, id := range vgroupIDs {
+		if _, exist := t.vgroups[id]; !exist {
+			t.vgroups[id] = 0
+		}
+	}
+	s.lock.Unlock()
+}
+
+// Produce appends messages to a topic, each message takes the next offset of its vgroup. Polls waiting on the topic are woken up.
+func (s *Server) Produce(topicName string, messages ...Message) {
+	s.lock.Lock()
+	t := s.getTopic(topicName)
+	for i := range messages {
+		m := messages[i]
+		if m.Type == 0 {
+			m.Type = common.TMQ_RES_DATA
+		}
+		m.offset = t.vgroups[m.VgroupID]
+		t.vgroups[m.VgroupID] = m.offset + 1
+		t.messages = append(t.messages, &m)
+	}
+	close(s.produced)
+	s.produced = make(chan struct{})
+	s.lock.Unlock()
+}
+
+// Committed returns the offset committed by a consumer group, tmq.OffsetInvalid if there is none.
+func (s *Server) Committed(groupID, topicName string, vgroupID int32) tmq.Offset {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	offset, exist := s.committed[groupID][topicVgroup{topic: topicName, vgroupID: vgroupID}]
+	if !exist {
+		return tmq.OffsetInvalid
+	}
+	return tmq.Offset(offset)
+}
+
+type delivery struct {
+	topic   string
+	message *Message
+	block   int
+}
+
+// consumer is the subscription of a /rest/tmq connection
+type consumer struct {
+	groupID     string
+	offsetReset string
+	topics      []string
+	positions   map[topicVgroup]int64
+	deliveries  map[uint64]*delivery
+	closeChan   chan struct{}
+}
+
+func (c *consumer) close() {
+	close(c.closeChan)
+}
+
+// position returns the next offset of a vgroup, must be called with the server locked
+func (c *consumer) position(s *Server, tv topicVgroup) int64 {
+	if pos, exist := c.positions[tv]; exist {
+		return pos
+	}
+	pos, exist := s.committed[c.groupID][tv]
+	if !exist {
+		if c.offsetReset == "latest" {
+			pos = s.getTopic(tv.topic).vgroups[tv.vgroupID]
+		} else {
+			pos = 0
+		}
+	}
+	c.positions[tv] = pos
+	return pos
+}
+
+// next returns the next message for the consumer and the channel closed by the next Produce
+func (c *consumer) next(s *Server) (*delivery, <-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, name := range c.topics {
+		for _, m := range s.getTopic(name).messages {
+			tv := topicVgroup{topic: name, vgroupID: m.VgroupID}
+			if m.offset >= c.position(s, tv) {
+				c.positions[tv] = m.offset + 1
+				return &delivery{topic: name, message: m}, nil
+			}
+		}
+	}
+	return nil, s.produced
+}
+
+// handleTMQ serves /rest/tmq, used by ws/tmq
+func (sess *session) handleTMQ(req *Request) error {
+	a, err := sess.parseArgs(req)
+	if err != nil {
+		return err
+	}
+	s := sess.server
+	if req.Action != "subscribe" && sess.consumer == nil {
+		return sess.writeError(req.Action, req.ReqID, 0xffff, "tmq not subscribed")
+	}
+	c := sess.consumer
+	switch req.Action {
+	case "subscribe":
+		s.lock.Lock()
+		ok := a.User == s.user && a.Password == s.password
+		for _, name := range a.Topics {
+			s.getTopic(name)
+		}
+		s.lock.Unlock()
+		if !ok {
+			return sess.writeError(req.Action, a.ReqID, CodeAuthFailure, "Authentication failure")
+		}
+		if c == nil {
+			c = &consumer{closeChan: make(chan struct{}), deliveries: make(map[uint64]*delivery)}
+			sess.consumer = c
+		}
+		c.groupID = a.GroupID
+		c.offsetReset = a.OffsetRest
+		c.topics = append([]string(nil), a.Topics...)
+		c.positions = make(map[topicVgroup]int64)
+		return sess.write(req.Action, a.ReqID, nil)
+	case "poll":
+		deadline := time.NewTimer(time.Duration(a.BlockingTime) * time.Millisecond)
+		defer deadline.Stop()
+		for {
+			d, produced := c.next(s)
+			if d != nil {
+				id := s.generateID()
+				c.deliveries[id] = d
+				return sess.write(req.Action, a.ReqID, response{
+					"have_message": true,
+					"topic":        d.topic,
+					"database":     d.message.Database,
+					"vgroup_id":    d.message.VgroupID,
+					"message_type": d.message.Type,
+					"message_id":   id,
+					"offset":       d.message.offset,
+				})
+			}
+			select {
+			case <-produced:
+				continue
+			case <-deadline.C:
+			case <-c.closeChan:
+			}
+			return sess.write(req.Action, a.ReqID, response{"have_message": false})
+		}
+	case "fetch":
+		d, exist := c.deliveries[a.MessageID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "message is nil")
+		}
+		if d.block >= len(d.message.Blocks) {
+			return sess.write(req.Action, a.ReqID, response{"message_id": a.MessageID, "completed": true})
+		}
+		block := d.message.Blocks[d.block]
+		resp := queryFields(&Result{Fields: block.Fields, Precision: block.Precision})
+		resp["message_id"] = a.MessageID
+		resp["completed"] = false
+		resp["table_name"] = block.TableName
+		resp["rows"] = len(block.Rows)
+		return sess.write(req.Action, a.ReqID, resp)
+	case "fetch_block":
+		d, exist := c.deliveries[a.MessageID]
+		if !exist || d.block >= len(d.message.Blocks) {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "message is nil")
+		}
+		block := d.message.Blocks[d.block]
+		raw, err := encodeBlock(block.Fields, block.Rows, block.Precision)
+		if err != nil {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, err.Error())
+		}
+		d.block += 1
+		// timing, req_id and message_id
+		return sess.writeBinary(raw, 0, a.ReqID, a.MessageID)
+	case "fetch_json_meta":
+		d, exist := c.deliveries[a.MessageID]
+		if !exist {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "message is nil")
+		}
+		meta := d.message.Meta
+		if meta == nil {
+			meta = json.RawMessage("{}")
+		}
+		return sess.write(req.Action, a.ReqID, response{"message_id": a.MessageID, "data": meta})
+	case "commit":
+		s.lock.Lock()
+		group := s.committed[c.groupID]
+		if group == nil {
+			group = make(map[topicVgroup]int64)
+			s.committed[c.groupID] = group
+		}
+		for tv, pos := range c.positions {
+			group[tv] = pos
+		}
+		s.lock.Unlock()
+		c.deliveries = make(map[uint64]*delivery)
+		return sess.write(req.Action, a.ReqID, response{"message_id": a.MessageID})
+	case "unsubscribe":
+		c.topics = nil
+		c.positions = make(map[topicVgroup]int64)
+		c.deliveries = make(map[uint64]*delivery)
+		return sess.write(req.Action, a.ReqID, nil)
+	case "assignment":
+		s.lock.Lock()
+		t := s.getTopic(a.Topic)
+		ids := make([]int32, 0, len(t.vgroups))
+		for id := range t.vgroups {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		assignment := make([]tmq.Assignment, len(ids))
+		for i, id := range ids {
+			assignment[i] = tmq.Assignment{
+				VGroupID: id,
+				Offset:   c.position(s, topicVgroup{topic: a.Topic, vgroupID: id}),
+				Begin:    0,
+				End:      t.vgroups[id],
+			}
+		}
+		s.lock.Unlock()
+		return sess.write(req.Action, a.ReqID, response{"assignment": assignment})
+	case "seek":
+		s.lock.Lock()
+		end, exist := s.getTopic(a.Topic).vgroups[a.VgroupID]
+		if exist && a.Offset >= 0 && a.Offset <= end {
+			c.positions[topicVgroup{topic: a.Topic, vgroupID: a.VgroupID}] = a.Offset
+		}
+		s.lock.Unlock()
+		if !exist || a.Offset < 0 || a.Offset > end {
+			return sess.writeError(req.Action, a.ReqID, CodeInvalidPara, "offset out of range")
+		}
+		return sess.write(req.Action, a.ReqID, nil)
+	case "committed", "position":
+		offsets := make([]int64, len(a.TopicVgroupIDs))
+		s.lock.Lock()
+		for i, id := range a.TopicVgroupIDs {
+			tv := topicVgroup{topic: id.Topic, vgroupID: id.VgroupID}
+			if req.Action == "position" {
+				offsets[i] = c.position(s, tv)
+				continue
+			}
+			offset, exist := s.committed[c.groupID][tv]
+			if !exist {
+				offset = int64(tmq.OffsetInvalid)
+			}
+			offsets[i] = offset
+		}
+		s.lock.Unlock()
+		return sess.write(req.Action, a.ReqID, response{req.Action: offsets})
+	case "commit_offset":
+		s.lock.Lock()
+		group := s.committed[c.groupID]
+		if group == nil {
+			group = make(map[topicVgroup]int64)
+			s.committed[c.groupID] = group
+		}
+		group[topicVgroup{topic: a.Topic, vgroupID: a.VgroupID}] = a.Offset
+		s.lock.Unlock()
+		return sess.write(req.Action, a.ReqID, response{"topic": a.Topic, "vgroup_id": a.VgroupID, "offset": a.Offset})
+	case "list_topics":
+		return sess.write(req.Action, a.ReqID, response{"topics": c.topics})
+	}
+	return sess.writeError(req.Action, req.ReqID, CodeInvalidPara, "unknown action "+req.Action)
+}